@@ -1,11 +1,16 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"kadane.xyz/go-backend/v2/src/apierror"
 	"kadane.xyz/go-backend/v2/src/judge0"
 	"kadane.xyz/go-backend/v2/src/sql/sql"
@@ -23,6 +28,10 @@ type AdminProblemRunRequest struct {
 	FunctionName string            `json:"functionName"`
 	Solutions    map[string]string `json:"solutions"` // ["language": "sourceCode"]
 	TestCase     TestCase          `json:"testCase"`
+	Limits       ProblemLimits     `json:"limits"`
+	Checker      ProblemChecker    `json:"checker"`
+	Signature    *ProblemSignature `json:"signature,omitempty"`
+	Design       *ProblemDesign    `json:"design,omitempty"`
 }
 
 type AdminProblemRunResult struct {
@@ -43,25 +52,31 @@ type AdminProblemResponse struct {
 
 type CreateAdminProblemData struct {
 	ProblemID string `json:"problemId"`
+	Version   int32  `json:"version"`
 }
 
 type CreateAdminProblemResponse struct {
 	Data CreateAdminProblemData `json:"data"`
 }
 
-func (h *Handler) ProblemRun(runRequest AdminProblemRunRequest) (AdminProblemResponse, *apierror.APIError) {
+func (h *Handler) ProblemRun(ctx context.Context, runRequest AdminProblemRunRequest) (AdminProblemResponse, *apierror.APIError) {
 	solutionRuns := make(map[string][]judge0.Submission) // Store all judge0 submission inputs for each language
 
 	// Create judge0 submission inputs by combining test case handling and template creation.
 	for language, sourceCode := range runRequest.Solutions {
 		// Create the submission for this test case and language
-		solutionRun := TemplateCreate(TemplateInput{
+		solutionRun, err := TemplateCreate(TemplateInput{
 			Language:     language,
 			SourceCode:   sourceCode,
 			FunctionName: runRequest.FunctionName,
 			TestCase:     runRequest.TestCase,
+			Signature:    runRequest.Signature,
+			Design:       runRequest.Design,
 		})
-		solutionRuns[language] = append(solutionRuns[language], solutionRun)
+		if err != nil {
+			return AdminProblemResponse{}, apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+		}
+		solutionRuns[language] = append(solutionRuns[language], runRequest.Limits.Apply(solutionRun, language))
 	}
 
 	// Validate submissions before sending
@@ -77,47 +92,41 @@ func (h *Handler) ProblemRun(runRequest AdminProblemRunRequest) (AdminProblemRes
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	var executorErr *apierror.APIError // a judge0 outage says nothing about the solutions
 
 	// Create submissions for each language
 	for language := range solutionRuns {
 		wg.Add(1)
 		go func(language string, submissions []judge0.Submission) {
 			defer wg.Done()
-			runResponses, _ := h.Judge0Client.CreateSubmissionBatchAndWait(submissions)
-			/*if err != nil {
-				apierror.SendError(w, http.StatusInternalServerError, "Failed to create solution submission for language: "+language)
-				continue
-			}*/
+			// Failed submissions come back as internal errors and fail the language below
+			runResponses, err := h.ExecuteSubmissions(ctx, 0, submissions, true)
+			if err != nil {
+				log.Printf("Problem run for language %s failed: %v", language, err)
+				if apiErr := ExecutorError(err); apiErr != nil {
+					mu.Lock()
+					executorErr = apiErr
+					mu.Unlock()
+				}
+			}
 
 			var localTestCase RunTestCase
 
-			// Compare outputs for each test case
-			for _, solutionResp := range runResponses {
-				testCase := RunTestCase{
+			// Check outputs for each test case with the problem's checker
+			testCases := make([]TestCase, len(runResponses))
+			for i := range testCases {
+				testCases[i] = runRequest.TestCase
+			}
+			statuses := h.GradeTestCases(ctx, runRequest.Checker, testCases, runResponses)
+			for i, solutionResp := range runResponses {
+				localTestCase = RunTestCase{
 					Time:           solutionResp.Time,
 					Memory:         int(solutionResp.Memory),
-					Status:         sql.SubmissionStatus(solutionResp.Status.Description),
+					Status:         statuses[i],
 					Output:         solutionResp.Stdout,
 					CompileOutput:  solutionResp.CompileOutput,
 					ExpectedOutput: runRequest.TestCase.Output,
 				}
-
-				if strings.Contains(solutionResp.Stdout, "[") {
-					solutionResp.Stdout = strings.ReplaceAll(solutionResp.Stdout, " ", "")
-					testCase.Output = strings.ReplaceAll(testCase.Output, " ", "")
-				}
-				if strings.Contains(solutionResp.Stdout, "\n") {
-					solutionResp.Stdout = strings.ReplaceAll(solutionResp.Stdout, "\n", "")
-					testCase.Output = strings.ReplaceAll(testCase.Output, "\n", "")
-				}
-
-				if solutionResp.Status.Description != "Accepted" ||
-					solutionResp.Stdout != runRequest.TestCase.Output ||
-					solutionResp.CompileOutput != "" {
-					testCase.Status = sql.SubmissionStatus("Wrong Answer")
-				}
-
-				localTestCase = testCase
 			}
 
 			// Determine overall status for this language
@@ -144,6 +153,13 @@ func (h *Handler) ProblemRun(runRequest AdminProblemRunRequest) (AdminProblemRes
 
 	wg.Wait()
 
+	if ctx.Err() != nil {
+		return AdminProblemResponse{}, apierror.NewError(http.StatusRequestTimeout, "Problem run cancelled")
+	}
+	if executorErr != nil {
+		return AdminProblemResponse{}, executorErr
+	}
+
 	// Determine the overall status of all runs
 	var status string
 	for _, run := range responseData.Data.Runs {
@@ -167,6 +183,30 @@ func ProblemRunRequestValidate(runRequest AdminProblemRunRequest) *apierror.APIE
 		return apierror.NewError(http.StatusBadRequest, "Missing function name")
 	}
 
+	if runRequest.Signature != nil {
+		if apiErr := runRequest.Signature.Validate(); apiErr != nil {
+			return apiErr
+		}
+		if apiErr := runRequest.Signature.ValidateTestCase(runRequest.TestCase); apiErr != nil {
+			return apiErr
+		}
+	}
+
+	// Design test cases construct the class and call its methods instead of passing inputs
+	if runRequest.Design != nil {
+		if runRequest.Signature != nil {
+			return apierror.NewError(http.StatusBadRequest, "A problem has either a signature or a design")
+		}
+		if apiErr := runRequest.Design.Validate(runRequest.FunctionName); apiErr != nil {
+			return apiErr
+		}
+		if apiErr := runRequest.Design.ValidateTestCase(runRequest.FunctionName, runRequest.TestCase); apiErr != nil {
+			return apiErr
+		}
+	} else if len(runRequest.TestCase.Calls) > 0 {
+		return apierror.NewError(http.StatusBadRequest, "Invalid test case: only design problems have calls")
+	}
+
 	// check map for missing values
 	for language, sourceCode := range runRequest.Solutions {
 		// Check if source code is missing
@@ -175,18 +215,30 @@ func ProblemRunRequestValidate(runRequest AdminProblemRunRequest) *apierror.APIE
 		}
 
 		// Check if language is valid
-		lang := string(sql.ProblemLanguage(language))
-		if language == "" || language != lang {
-			return apierror.NewError(http.StatusBadRequest, "Invalid language: "+language)
+		if apiErr := ValidateLanguage(language); apiErr != nil {
+			return apiErr
 		}
 
-		// Check if function name is valid
-		if !strings.Contains(sourceCode, runRequest.FunctionName) {
+		// Check the solution declares the function, with the declared parameters if there is a signature
+		if runRequest.Signature != nil {
+			if err := runRequest.Signature.Check(language, sourceCode, runRequest.FunctionName); err != nil {
+				return apierror.NewError(http.StatusBadRequest, "Solution doesn't match the signature: "+err.Error())
+			}
+		} else if !strings.Contains(sourceCode, runRequest.FunctionName) {
 			return apierror.NewError(http.StatusBadRequest, "Correct function name: "+runRequest.FunctionName+" not found in "+language+" source code")
 		}
 	}
 
-	return nil
+	if runRequest.Design == nil {
+		if _, err := TemplateStdin(runRequest.TestCase); err != nil {
+			return apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+		}
+	}
+
+	if apiErr := runRequest.Limits.Validate(); apiErr != nil {
+		return apiErr
+	}
+	return runRequest.Checker.Validate()
 }
 
 // GET: /admin/validate
@@ -203,8 +255,10 @@ func (h *Handler) GetAdminValidation(w http.ResponseWriter, r *http.Request) {
 }
 
 type AdminProblem struct {
-	Problem  Problem           `json:"problem"`
-	Solution map[string]string `json:"solution,omitempty"` // ["language": "sourceCode"]
+	Problem   Problem           `json:"problem"`
+	Solution  map[string]string `json:"solution,omitempty"` // ["language": "sourceCode"]
+	Version   int32             `json:"version"`
+	RetiredAt *time.Time        `json:"retiredAt,omitempty"`
 }
 
 type AdminProblemsResponse struct {
@@ -243,6 +297,11 @@ func (h *Handler) GetAdminProblems(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		var retiredAt *time.Time
+		if problem.RetiredAt.Valid {
+			retiredAt = &problem.RetiredAt.Time
+		}
+
 		adminProblems = append(adminProblems, AdminProblem{
 			Problem: Problem{
 				ID:           problem.ID,
@@ -253,7 +312,9 @@ func (h *Handler) GetAdminProblems(w http.ResponseWriter, r *http.Request) {
 				Difficulty:   problem.Difficulty,
 				Tags:         problem.Tags,
 			},
-			Solution: solutionMap,
+			Solution:  solutionMap,
+			Version:   problem.Version,
+			RetiredAt: retiredAt,
 		})
 	}
 
@@ -263,8 +324,38 @@ func (h *Handler) GetAdminProblems(w http.ResponseWriter, r *http.Request) {
 	SendJSONResponse(w, http.StatusOK, response)
 }
 
+// ValidateProblemSolutions runs the solutions of a problem against each of its test cases, every one of them
+// must be accepted
+func (h *Handler) ValidateProblemSolutions(ctx context.Context, request ProblemRequest) *apierror.APIError {
+	for i, testCase := range request.TestCases {
+		responseData, apiErr := h.ProblemRun(ctx, AdminProblemRunRequest{
+			FunctionName: request.FunctionName,
+			Solutions:    request.Solutions,
+			TestCase:     testCase,
+			Limits:       request.Limits,
+			Checker:      request.Checker,
+			Signature:    request.Signature,
+			Design:       request.Design,
+		})
+		if apiErr != nil {
+			return apiErr
+		}
+
+		// Check if any test cases fail
+		if responseData.Data.Status != "Accepted" {
+			return apierror.NewError(http.StatusBadRequest, "Wrong answer for test case: "+strconv.Itoa(i+1))
+		}
+	}
+	return nil
+}
+
 // POST: /admin/problems
 func (h *Handler) CreateAdminProblem(w http.ResponseWriter, r *http.Request) {
+	userId, err := GetClientUserID(w, r)
+	if err != nil {
+		return
+	}
+
 	request, apiErr := DecodeJSONRequest[ProblemRequest](r)
 	if apiErr != nil {
 		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
@@ -273,31 +364,18 @@ func (h *Handler) CreateAdminProblem(w http.ResponseWriter, r *http.Request) {
 
 	apiErr = CreateProblemRequestValidate(request)
 	if apiErr != nil {
-		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		apiErr.Send(w)
 		return
 	}
 
 	// Test problem test cases against solutions in each language
-	for i, testCase := range request.TestCases {
-		responseData, apiErr := h.ProblemRun(AdminProblemRunRequest{
-			FunctionName: request.FunctionName,
-			Solutions:    request.Solutions,
-			TestCase:     testCase,
-		})
-		if apiErr != nil {
-			apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
-			return
-		}
-
-		// Check if any test cases fail
-		if responseData.Data.Status != "Accepted" {
-			apierror.SendError(w, http.StatusBadRequest, "Wrong answer for test case: "+testCase.Input[i].Name)
-			return
-		}
+	if apiErr := h.ValidateProblemSolutions(r.Context(), request); apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
 	}
 
 	// Create problem in database if all test cases pass
-	problemID, apiErr := h.CreateProblem(request)
+	problemID, apiErr := h.CreateProblem(r.Context(), request, userId)
 	if apiErr != nil {
 		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
 		return
@@ -306,12 +384,177 @@ func (h *Handler) CreateAdminProblem(w http.ResponseWriter, r *http.Request) {
 	response := CreateAdminProblemResponse{
 		Data: CreateAdminProblemData{
 			ProblemID: problemID.Data.ProblemID,
+			Version:   1,
 		},
 	}
 
 	SendJSONResponse(w, http.StatusCreated, response)
 }
 
+// adminProblemID reads the problem ID of admin problem routes, sending a 400 when it's invalid
+func adminProblemID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "problemId"), 10, 32)
+	if err != nil || id <= 0 {
+		apierror.SendError(w, http.StatusBadRequest, "Invalid problem ID")
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// updateAdminProblem validates the request and its solutions then stores it as the next version of the problem
+func (h *Handler) updateAdminProblem(w http.ResponseWriter, r *http.Request, problemID int32, request ProblemRequest) {
+	userId, err := GetClientUserID(w, r)
+	if err != nil {
+		return
+	}
+
+	if apiErr := CreateProblemRequestValidate(request); apiErr != nil {
+		apiErr.Send(w)
+		return
+	}
+
+	if apiErr := h.ValidateProblemSolutions(r.Context(), request); apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	version, apiErr := h.UpdateProblem(r.Context(), problemID, request, userId)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	// Test cases, limits and the checker may have changed
+	h.InvalidateProblemResults(r.Context(), problemID)
+
+	response := CreateAdminProblemResponse{
+		Data: CreateAdminProblemData{
+			ProblemID: strconv.Itoa(int(problemID)),
+			Version:   version,
+		},
+	}
+
+	SendJSONResponse(w, http.StatusOK, response)
+}
+
+// PUT: /admin/problems/{problemId}
+// Replaces the whole problem, submissions keep the version they were judged against
+func (h *Handler) UpdateAdminProblem(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := adminProblemID(w, r)
+	if !ok {
+		return
+	}
+
+	request, apiErr := DecodeJSONRequest[ProblemRequest](r)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	// Don't run the solutions of a problem that can't be updated
+	if _, apiErr := h.GetProblemRequest(r.Context(), problemID); apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	h.updateAdminProblem(w, r, problemID, request)
+}
+
+// PATCH: /admin/problems/{problemId}
+// Fields missing from the body keep their current value, the problem is validated and run again as a whole
+func (h *Handler) PatchAdminProblem(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := adminProblemID(w, r)
+	if !ok {
+		return
+	}
+
+	patch, apiErr := DecodeJSONRequest[map[string]json.RawMessage](r)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	current, apiErr := h.GetProblemRequest(r.Context(), problemID)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	request, apiErr := PatchProblemRequest(current, patch)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	h.updateAdminProblem(w, r, problemID, request)
+}
+
+// DELETE: /admin/problems/{problemId}
+// Retires the problem, it is hidden and takes no new runs or submissions while its submissions are kept
+func (h *Handler) DeleteAdminProblem(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := adminProblemID(w, r)
+	if !ok {
+		return
+	}
+
+	retired, err := h.PostgresQueries.RetireProblem(r.Context(), problemID)
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to delete problem")
+		return
+	}
+	if retired == 0 {
+		apierror.SendError(w, http.StatusNotFound, "Problem not found")
+		return
+	}
+
+	h.InvalidateProblemResults(r.Context(), problemID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type AdminProblemVersion struct {
+	Version   int32          `json:"version"`
+	Problem   ProblemRequest `json:"problem"`
+	CreatedBy string         `json:"createdBy,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+type AdminProblemVersionsResponse struct {
+	Data []AdminProblemVersion `json:"data"`
+}
+
+// GET: /admin/problems/{problemId}/versions
+// Newest first, problems created before versioning start with the version of their first edit
+func (h *Handler) GetAdminProblemVersions(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := adminProblemID(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.PostgresQueries.GetProblemVersions(r.Context(), problemID)
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to get problem versions")
+		return
+	}
+
+	versions := make([]AdminProblemVersion, 0, len(rows))
+	for _, row := range rows {
+		var problem ProblemRequest
+		if err := json.Unmarshal(row.Problem, &problem); err != nil {
+			apierror.SendError(w, http.StatusInternalServerError, "Failed to get problem versions")
+			return
+		}
+		versions = append(versions, AdminProblemVersion{
+			Version:   row.Version,
+			Problem:   problem,
+			CreatedBy: row.CreatedBy.String,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+
+	SendJSONResponse(w, http.StatusOK, AdminProblemVersionsResponse{Data: versions})
+}
+
 // POST: /admin/problems/run
 // Make sure to check test cases for each language
 func (h *Handler) CreateAdminProblemRun(w http.ResponseWriter, r *http.Request) {
@@ -329,7 +572,7 @@ func (h *Handler) CreateAdminProblemRun(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Run problems against judge0
-	responseData, apiErr := h.ProblemRun(runRequest)
+	responseData, apiErr := h.ProblemRun(r.Context(), runRequest)
 	if apiErr != nil {
 		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
 		return
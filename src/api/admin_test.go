@@ -0,0 +1,597 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// adminTwoSumTestCase matches the output the fake judge0 server returns once spaces are stripped
+var adminTwoSumTestCase = TestCase{
+	Description: "Two sum",
+	Input: []TestCaseInput{
+		{Name: "nums", Type: IntArrayType, Value: "[2, 7, 11, 15]"},
+		{Name: "target", Type: IntType, Value: "9"},
+	},
+	Output:     "[0,1]",
+	Visibility: sql.VisibilityPublic,
+}
+
+// adminTwoSumSignature declares the function of adminTwoSumTestCase
+var adminTwoSumSignature = ProblemSignature{
+	Parameters: []SignatureParameter{
+		{Name: "nums", Type: IntArrayType},
+		{Name: "target", Type: IntType},
+	},
+	ReturnType: IntArrayType,
+}
+
+// adminCounterDesign declares a counter class and adminCounterTestCase calls it
+var adminCounterDesign = ProblemDesign{
+	Constructor: []SignatureParameter{{Name: "start", Type: IntType}},
+	Methods: []DesignMethod{
+		{Name: "add", Parameters: []SignatureParameter{{Name: "value", Type: IntType}}, ReturnType: VoidType},
+		{Name: "get", ReturnType: IntType},
+	},
+}
+
+var adminCounterTestCase = TestCase{
+	Description: "Counter",
+	Calls: []DesignCall{
+		{Method: "Counter", Arguments: []json.RawMessage{json.RawMessage("1")}},
+		{Method: "add", Arguments: []json.RawMessage{json.RawMessage("2")}},
+		{Method: "get"},
+	},
+	Output:     "[null,null,3]",
+	Visibility: sql.VisibilityPublic,
+}
+
+func TestCreateAdminProblemRun(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name: "Run solutions",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python":     "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+					"javascript": "function twoSum(nums, target) { return [0, 1]; }",
+				},
+				TestCase: adminTwoSumTestCase,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Run solutions in new languages",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"rust":   "impl Solution {\n    pub fn twoSum(nums: Vec<i32>, target: i32) -> Vec<i32> { vec![0, 1] }\n}",
+					"c":      "int* twoSum(int* nums, int numsSize, int target, int* returnSize) { static int r[2] = {0, 1}; *returnSize = 2; return r; }",
+					"csharp": "public class Solution { public int[] twoSum(int[] nums, int target) { return new int[]{0, 1}; } }",
+					"kotlin": "class Solution { fun twoSum(nums: IntArray, target: Int): IntArray = intArrayOf(0, 1) }",
+					"ruby":   "def twoSum(nums, target)\n  [0, 1]\nend",
+				},
+				TestCase: adminTwoSumTestCase,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Run solution in unknown language",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"cobol": "twoSum",
+				},
+				TestCase: adminTwoSumTestCase,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with limits above judge0's maximum",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCase: adminTwoSumTestCase,
+				Limits:   ProblemLimits{CPUTimeLimit: 60},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with a multiplier for an unknown language",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCase: adminTwoSumTestCase,
+				Limits:   ProblemLimits{CPUTimeLimit: 2, TimeMultipliers: map[string]float64{"cobol": 2}},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with an unknown checker",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCase: adminTwoSumTestCase,
+				Checker:  ProblemChecker{Mode: "fuzzy"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with a custom checker without source code",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCase: adminTwoSumTestCase,
+				Checker:  ProblemChecker{Mode: sql.CheckerModeCustom, Language: "python"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with an unordered checker",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [1, 0]",
+				},
+				TestCase: adminTwoSumTestCase,
+				Checker:  ProblemChecker{Mode: sql.CheckerModeUnordered},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Run with a tree and a linked list",
+			body: AdminProblemRunRequest{
+				FunctionName: "merge",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def merge(self, root, head):\n        return head",
+				},
+				TestCase: TestCase{
+					Input: []TestCaseInput{
+						{Name: "root", Type: TreeNodeType, Value: "[1,null,2,3]"},
+						{Name: "head", Type: ListNodeType, Value: "[1,2,3]"},
+					},
+					Output: "[1,2,3]",
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Run with a tree without a root",
+			body: AdminProblemRunRequest{
+				FunctionName: "merge",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def merge(self, root):\n        return root",
+				},
+				TestCase: TestCase{
+					Input:  []TestCaseInput{{Name: "root", Type: TreeNodeType, Value: "[null,1]"}},
+					Output: "[]",
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with a graph edge to a missing node",
+			body: AdminProblemRunRequest{
+				FunctionName: "paths",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def paths(self, graph):\n        return []",
+				},
+				TestCase: TestCase{
+					Input:  []TestCaseInput{{Name: "graph", Type: GraphType, Value: "[[1],[2]]"}},
+					Output: "[]",
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run with a char longer than one character",
+			body: AdminProblemRunRequest{
+				FunctionName: "count",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def count(self, c):\n        return 1",
+				},
+				TestCase: TestCase{
+					Input:  []TestCaseInput{{Name: "c", Type: CharType, Value: "ab"}},
+					Output: "1",
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run solutions matching the signature",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums: List[int], target: int) -> List[int]:\n        return [0, 1]",
+					"go":     "func twoSum(nums []int, target int) []int {\n\treturn []int{0, 1}\n}",
+				},
+				TestCase:  adminTwoSumTestCase,
+				Signature: &adminTwoSumSignature,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Run a solution with the wrong parameter types",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"go": "func twoSum(nums []int64, target int64) []int {\n\treturn []int{0, 1}\n}",
+				},
+				TestCase:  adminTwoSumTestCase,
+				Signature: &adminTwoSumSignature,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run a test case missing a parameter of the signature",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCase: TestCase{
+					Input:  adminTwoSumTestCase.Input[:1],
+					Output: "[0,1]",
+				},
+				Signature: &adminTwoSumSignature,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run design solutions",
+			body: AdminProblemRunRequest{
+				FunctionName: "Counter",
+				Solutions: map[string]string{
+					"python":     "class Counter:\n    def __init__(self, start):\n        self.value = start\n    def add(self, value):\n        self.value += value\n    def get(self):\n        return self.value",
+					"javascript": "class Counter {\n  constructor(start) { this.value = start; }\n  add(value) { this.value += value; }\n  get() { return this.value; }\n}",
+				},
+				TestCase: adminCounterTestCase,
+				Design:   &adminCounterDesign,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Run a design test case not constructing the class first",
+			body: AdminProblemRunRequest{
+				FunctionName: "Counter",
+				Solutions: map[string]string{
+					"python": "class Counter:\n    def __init__(self, start):\n        self.value = start\n    def get(self):\n        return self.value",
+				},
+				TestCase: TestCase{
+					Calls:  adminCounterTestCase.Calls[1:],
+					Output: "[null,3]",
+				},
+				Design: &adminCounterDesign,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run a design test case calling an unknown method",
+			body: AdminProblemRunRequest{
+				FunctionName: "Counter",
+				Solutions: map[string]string{
+					"python": "class Counter:\n    def __init__(self, start):\n        self.value = start\n    def get(self):\n        return self.value",
+				},
+				TestCase: TestCase{
+					Calls:  []DesignCall{adminCounterTestCase.Calls[0], {Method: "reset"}},
+					Output: "[null,null]",
+				},
+				Design: &adminCounterDesign,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run without function name",
+			body: AdminProblemRunRequest{
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCase: adminTwoSumTestCase,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run without solutions",
+			body: AdminProblemRunRequest{
+				FunctionName: "twoSum",
+				TestCase:     adminTwoSumTestCase,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPost, "/admin/problems/run", testCase.body)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.CreateAdminProblemRun)
+		})
+	}
+}
+
+func TestCreateAdminProblem(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name: "Create problem",
+			body: ProblemRequest{
+				Title:        "Two Sum Admin",
+				Description:  "Return the indices of the two numbers that add up to target.",
+				FunctionName: "twoSum",
+				Tags:         []string{"array"},
+				Difficulty:   "easy",
+				Code: ProblemRequestCode{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        pass",
+				},
+				Points: 100,
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCases: []TestCase{adminTwoSumTestCase},
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Create problem with generated starter code",
+			body: ProblemRequest{
+				Title:        "Two Sum Admin Signature",
+				Description:  "Return the indices of the two numbers that add up to target.",
+				FunctionName: "twoSum",
+				Tags:         []string{"array"},
+				Difficulty:   "easy",
+				Points:       100,
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCases: []TestCase{adminTwoSumTestCase},
+				Signature: &ProblemSignature{
+					Parameters: adminTwoSumSignature.Parameters,
+					ReturnType: IntArrayType,
+				},
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Create problem with an unknown signature type",
+			body: ProblemRequest{
+				Title:        "Two Sum Admin Invalid Signature",
+				Description:  "Return the indices of the two numbers that add up to target.",
+				FunctionName: "twoSum",
+				Points:       100,
+				Solutions: map[string]string{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				},
+				TestCases: []TestCase{adminTwoSumTestCase},
+				Signature: &ProblemSignature{
+					Parameters: []SignatureParameter{{Name: "nums", Type: "set"}, {Name: "target", Type: IntType}},
+					ReturnType: IntArrayType,
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Create problem without solutions",
+			body: ProblemRequest{
+				Title:        "Two Sum Admin Missing Solution",
+				Description:  "Return the indices of the two numbers that add up to target.",
+				FunctionName: "twoSum",
+				Code: ProblemRequestCode{
+					"python": "class Solution:\n    def twoSum(self, nums, target):\n        pass",
+				},
+				TestCases: []TestCase{adminTwoSumTestCase},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPost, "/admin/problems", testCase.body)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.CreateAdminProblem)
+		})
+	}
+}
+
+// adminVersionsProblem is created by TestAdminProblemLifecycle, its title is unique to the test
+var adminVersionsProblem = ProblemRequest{
+	Title:        "Two Sum Admin Versions",
+	Description:  "Return the indices of the two numbers that add up to target.",
+	FunctionName: "twoSum",
+	Difficulty:   "easy",
+	Points:       100,
+	Solutions: map[string]string{
+		"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+	},
+	TestCases: []TestCase{adminTwoSumTestCase},
+	Signature: &adminTwoSumSignature,
+}
+
+func TestAdminProblemLifecycle(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	handler.CreateAdminProblem(w, newTestRequestWithBody(t, http.MethodPost, "/admin/problems", adminVersionsProblem))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d, message: %s", http.StatusCreated, w.Code, extractErrorMessage(w.Body))
+	}
+	var created CreateAdminProblemResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	urlParams := map[string]string{"problemId": created.Data.ProblemID}
+
+	edited := adminVersionsProblem
+	edited.Hints = []ProblemRequestHint{{Description: "Use a map", Answer: "Store the indices of seen numbers"}}
+	edited.TestCases = []TestCase{adminTwoSumTestCase, {
+		Description: "Hidden two sum",
+		Input:       adminTwoSumTestCase.Input,
+		Output:      adminTwoSumTestCase.Output,
+		Visibility:  sql.VisibilityPrivate,
+	}}
+
+	steps := []struct {
+		name           string
+		method         string
+		body           any
+		handlerFunc    http.HandlerFunc
+		expectedStatus int
+	}{
+		{name: "Patch the title", method: http.MethodPatch, body: map[string]any{"title": "Two Sum Admin Versions Edited"}, handlerFunc: handler.PatchAdminProblem, expectedStatus: http.StatusOK},
+		{name: "Patch an unknown difficulty", method: http.MethodPatch, body: map[string]any{"difficulty": "impossible"}, handlerFunc: handler.PatchAdminProblem, expectedStatus: http.StatusBadRequest},
+		{name: "Patch an unknown field", method: http.MethodPatch, body: map[string]any{"name": "Two Sum"}, handlerFunc: handler.PatchAdminProblem, expectedStatus: http.StatusBadRequest},
+		{name: "Put hints and a hidden test case", method: http.MethodPut, body: edited, handlerFunc: handler.UpdateAdminProblem, expectedStatus: http.StatusOK},
+		{name: "Put a solution failing the signature", method: http.MethodPut, body: ProblemRequest{
+			Title:        edited.Title,
+			Description:  edited.Description,
+			FunctionName: edited.FunctionName,
+			Difficulty:   edited.Difficulty,
+			Solutions:    map[string]string{"go": "func twoSum(nums []int64, target int64) []int {\n\treturn []int{0, 1}\n}"},
+			TestCases:    edited.TestCases,
+			Signature:    edited.Signature,
+		}, handlerFunc: handler.UpdateAdminProblem, expectedStatus: http.StatusBadRequest},
+		{name: "Get versions", method: http.MethodGet, handlerFunc: handler.GetAdminProblemVersions, expectedStatus: http.StatusOK},
+		{name: "Retire", method: http.MethodDelete, handlerFunc: handler.DeleteAdminProblem, expectedStatus: http.StatusNoContent},
+		{name: "Retire again", method: http.MethodDelete, handlerFunc: handler.DeleteAdminProblem, expectedStatus: http.StatusNotFound},
+		{name: "Patch once retired", method: http.MethodPatch, body: map[string]any{"title": "Two Sum Admin Versions Retired"}, handlerFunc: handler.PatchAdminProblem, expectedStatus: http.StatusNotFound},
+	}
+
+	// Steps build on each other so they run in order
+	for _, step := range steps {
+		request := newTestRequestWithBody(t, step.method, "/admin/problems/{problemId}", step.body)
+		request = applyURLParams(request, urlParams)
+
+		w := httptest.NewRecorder()
+		step.handlerFunc(w, request)
+		if w.Code != step.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d, message: %s", step.name, step.expectedStatus, w.Code, extractErrorMessage(w.Body))
+		}
+
+		if step.method == http.MethodGet {
+			var versions AdminProblemVersionsResponse
+			if err := json.NewDecoder(w.Body).Decode(&versions); err != nil {
+				t.Fatalf("Failed to decode versions: %v", err)
+			}
+			if len(versions.Data) != 3 || versions.Data[0].Version != 3 || len(versions.Data[0].Problem.TestCases) != 2 {
+				t.Errorf("Expected 3 versions, the latest with 2 test cases, got %+v", versions.Data)
+			}
+		}
+	}
+}
+
+func TestAdminProblemNotFound(t *testing.T) {
+	testCases := []struct {
+		TestingCase
+		handlerFunc http.HandlerFunc
+	}{
+		{TestingCase{name: "Put invalid problem ID", urlParams: map[string]string{"problemId": "abc"}, body: adminVersionsProblem, expectedStatus: http.StatusBadRequest}, handler.UpdateAdminProblem},
+		{TestingCase{name: "Put missing problem", urlParams: map[string]string{"problemId": "999999"}, body: adminVersionsProblem, expectedStatus: http.StatusNotFound}, handler.UpdateAdminProblem},
+		{TestingCase{name: "Patch missing problem", urlParams: map[string]string{"problemId": "999999"}, body: map[string]any{"title": "Missing"}, expectedStatus: http.StatusNotFound}, handler.PatchAdminProblem},
+		{TestingCase{name: "Delete invalid problem ID", urlParams: map[string]string{"problemId": "0"}, expectedStatus: http.StatusBadRequest}, handler.DeleteAdminProblem},
+		{TestingCase{name: "Delete missing problem", urlParams: map[string]string{"problemId": "999999"}, expectedStatus: http.StatusNotFound}, handler.DeleteAdminProblem},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPut, "/admin/problems/{problemId}", testCase.body)
+			request = applyURLParams(request, testCase.urlParams)
+
+			executeTestRequest(t, request, testCase.expectedStatus, testCase.handlerFunc)
+		})
+	}
+}
+
+func TestPatchProblemRequest(t *testing.T) {
+	handWritten := "class Solution:\n    def twoSum(self, nums, target):\n        # hand written\n        pass"
+	current := adminVersionsProblem
+	current.Code = ProblemRequestCode{"python": handWritten}
+	for language, stub := range adminTwoSumSignature.Stubs("twoSum") {
+		if language != "python" {
+			current.Code[language] = stub
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		patch          string
+		expectedErr    bool
+		expectedCheck  func(ProblemRequest) bool
+		expectedReason string
+	}{
+		{
+			name:           "Keep fields missing from the patch",
+			patch:          `{"title": "Edited"}`,
+			expectedCheck:  func(p ProblemRequest) bool { return p.Title == "Edited" && len(p.TestCases) == 1 && p.Signature != nil },
+			expectedReason: "edited title with the current test cases and signature",
+		},
+		{
+			name:           "Replace lists as a whole",
+			patch:          `{"testCases": []}`,
+			expectedCheck:  func(p ProblemRequest) bool { return len(p.TestCases) == 0 },
+			expectedReason: "no test cases",
+		},
+		{
+			name:           "Remove the signature",
+			patch:          `{"signature": null}`,
+			expectedCheck:  func(p ProblemRequest) bool { return p.Signature == nil },
+			expectedReason: "no signature",
+		},
+		{
+			name:           "Generate starter code again for a new function name",
+			patch:          `{"functionName": "pairSum"}`,
+			expectedCheck:  func(p ProblemRequest) bool { return len(p.Code) == 1 && p.Code["python"] == handWritten },
+			expectedReason: "only the hand written starter code",
+		},
+		{
+			name:        "Reject unknown fields",
+			patch:       `{"name": "Edited"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "Reject fields of the wrong type",
+			patch:       `{"points": "many"}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var patch map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(testCase.patch), &patch); err != nil {
+				t.Fatalf("Failed to decode patch: %v", err)
+			}
+
+			patched, apiErr := PatchProblemRequest(current, patch)
+			if testCase.expectedErr {
+				if apiErr == nil {
+					t.Errorf("Expected an error, got %+v", patched)
+				}
+				return
+			}
+			if apiErr != nil {
+				t.Fatalf("Failed to patch: %s", apiErr.Message())
+			}
+			if !testCase.expectedCheck(patched) {
+				t.Errorf("Expected %s, got %+v", testCase.expectedReason, patched)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
 )
 
 // helper functions
@@ -19,3 +23,35 @@ func DecodeJSONRequest[T any](r *http.Request) (T, *apierror.APIError) {
 	}
 	return request, nil
 }
+
+// WithTx runs fn with queries in a transaction, which is only committed when fn succeeds
+func (h *Handler) WithTx(ctx context.Context, fn func(queries *sql.Queries) *apierror.APIError) *apierror.APIError {
+	tx, err := h.PostgresClient.Begin(ctx)
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to start transaction")
+	}
+	// Does nothing once committed, and still rolls back when ctx is done
+	defer tx.Rollback(context.Background())
+
+	if apiErr := fn(h.PostgresQueries.WithTx(tx)); apiErr != nil {
+		return apiErr
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to commit transaction")
+	}
+	return nil
+}
+
+// ExecutorError maps code execution backend failures to 503 and 504 responses.
+// It returns nil for any other error, which callers handle themselves.
+func ExecutorError(err error) *apierror.APIError {
+	switch {
+	case errors.Is(err, judge0.ErrTimeout):
+		return apierror.NewError(http.StatusGatewayTimeout, "Code execution timed out")
+	case errors.Is(err, judge0.ErrQueueFull):
+		return apierror.NewError(http.StatusServiceUnavailable, "Code execution is busy, try again later")
+	case errors.Is(err, judge0.ErrUnauthorized), errors.Is(err, judge0.ErrUnavailable):
+		return apierror.NewError(http.StatusServiceUnavailable, "Code execution is unavailable")
+	}
+	return nil
+}
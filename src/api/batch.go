@@ -0,0 +1,213 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// templateTestDelimiter starts the line the harness prints after each test case of a batch, followed by the
+// index of the test case and the seconds it took. Each batch adds a random nonce to it, see templateBatchDelimiter.
+const templateTestDelimiter = "#kadane-test-case"
+
+// templateBatchDelimiter is the delimiter of the batch with the nonce. The solution shares stdout with the harness,
+// the nonce keeps lines it prints from passing for the delimiter. Batches stored without a nonce use the bare one.
+func templateBatchDelimiter(nonce string) string {
+	if nonce == "" {
+		return templateTestDelimiter
+	}
+	return templateTestDelimiter + "-" + nonce
+}
+
+// templateTestLine matches the delimiter lines of the batch with the nonce
+func templateTestLine(nonce string) *regexp.Regexp {
+	return regexp.MustCompile(`\n` + regexp.QuoteMeta(templateBatchDelimiter(nonce)) + ` (\d+) (\d+(?:\.\d+)?)\n`)
+}
+
+// judge0 status ids of the verdicts split batch results get
+const (
+	batchStatusAccepted          = 3
+	batchStatusTimeLimitExceeded = 5
+	batchStatusRuntimeError      = 11 // NZEC
+)
+
+// BatchTestCases are the test cases of a batch submission, stored with its judge0 token in place of a single test case
+type BatchTestCases struct {
+	TestCases []TestCase `json:"testCases"`
+	TimeLimit float64    `json:"timeLimit,omitempty"` // seconds per test case, see TemplateBatchResults
+	Nonce     string     `json:"nonce,omitempty"`     // of the delimiter lines, see templateBatchDelimiter
+}
+
+// TemplateBatches splits the test cases into batches that each fit in judge0's time limits, see
+// ProblemLimits.BatchSize, and creates a submission for each with the limits applied. It returns no batches when
+// a batch can't fit more than one test case, the test cases run on their own then.
+func TemplateBatches(templateInput TemplateInput, testCases []TestCase, limits ProblemLimits) ([]judge0.Submission, []BatchTestCases, error) {
+	size := limits.BatchSize(templateInput.Language, len(testCases))
+	if size <= 1 {
+		return nil, nil, nil
+	}
+
+	var submissions []judge0.Submission
+	var batches []BatchTestCases
+	for start := 0; start < len(testCases); start += size {
+		batch := BatchTestCases{
+			TestCases: testCases[start:min(start+size, len(testCases))],
+			TimeLimit: limits.TestTimeLimit(templateInput.Language),
+			Nonce:     rand.Text(),
+		}
+		submission, err := TemplateBatch(templateInput, batch.TestCases, batch.Nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		submissions = append(submissions, limits.ApplyBatch(submission, templateInput.Language, len(batch.TestCases)))
+		batches = append(batches, batch)
+	}
+	return submissions, batches, nil
+}
+
+// TemplateBatch creates one judge0 submission running a solution against every test case, so the solution is
+// compiled and started once. The inputs of all test cases are passed on stdin as a single JSON array, the harness
+// runs the test cases in order and prints a delimiter line with the nonce and its time after the result of each,
+// see TemplateBatchResults.
+func TemplateBatch(templateInput TemplateInput, testCases []TestCase, nonce string) (judge0.Submission, error) {
+	language, ok := templateLanguages[templateInput.Language]
+	if !ok {
+		return judge0.Submission{}, fmt.Errorf("unsupported language %s", templateInput.Language)
+	}
+	if len(testCases) == 0 {
+		return judge0.Submission{}, errors.New("a batch needs at least one test case")
+	}
+
+	var inputs []TestCaseInput
+	tests := make([]string, len(testCases))
+	for i, testCase := range testCases {
+		var main string
+		if templateInput.Design != nil {
+			var calls []templateDesignCall
+			var err error
+			testCase, calls, err = templateDesignCalls(templateInput, testCase, len(inputs), language)
+			if err != nil {
+				return judge0.Submission{}, fmt.Errorf("test case %d: %w", i+1, err)
+			}
+			main = language.design(templateInput.FunctionName, calls)
+		} else {
+			if templateInput.Signature != nil {
+				testCase = templateInput.Signature.TestCase(testCase)
+			}
+			templateInput.TestCase = testCase
+			declarations, arguments := templateInputs(testCase.Input, len(inputs), language.argument)
+			main = language.main(templateInput, declarations, arguments)
+		}
+		inputs = append(inputs, testCase.Input...)
+		tests[i] = language.test(templateBatchDelimiter(nonce), i, main)
+	}
+
+	stdin, err := TemplateStdin(TestCase{Input: inputs})
+	if err != nil {
+		return judge0.Submission{}, err
+	}
+
+	return judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID(templateInput.Language),
+		SourceCode: language.program(templateInput.SourceCode, strings.Join(tests, "\n")),
+		Stdin:      stdin,
+	}, nil
+}
+
+// TemplateBatchResults splits the judge0 result of a batch into the results of its test cases. A test case
+// followed by its delimiter line finished: it gets the output before the line and the time on it, and is accepted
+// unless it took longer than the time limit (when set). The test case the program stopped in gets the result of
+// the whole program, a runtime error if it exited normally, and the test cases after it get the same verdict.
+func TemplateBatchResults(result judge0.SubmissionResult, batch BatchTestCases) []judge0.SubmissionResult {
+	count, timeLimit := len(batch.TestCases), batch.TimeLimit
+	results := make([]judge0.SubmissionResult, 0, count)
+	stdout := result.Stdout
+	start := 0
+	for _, match := range templateTestLine(batch.Nonce).FindAllStringSubmatchIndex(stdout, -1) {
+		if len(results) == count {
+			break
+		}
+		index, err := strconv.Atoi(stdout[match[2]:match[3]])
+		if err != nil || index != len(results) {
+			continue // printed by the solution itself
+		}
+		seconds, _ := strconv.ParseFloat(stdout[match[4]:match[5]], 64)
+
+		testResult := result
+		testResult.Stdout = stdout[start:match[0]]
+		testResult.Stderr = ""
+		testResult.Message = ""
+		testResult.ExitCode = 0
+		testResult.ExitSignal = 0
+		testResult.Time = strconv.FormatFloat(seconds, 'f', 3, 64)
+		testResult.WallTime = ""
+		testResult.Status.ID, testResult.Status.Description = batchStatusAccepted, string(sql.SubmissionStatusAccepted)
+		if timeLimit > 0 && seconds > timeLimit {
+			testResult.Status.ID, testResult.Status.Description = batchStatusTimeLimitExceeded, string(sql.SubmissionStatusTimeLimitExceeded)
+		}
+		results = append(results, testResult)
+		start = match[1]
+	}
+
+	if len(results) < count {
+		stopped := result
+		stopped.Stdout = stdout[start:]
+		if stopped.Status.Description == string(sql.SubmissionStatusAccepted) {
+			// The program exited without finishing the test case, e.g. the solution called exit
+			stopped.Status.ID, stopped.Status.Description = batchStatusRuntimeError, string(sql.SubmissionStatusRuntimeErrorNZEC)
+		}
+		results = append(results, stopped)
+
+		skipped := stopped
+		skipped.Stdout = ""
+		skipped.Stderr = ""
+		skipped.Time = ""
+		skipped.WallTime = ""
+		for len(results) < count {
+			results = append(results, skipped)
+		}
+	}
+	return results
+}
+
+// Judge0TokenResults reads the test cases stored with a judge0 token and pairs them with their results, the result
+// of a batch is split between its test cases
+func Judge0TokenResults(testCase []byte, batch bool, result judge0.SubmissionResult) ([]TestCase, []judge0.SubmissionResult, error) {
+	if !batch {
+		var single TestCase
+		if err := json.Unmarshal(testCase, &single); err != nil {
+			return nil, nil, err
+		}
+		return []TestCase{single}, []judge0.SubmissionResult{result}, nil
+	}
+
+	var batchTestCases BatchTestCases
+	if err := json.Unmarshal(testCase, &batchTestCases); err != nil {
+		return nil, nil, err
+	}
+	return batchTestCases.TestCases, TemplateBatchResults(result, batchTestCases), nil
+}
+
+// Judge0TokenStatuses reads the statuses graded when the result of a judge0 token was recorded, one per result of
+// Judge0TokenResults. It returns nil for results recorded before their statuses were stored.
+func Judge0TokenStatuses(statuses []byte, results int) ([]sql.SubmissionStatus, error) {
+	if statuses == nil {
+		return nil, nil
+	}
+
+	var tokenStatuses []sql.SubmissionStatus
+	if err := json.Unmarshal(statuses, &tokenStatuses); err != nil {
+		return nil, err
+	}
+	if len(tokenStatuses) != results {
+		return nil, fmt.Errorf("expected %d statuses, got %d", results, len(tokenStatuses))
+	}
+	return tokenStatuses, nil
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestTemplateBatches(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limits        ProblemLimits
+		count         int
+		expectedSizes []int
+	}{
+		{
+			name:          "Batch every test case without time limits",
+			count:         20,
+			expectedSizes: []int{20},
+		},
+		{
+			name:          "Batch every test case under the limits",
+			limits:        ProblemLimits{CPUTimeLimit: 1, WallTimeLimit: 1, TimeMultipliers: map[string]float64{"python": 1}},
+			count:         10,
+			expectedSizes: []int{10},
+		},
+		{
+			name:          "Split test cases over the CPU time limit",
+			limits:        ProblemLimits{CPUTimeLimit: 2, TimeMultipliers: map[string]float64{"python": 1}},
+			count:         20,
+			expectedSizes: []int{7, 7, 6},
+		},
+		{
+			name:          "Split test cases over the wall time limit",
+			limits:        ProblemLimits{CPUTimeLimit: 1, WallTimeLimit: 5, TimeMultipliers: map[string]float64{"python": 1}},
+			count:         10,
+			expectedSizes: []int{4, 4, 2},
+		},
+		{
+			name:          "Split test cases over the scaled time limit",
+			limits:        ProblemLimits{CPUTimeLimit: 1, TimeMultipliers: map[string]float64{"python": 3}},
+			count:         10,
+			expectedSizes: []int{5, 5},
+		},
+		{
+			name:   "Run test cases filling the time limit on their own",
+			limits: ProblemLimits{CPUTimeLimit: 10},
+			count:  3,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			batchTestCases := make([]TestCase, testCase.count)
+			for i := range batchTestCases {
+				batchTestCases[i] = TestCase{Input: []TestCaseInput{{Name: "value", Type: IntType, Value: strconv.Itoa(i)}}}
+			}
+			submissions, batches, err := TemplateBatches(TemplateInput{
+				Language:     "python",
+				FunctionName: "identity",
+				SourceCode:   "def identity(value):\n    return value",
+			}, batchTestCases, testCase.limits)
+			if err != nil {
+				t.Fatalf("Failed to create batches: %v", err)
+			}
+
+			var sizes []int
+			nonces := map[string]bool{}
+			for i, batch := range batches {
+				sizes = append(sizes, len(batch.TestCases))
+				nonces[batch.Nonce] = true
+				if submissions[i].CPUTimeLimit > judge0.MaxCPUTimeLimit || submissions[i].WallTimeLimit > judge0.MaxWallTimeLimit {
+					t.Errorf("Expected batch %d under judge0's time limits, got %v and %v", i, submissions[i].CPUTimeLimit, submissions[i].WallTimeLimit)
+				}
+				if !strings.Contains(submissions[i].SourceCode, templateBatchDelimiter(batch.Nonce)) {
+					t.Errorf("Expected batch %d to print its delimiter", i)
+				}
+			}
+			if !slices.Equal(sizes, testCase.expectedSizes) {
+				t.Errorf("Expected batch sizes %v, got %v", testCase.expectedSizes, sizes)
+			}
+			if len(submissions) != len(batches) || len(nonces) != len(batches) {
+				t.Errorf("Expected a submission and a nonce per batch, got %d submissions and %d nonces for %d batches", len(submissions), len(nonces), len(batches))
+			}
+		})
+	}
+}
+
+func TestTemplateBatchResults(t *testing.T) {
+	result := func(status sql.SubmissionStatus, stdout string) judge0.SubmissionResult {
+		var result judge0.SubmissionResult
+		result.Status.Description = string(status)
+		result.Stdout = stdout
+		return result
+	}
+
+	testCases := []struct {
+		name             string
+		result           judge0.SubmissionResult
+		timeLimit        float64
+		nonce            string
+		expectedStatuses []sql.SubmissionStatus
+		expectedOutputs  []string
+	}{
+		{
+			name:             "Split finished test cases",
+			result:           result(sql.SubmissionStatusAccepted, "1\n#kadane-test-case 0 0.010\n[2]\n#kadane-test-case 1 0.020\n"),
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted, sql.SubmissionStatusAccepted},
+			expectedOutputs:  []string{"1", "[2]"},
+		},
+		{
+			name:             "Split test case over the time limit",
+			result:           result(sql.SubmissionStatusAccepted, "1\n#kadane-test-case 0 0.010\n2\n#kadane-test-case 1 1.500\n"),
+			timeLimit:        1,
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted, sql.SubmissionStatusTimeLimitExceeded},
+			expectedOutputs:  []string{"1", "2"},
+		},
+		{
+			name:             "Split runtime error",
+			result:           result(sql.SubmissionStatusRuntimeErrorNZEC, "1\n#kadane-test-case 0 0.010\n"),
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted, sql.SubmissionStatusRuntimeErrorNZEC, sql.SubmissionStatusRuntimeErrorNZEC},
+			expectedOutputs:  []string{"1", "", ""},
+		},
+		{
+			name:             "Split program exiting early",
+			result:           result(sql.SubmissionStatusAccepted, "partial"),
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusRuntimeErrorNZEC},
+			expectedOutputs:  []string{"partial"},
+		},
+		{
+			name:             "Split delimiters printed by the solution",
+			result:           result(sql.SubmissionStatusAccepted, "\n#kadane-test-case 1 0.000\n1\n#kadane-test-case 0 0.010\n"),
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted},
+			expectedOutputs:  []string{"\n#kadane-test-case 1 0.000\n1"},
+		},
+		{
+			name:             "Split delimiters without the nonce",
+			result:           result(sql.SubmissionStatusAccepted, "\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000\n1\n#kadane-test-case-NONCE 0 0.010\n2\n#kadane-test-case-NONCE 1 0.020\n"),
+			nonce:            "NONCE",
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted, sql.SubmissionStatusAccepted},
+			expectedOutputs:  []string{"\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000\n1", "2"},
+		},
+		{
+			name:             "Split compilation error",
+			result:           result(sql.SubmissionStatusCompilationError, ""),
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusCompilationError, sql.SubmissionStatusCompilationError},
+			expectedOutputs:  []string{"", ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := TemplateBatchResults(testCase.result, BatchTestCases{
+				TestCases: make([]TestCase, len(testCase.expectedStatuses)),
+				TimeLimit: testCase.timeLimit,
+				Nonce:     testCase.nonce,
+			})
+
+			var statuses []sql.SubmissionStatus
+			var outputs []string
+			for _, result := range results {
+				statuses = append(statuses, sql.SubmissionStatus(result.Status.Description))
+				outputs = append(outputs, result.Stdout)
+			}
+			if !slices.Equal(statuses, testCase.expectedStatuses) {
+				t.Errorf("Expected statuses %v, got %v", testCase.expectedStatuses, statuses)
+			}
+			if !slices.Equal(outputs, testCase.expectedOutputs) {
+				t.Errorf("Expected outputs %q, got %q", testCase.expectedOutputs, outputs)
+			}
+		})
+	}
+}
+
+func TestJudge0TokenStatuses(t *testing.T) {
+	testCases := []struct {
+		name             string
+		statuses         []byte
+		results          int
+		expectedStatuses []sql.SubmissionStatus
+		expectedErr      bool
+	}{
+		{
+			name:             "Read stored statuses",
+			statuses:         []byte(`["Accepted", "Wrong Answer"]`),
+			results:          2,
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted, sql.SubmissionStatusWrongAnswer},
+		},
+		{
+			name:    "Read result recorded without statuses",
+			results: 1,
+		},
+		{
+			name:        "Read statuses of another number of results",
+			statuses:    []byte(`["Accepted"]`),
+			results:     2,
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			statuses, err := Judge0TokenStatuses(testCase.statuses, testCase.results)
+			if (err != nil) != testCase.expectedErr {
+				t.Fatalf("Expected error %v, got %v", testCase.expectedErr, err)
+			}
+			if !slices.Equal(statuses, testCase.expectedStatuses) {
+				t.Errorf("Expected statuses %v, got %v", testCase.expectedStatuses, statuses)
+			}
+		})
+	}
+}
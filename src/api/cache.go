@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/resultcache"
+)
+
+// ExecuteSubmissions runs submissions with the executor and waits for their results like
+// CreateSubmissionBatchAndWaitContext. Results cached for identical submissions are served without judge0, and
+// the deterministic results of the others are cached for the problem, 0 outside of a problem. Submissions that
+// never run twice, like batches with their nonce, pass cached false and skip the cache.
+func (h *Handler) ExecuteSubmissions(ctx context.Context, problemID int32, submissions []judge0.Submission, cached bool) ([]judge0.SubmissionResult, error) {
+	if h.ResultCache == nil || !cached {
+		return h.Executor.CreateSubmissionBatchAndWaitContext(ctx, submissions)
+	}
+
+	results := make([]judge0.SubmissionResult, len(submissions))
+	keys := make([]string, len(submissions))
+	var missing []int // indexes of submissions judge0 runs
+	for i, submission := range submissions {
+		keys[i] = resultcache.Key(submission)
+		if cached, ok := h.ResultCache.Get(ctx, keys[i]); ok {
+			results[i] = cached
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	run := make([]judge0.Submission, len(missing))
+	for i, index := range missing {
+		run[i] = submissions[index]
+	}
+	runResults, err := h.Executor.CreateSubmissionBatchAndWaitContext(ctx, run)
+
+	// Failures are reported at the index of the submission passed in, not of the ones judge0 ran
+	var batchErr *judge0.BatchError
+	if errors.As(err, &batchErr) {
+		remapped := &judge0.BatchError{Total: len(submissions), Errors: make(map[int]error, len(batchErr.Errors))}
+		for i, submissionErr := range batchErr.Errors {
+			remapped.Errors[missing[i]] = submissionErr
+		}
+		err = remapped
+	}
+
+	// Failed submissions come back as internal errors, which are never cached
+	for i, result := range runResults {
+		index := missing[i]
+		results[index] = result
+		if resultcache.Cacheable(result) {
+			h.ResultCache.Set(ctx, keys[index], problemID, result)
+		}
+	}
+	return results, err
+}
+
+// InvalidateProblemResults drops the cached results of a problem, its test cases changed
+func (h *Handler) InvalidateProblemResults(ctx context.Context, problemID int32) {
+	if h.ResultCache != nil {
+		h.ResultCache.InvalidateProblem(ctx, problemID)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/judge0/judge0test"
+	"kadane.xyz/go-backend/v2/src/resultcache"
+)
+
+func TestExecuteSubmissions(t *testing.T) {
+	testCases := []struct {
+		name         string
+		result       judge0test.Result
+		expectedRuns int // judge0 submissions after running the same submissions twice
+	}{
+		{
+			name:         "Serve accepted results from the cache",
+			result:       judge0test.Result{StatusID: judge0test.StatusAccepted, Stdout: "[0, 1]"},
+			expectedRuns: 2,
+		},
+		{
+			name:         "Serve runtime errors from the cache",
+			result:       judge0test.Result{StatusID: judge0test.StatusRuntimeErrorNZEC},
+			expectedRuns: 2,
+		},
+		{
+			name:         "Run time limits again",
+			result:       judge0test.Result{StatusID: judge0test.StatusTimeLimitExceeded},
+			expectedRuns: 4,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := judge0test.NewServer(judge0test.Sequence(testCase.result))
+			defer server.Close()
+			cachedHandler := Handler{Executor: server.Judge0Client(), ResultCache: resultcache.NewMemory(0)}
+
+			submissions := []judge0.Submission{
+				{LanguageID: 71, SourceCode: "print([0, 1])", Stdin: "[1]"},
+				{LanguageID: 71, SourceCode: "print([0, 1])", Stdin: "[2]"},
+			}
+			for range 2 {
+				results, err := cachedHandler.ExecuteSubmissions(context.Background(), 1, submissions, true)
+				if err != nil {
+					t.Fatalf("Failed to execute submissions: %v", err)
+				}
+				for _, result := range results {
+					if result.Status.ID != testCase.result.StatusID || result.Stdout != testCase.result.Stdout {
+						t.Errorf("Expected status %d with output %q, got %d with %q", testCase.result.StatusID, testCase.result.Stdout, result.Status.ID, result.Stdout)
+					}
+				}
+			}
+
+			if runs := len(server.Submissions()); runs != testCase.expectedRuns {
+				t.Errorf("Expected %d judge0 submissions, got %d", testCase.expectedRuns, runs)
+			}
+		})
+	}
+}
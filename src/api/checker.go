@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/checker"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// ProblemChecker selects how the outputs of a problem are compared with the expected outputs
+type ProblemChecker struct {
+	Mode    sql.CheckerMode `json:"mode,omitempty"`    // default, exact, token, float, unordered, any or custom
+	Epsilon float64         `json:"epsilon,omitempty"` // float tolerance, the checker default when zero
+	// Custom checker program, it reads {"input", "expectedOutput", "output"} as JSON from stdin and prints true or false
+	Language   string `json:"language,omitempty"`
+	SourceCode string `json:"sourceCode,omitempty"`
+}
+
+// NewProblemChecker reads the checker stored with a problem
+func NewProblemChecker(mode sql.CheckerMode, epsilon pgtype.Float8, language sql.NullProblemLanguage, sourceCode pgtype.Text) ProblemChecker {
+	return ProblemChecker{
+		Mode:       mode,
+		Epsilon:    epsilon.Float64,
+		Language:   string(language.ProblemLanguage),
+		SourceCode: sourceCode.String,
+	}
+}
+
+// ProblemCheckerFromRow reads the checker of a problem row
+func ProblemCheckerFromRow(problem sql.GetProblemRow) ProblemChecker {
+	return NewProblemChecker(problem.Checker, problem.CheckerEpsilon, problem.CheckerLanguage, problem.CheckerSourceCode)
+}
+
+// Validate checks the checker mode and the custom checker program
+func (c ProblemChecker) Validate() *apierror.APIError {
+	if c.Mode != "" && !slices.Contains(checker.Modes, checker.Mode(c.Mode)) {
+		return apierror.NewError(http.StatusBadRequest, "Invalid checker mode: "+string(c.Mode))
+	}
+	if c.Epsilon < 0 {
+		return apierror.NewError(http.StatusBadRequest, "Checker epsilon must not be negative")
+	}
+	if c.Mode != sql.CheckerModeCustom {
+		return nil
+	}
+
+	if c.SourceCode == "" {
+		return apierror.NewError(http.StatusBadRequest, "Missing custom checker source code")
+	}
+	return ValidateLanguage(c.Language)
+}
+
+// Params returns the checker columns of a problem
+func (c ProblemChecker) Params() (sql.CheckerMode, pgtype.Float8, sql.NullProblemLanguage, pgtype.Text) {
+	mode := c.Mode
+	if mode == "" {
+		mode = sql.CheckerModeDefault
+	}
+	return mode,
+		pgtype.Float8{Float64: c.Epsilon, Valid: c.Epsilon > 0},
+		sql.NullProblemLanguage{ProblemLanguage: sql.ProblemLanguage(c.Language), Valid: c.Language != ""},
+		pgtype.Text{String: c.SourceCode, Valid: c.SourceCode != ""}
+}
+
+// SubmissionChecker fetches the checker of the problem a submission was made for
+func (h *Handler) SubmissionChecker(ctx context.Context, submissionId pgtype.UUID) (ProblemChecker, error) {
+	row, err := h.PostgresQueries.GetSubmissionChecker(ctx, submissionId)
+	if err != nil {
+		return ProblemChecker{}, err
+	}
+	return NewProblemChecker(row.Checker, row.CheckerEpsilon, row.CheckerLanguage, row.CheckerSourceCode), nil
+}
+
+// NewChecker builds the checker, custom checker programs run on the handler's executor
+func (h *Handler) NewChecker(c ProblemChecker) (checker.Checker, error) {
+	if c.Mode != sql.CheckerModeCustom {
+		return checker.New(checker.Mode(c.Mode), c.Epsilon)
+	}
+
+	// The language may have been disabled since the checker was saved
+	entry, ok := judge0.Languages().Lookup(c.Language)
+	if !ok {
+		return nil, fmt.Errorf("unknown checker language %q", c.Language)
+	}
+	if !entry.Enabled {
+		return nil, fmt.Errorf("checker language %q is not available", c.Language)
+	}
+	return checker.NewCustom(h.Executor, entry.ID, c.SourceCode), nil
+}
+
+// GradeTestCases decides the status of every judge0 result. Results judge0 didn't accept keep their
+// status, the outputs of the others are checked. If the checker fails they are internal errors.
+func (h *Handler) GradeTestCases(ctx context.Context, problemChecker ProblemChecker, testCases []TestCase, responses []judge0.SubmissionResult) []sql.SubmissionStatus {
+	statuses := make([]sql.SubmissionStatus, len(responses))
+	var indexes []int
+	var cases []checker.Case
+
+	for i, resp := range responses {
+		// Compilers like mcs and kotlinc print warnings to compile output, only judge0's status fails a compile
+		if resp.Status.Description != "Accepted" {
+			statuses[i] = sql.SubmissionStatus(resp.Status.Description)
+			continue
+		}
+
+		input, err := json.Marshal(testCases[i].Input)
+		if err != nil {
+			statuses[i] = sql.SubmissionStatusInternalError
+			continue
+		}
+		indexes = append(indexes, i)
+		cases = append(cases, checker.Case{Input: input, Expected: testCases[i].Output, Output: resp.Stdout})
+	}
+	if len(cases) == 0 {
+		return statuses
+	}
+
+	outputChecker, err := h.NewChecker(problemChecker)
+	var accepted []bool
+	if err == nil {
+		accepted, err = outputChecker.Check(ctx, cases)
+	}
+	if err != nil {
+		log.Printf("Failed to check outputs: %v", err)
+		for _, i := range indexes {
+			statuses[i] = sql.SubmissionStatusInternalError
+		}
+		return statuses
+	}
+
+	for j, i := range indexes {
+		if accepted[j] {
+			statuses[i] = sql.SubmissionStatusAccepted
+		} else {
+			statuses[i] = sql.SubmissionStatusWrongAnswer
+		}
+	}
+	return statuses
+}
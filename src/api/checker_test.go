@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestGradeTestCases(t *testing.T) {
+	result := func(status sql.SubmissionStatus, stdout, compileOutput string) judge0.SubmissionResult {
+		var result judge0.SubmissionResult
+		result.Status.Description = string(status)
+		result.Stdout = stdout
+		result.CompileOutput = compileOutput
+		return result
+	}
+	twoSum := TestCase{
+		Input:  []TestCaseInput{{Name: "target", Type: IntType, Value: "9"}},
+		Output: "[0, 1]",
+	}
+
+	testCases := []struct {
+		name             string
+		checker          ProblemChecker
+		responses        []judge0.SubmissionResult
+		expectedStatuses []sql.SubmissionStatus
+	}{
+		{
+			name: "Grade C# compiled with warnings",
+			responses: []judge0.SubmissionResult{
+				result(sql.SubmissionStatusAccepted, "[0, 1]\n", "Main.cs(3,13): warning CS0168: The variable `unused' is declared but never used\nCompilation succeeded - 1 warning(s)\n"),
+			},
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusAccepted},
+		},
+		{
+			name: "Grade wrong answer compiled with warnings",
+			responses: []judge0.SubmissionResult{
+				result(sql.SubmissionStatusAccepted, "[1, 0]\n", "warning: variable 'unused' is never used\n"),
+			},
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusWrongAnswer},
+		},
+		{
+			name: "Grade compilation error",
+			responses: []judge0.SubmissionResult{
+				result(sql.SubmissionStatusCompilationError, "", "Main.cs(3,1): error CS1525: Unexpected symbol `}'\n"),
+			},
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusCompilationError},
+		},
+		{
+			name:    "Grade with a custom checker in an unknown language",
+			checker: ProblemChecker{Mode: sql.CheckerModeCustom, Language: "cobol", SourceCode: "print(true)"},
+			responses: []judge0.SubmissionResult{
+				result(sql.SubmissionStatusAccepted, "[0, 1]\n", ""),
+			},
+			expectedStatuses: []sql.SubmissionStatus{sql.SubmissionStatusInternalError},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			testCases := slices.Repeat([]TestCase{twoSum}, len(testCase.responses))
+			statuses := (&Handler{}).GradeTestCases(context.Background(), testCase.checker, testCases, testCase.responses)
+			if !slices.Equal(statuses, testCase.expectedStatuses) {
+				t.Errorf("Expected statuses %v, got %v", testCase.expectedStatuses, statuses)
+			}
+		})
+	}
+}
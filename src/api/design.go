@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// DesignMethod is a method of the class a design problem implements
+type DesignMethod struct {
+	Name       string               `json:"name"`
+	Parameters []SignatureParameter `json:"parameters"`
+	ReturnType TestCaseType         `json:"returnType"` // void for methods returning nothing
+}
+
+// ProblemDesign declares the class design problems (e.g. LRU Cache) implement. The class is named after
+// the problem's function name, and test cases construct it and call its methods in sequence.
+type ProblemDesign struct {
+	Constructor []SignatureParameter `json:"constructor"`
+	Methods     []DesignMethod       `json:"methods"`
+}
+
+// DesignCall is a call of a design test case, the first one constructs the class and is named after it
+type DesignCall struct {
+	Method    string            `json:"method"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+// ProblemDesignFromRow reads the class stored with a problem, function problems have none
+func ProblemDesignFromRow(design []byte) *ProblemDesign {
+	if len(design) == 0 {
+		return nil
+	}
+	var decoded ProblemDesign
+	if err := json.Unmarshal(design, &decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+// Validate checks the constructor and methods of the class
+func (d *ProblemDesign) Validate(className string) *apierror.APIError {
+	if !signatureIdentifier.MatchString(className) {
+		return apierror.NewError(http.StatusBadRequest, "Invalid design class name: "+className)
+	}
+	if len(d.Methods) == 0 {
+		return apierror.NewError(http.StatusBadRequest, "A design needs at least one method")
+	}
+	if apiErr := (&ProblemSignature{Parameters: d.Constructor, ReturnType: IntType}).Validate(); apiErr != nil {
+		return apiErr
+	}
+
+	names := map[string]bool{className: true}
+	for i, method := range d.Methods {
+		if !signatureIdentifier.MatchString(method.Name) || names[method.Name] {
+			return apierror.NewError(http.StatusBadRequest, "Invalid design method name: "+method.Name)
+		}
+		names[method.Name] = true
+
+		returnType := method.ReturnType
+		if returnType == VoidType {
+			returnType = IntType // validated as any other signature otherwise
+		}
+		signature := ProblemSignature{Parameters: method.Parameters, ReturnType: returnType}
+		if apiErr := signature.Validate(); apiErr != nil {
+			return apiErr
+		}
+		if method.ReturnType != VoidType && signature.ReturnType != method.ReturnType {
+			d.Methods[i].ReturnType = signature.ReturnType
+		}
+	}
+	return nil
+}
+
+// method finds a method of the class, the constructor is named after the class
+func (d *ProblemDesign) method(className string, name string) (DesignMethod, bool) {
+	if name == className {
+		return DesignMethod{Name: className, Parameters: d.Constructor, ReturnType: VoidType}, true
+	}
+	index := slices.IndexFunc(d.Methods, func(method DesignMethod) bool { return method.Name == name })
+	if index < 0 {
+		return DesignMethod{}, false
+	}
+	return d.Methods[index], true
+}
+
+// ValidateTestCase checks that a test case constructs the class first and calls its methods with arguments of their types
+func (d *ProblemDesign) ValidateTestCase(className string, testCase TestCase) *apierror.APIError {
+	if len(testCase.Calls) == 0 || testCase.Calls[0].Method != className {
+		return apierror.NewError(http.StatusBadRequest, "Invalid test case: the first call must construct "+className)
+	}
+	if len(testCase.Input) > 0 {
+		return apierror.NewError(http.StatusBadRequest, "Invalid test case: design test cases pass arguments in their calls")
+	}
+
+	for i, call := range testCase.Calls {
+		method, ok := d.method(className, call.Method)
+		if !ok || (i > 0 && call.Method == className) {
+			return apierror.NewError(http.StatusBadRequest, "Invalid test case: unknown method "+call.Method)
+		}
+		if len(call.Arguments) != len(method.Parameters) {
+			return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Invalid test case: %s takes %d arguments, found %d", call.Method, len(method.Parameters), len(call.Arguments)))
+		}
+	}
+
+	if _, err := TemplateStdin(d.TestCase(className, testCase)); err != nil {
+		return apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+	}
+	return nil
+}
+
+// TestCase flattens the arguments of every call into the inputs of the test case, in order, so they
+// are passed on stdin like the arguments of a function
+func (d *ProblemDesign) TestCase(className string, testCase TestCase) TestCase {
+	var inputs []TestCaseInput
+	for _, call := range testCase.Calls {
+		method, _ := d.method(className, call.Method)
+		for i, argument := range call.Arguments {
+			input := TestCaseInput{Name: call.Method, Value: string(argument)}
+			if i < len(method.Parameters) {
+				input.Name, input.Type = method.Parameters[i].Name, method.Parameters[i].Type
+			}
+			inputs = append(inputs, input)
+		}
+	}
+	testCase.Input = inputs
+	return testCase
+}
+
+// templateDesignCall is a call of a design test case with its arguments decoded by a language
+type templateDesignCall struct {
+	Method       DesignMethod
+	Declarations string // statements declaring the arguments, for languages passing them by reference
+	Arguments    string
+}
+
+// TemplateDesign creates the judge0 submission running a design test case's calls against a solution
+func TemplateDesign(templateInput TemplateInput) (judge0.Submission, error) {
+	language, ok := templateLanguages[templateInput.Language]
+	if !ok {
+		return judge0.Submission{}, fmt.Errorf("unsupported language %s", templateInput.Language)
+	}
+
+	testCase, calls, err := templateDesignCalls(templateInput, templateInput.TestCase, 0, language)
+	if err != nil {
+		return judge0.Submission{}, err
+	}
+	stdin, err := TemplateStdin(testCase)
+	if err != nil {
+		return judge0.Submission{}, err
+	}
+
+	return judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID(templateInput.Language),
+		SourceCode: language.program(templateInput.SourceCode, language.design(templateInput.FunctionName, calls)),
+		Stdin:      stdin,
+	}, nil
+}
+
+// templateDesignCalls decodes the arguments of a design test case's calls in a language, the first one being at
+// index offset of stdin. Returns the test case with the arguments as its inputs and the calls.
+func templateDesignCalls(templateInput TemplateInput, testCase TestCase, offset int, language templateLanguage) (TestCase, []templateDesignCall, error) {
+	className := templateInput.FunctionName
+	testCase = templateInput.Design.TestCase(className, testCase)
+
+	var calls []templateDesignCall
+	index := 0
+	for _, call := range testCase.Calls {
+		method, ok := templateInput.Design.method(className, call.Method)
+		if !ok {
+			return TestCase{}, nil, fmt.Errorf("unknown method %s", call.Method)
+		}
+		declarations, arguments := templateInputs(testCase.Input[index:index+len(call.Arguments)], offset+index, language.argument)
+		index += len(call.Arguments)
+		calls = append(calls, templateDesignCall{Method: method, Declarations: declarations, Arguments: arguments})
+	}
+	if len(calls) == 0 || calls[0].Method.Name != className {
+		return TestCase{}, nil, fmt.Errorf("the first call must construct %s", className)
+	}
+	return testCase, calls, nil
+}
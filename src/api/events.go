@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// submission event types sent over server-sent events
+const (
+	SubmissionEventTestCase = "testCase" // a single test case finished
+	SubmissionEventResult   = "result"   // the submission is graded, always the last event
+)
+
+// default values for submission event streams
+const (
+	submissionEventBuffer          = 16
+	submissionEventRefreshInterval = time.Second
+	submissionEventHeartbeat       = 15 * time.Second
+)
+
+// SubmissionEvent is published when a judge0 result is recorded. Result events carry no data,
+// streams load the graded submission from the database when they receive one.
+type SubmissionEvent struct {
+	Type string
+	Data any
+}
+
+// SubmissionTestCaseEvent is the verdict of a single test case, inputs and outputs are never streamed
+type SubmissionTestCaseEvent struct {
+	Index  int32                `json:"index"`
+	Status sql.SubmissionStatus `json:"status"`
+	Time   string               `json:"time"`
+	Memory int                  `json:"memory"`
+}
+
+// SubmissionEventHub fans out submission events to the streams of this instance.
+// Streams also refresh from the database, so events missed here (for example a judge0
+// callback handled by another instance) are still delivered, only later.
+type SubmissionEventHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan SubmissionEvent]struct{}
+}
+
+func NewSubmissionEventHub() *SubmissionEventHub {
+	return &SubmissionEventHub{
+		subscribers: make(map[uuid.UUID]map[chan SubmissionEvent]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of a submission and a function to stop receiving them
+func (hub *SubmissionEventHub) Subscribe(submissionId uuid.UUID) (<-chan SubmissionEvent, func()) {
+	if hub == nil {
+		return nil, func() {}
+	}
+
+	events := make(chan SubmissionEvent, submissionEventBuffer)
+
+	hub.mu.Lock()
+	if hub.subscribers[submissionId] == nil {
+		hub.subscribers[submissionId] = make(map[chan SubmissionEvent]struct{})
+	}
+	hub.subscribers[submissionId][events] = struct{}{}
+	hub.mu.Unlock()
+
+	unsubscribe := func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		delete(hub.subscribers[submissionId], events)
+		if len(hub.subscribers[submissionId]) == 0 {
+			delete(hub.subscribers, submissionId)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// Publish sends an event to every subscriber of the submission without blocking
+func (hub *SubmissionEventHub) Publish(submissionId uuid.UUID, event SubmissionEvent) {
+	if hub == nil {
+		return
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for events := range hub.subscribers[submissionId] {
+		select {
+		case events <- event:
+		default:
+			// Slow subscriber, the database refresh catches it up
+		}
+	}
+}
+
+// NewSubmissionTestCaseEvent reports a single graded judge0 result
+func NewSubmissionTestCaseEvent(index int32, result judge0.SubmissionResult, status sql.SubmissionStatus) SubmissionTestCaseEvent {
+	return SubmissionTestCaseEvent{
+		Index:  index,
+		Status: status,
+		Time:   result.Time,
+		Memory: result.Memory,
+	}
+}
+
+// IsSubmissionPending reports whether a submission is still waiting on judge0
+func IsSubmissionPending(status sql.SubmissionStatus) bool {
+	return status == sql.SubmissionStatusInQueue || status == sql.SubmissionStatusProcessing
+}
+
+// writeSubmissionEvent writes a single server-sent event and flushes it to the client
+func writeSubmissionEvent(w http.ResponseWriter, flusher http.Flusher, event SubmissionEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// submissionStream tracks what a single event stream already sent
+type submissionStream struct {
+	h            *Handler
+	w            http.ResponseWriter
+	flusher      http.Flusher
+	submissionId pgtype.UUID
+	userId       string
+	sent         map[int32]bool
+}
+
+// send writes an event, skipping test cases that were already sent. It reports whether the stream is done.
+func (s *submissionStream) send(event SubmissionEvent) (bool, error) {
+	if testCase, ok := event.Data.(SubmissionTestCaseEvent); ok {
+		if s.sent[testCase.Index] {
+			return false, nil
+		}
+		s.sent[testCase.Index] = true
+	}
+
+	if err := writeSubmissionEvent(s.w, s.flusher, event); err != nil {
+		return true, err
+	}
+	return event.Type == SubmissionEventResult, nil
+}
+
+// refresh sends every finished test case and the result from the database. It reports whether the stream is done.
+func (s *submissionStream) refresh(ctx context.Context) (bool, error) {
+	tokens, err := s.h.PostgresQueries.GetSubmissionJudge0Tokens(ctx, s.submissionId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, token := range tokens {
+		if token.Result == nil {
+			continue
+		}
+
+		var result judge0.SubmissionResult
+		if err := json.Unmarshal(token.Result, &result); err != nil {
+			return false, err
+		}
+		// A batch token holds the results of every test case from its index on
+		_, tokenResults, err := Judge0TokenResults(token.TestCase, token.Batch, result)
+		if err != nil {
+			return false, err
+		}
+		// Results are graded once when they are recorded, older ones without statuses only send the result
+		statuses, err := Judge0TokenStatuses(token.Statuses, len(tokenResults))
+		if err != nil {
+			return false, err
+		}
+
+		for i, status := range statuses {
+			if _, err := s.send(SubmissionEvent{
+				Type: SubmissionEventTestCase,
+				Data: NewSubmissionTestCaseEvent(token.TestCaseIndex+int32(i), tokenResults[i], status),
+			}); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	submission, err := s.h.PostgresQueries.GetSubmissionByID(ctx, sql.GetSubmissionByIDParams{
+		ID:     s.submissionId,
+		UserID: s.userId,
+	})
+	if err != nil {
+		return false, err
+	}
+	if IsSubmissionPending(submission.Status) {
+		return false, nil
+	}
+
+	response, err := SubmissionFromRow(submission)
+	if err != nil {
+		return false, err
+	}
+	return s.send(SubmissionEvent{Type: SubmissionEventResult, Data: response})
+}
+
+// GET: /submissions/{token}/events
+// Streams per test case verdicts as server-sent events, ending with the graded submission
+func (h *Handler) GetSubmissionEvents(w http.ResponseWriter, r *http.Request) {
+	// Get userid from middleware context
+	userId, err := GetClientUserID(w, r)
+	if err != nil {
+		return
+	}
+
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		apierror.SendError(w, http.StatusBadRequest, "Missing submission ID")
+		return
+	}
+
+	idUUID, err := uuid.Parse(token)
+	if err != nil {
+		apierror.SendError(w, http.StatusBadRequest, "Invalid submission ID")
+		return
+	}
+
+	submissionId := pgtype.UUID{Bytes: idUUID, Valid: true}
+	submission, err := h.PostgresQueries.GetSubmissionByID(r.Context(), sql.GetSubmissionByIDParams{
+		ID:     submissionId,
+		UserID: userId,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.SendError(w, http.StatusNotFound, "Submission not found")
+		return
+	}
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to get submission")
+		return
+	}
+	// Submissions of other accounts are not found, so their ids can't be probed
+	if submission.AccountID != userId {
+		apierror.SendError(w, http.StatusNotFound, "Submission not found")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.SendError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// Subscribe before reading the current state so no event is lost in between
+	events, unsubscribe := h.SubmissionEvents.Subscribe(idUUID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &submissionStream{
+		h:            h,
+		w:            w,
+		flusher:      flusher,
+		submissionId: submissionId,
+		userId:       userId,
+		sent:         make(map[int32]bool),
+	}
+
+	refresh := time.NewTicker(submissionEventRefreshInterval)
+	defer refresh.Stop()
+	heartbeat := time.NewTicker(submissionEventHeartbeat)
+	defer heartbeat.Stop()
+
+	done, err := stream.refresh(r.Context())
+	for !done && err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if event.Type == SubmissionEventResult {
+				done, err = stream.refresh(r.Context())
+			} else {
+				done, err = stream.send(event)
+			}
+		case <-refresh.C:
+			done, err = stream.refresh(r.Context())
+		case <-heartbeat.C:
+			// Comment line keeps proxies from closing an idle stream
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err == nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
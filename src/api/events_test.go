@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/judge0/judge0test"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestGetSubmissionEvents(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name:           "Stream invalid submission id",
+			urlParams:      map[string]string{"token": "not-a-uuid"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Stream missing submission",
+			urlParams:      map[string]string{"token": "00000000-0000-0000-0000-000000000000"},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequest(t, http.MethodGet, "/submissions/{token}/events", nil)
+			request = applyURLParams(request, testCase.urlParams)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.GetSubmissionEvents)
+		})
+	}
+}
+
+// judge0BatchAccept answers batch submissions with the two sum answer and a delimiter line, with the nonce found in
+// the source code, after each of up to count test cases, the delimiters past the test cases of the batch are ignored
+func judge0BatchAccept(count int) judge0test.Responder {
+	delimiter := regexp.MustCompile(regexp.QuoteMeta(templateTestDelimiter) + `-[A-Z2-7]+`)
+	return func(submission judge0.Submission) judge0test.Result {
+		var stdout strings.Builder
+		for i := range count {
+			fmt.Fprintf(&stdout, "[0, 1]\n%s %d 0.001\n", delimiter.FindString(submission.SourceCode), i)
+		}
+		return judge0test.Accept(stdout.String())(submission)
+	}
+}
+
+func TestStreamSubmissionEvents(t *testing.T) {
+	testCases := []struct {
+		name  string
+		batch bool
+	}{
+		{name: "Stream a submission with a token per test case"},
+		{name: "Stream a batched submission", batch: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			streamHandler := handler
+			if testCase.batch {
+				judge0Batch := judge0test.NewServer(judge0BatchAccept(100))
+				defer judge0Batch.Close()
+				streamHandler.Executor = judge0Batch.Judge0Client()
+				streamHandler.BatchTestCases = true
+			}
+
+			response, apiErr := streamHandler.ProcessSubmission(context.Background(), SubmissionRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				ProblemID:  1,
+			}, clientToken.UserID)
+			if apiErr != nil {
+				t.Fatalf("Failed to create submission: %s", apiErr.Message())
+			}
+
+			request := newTestRequest(t, http.MethodGet, "/submissions/{token}/events", nil)
+			request = applyURLParams(request, map[string]string{"token": response.Data.Id})
+
+			ctx, cancel := context.WithTimeout(request.Context(), 10*time.Second)
+			defer cancel()
+			request = request.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+			streamHandler.GetSubmissionEvents(w, request)
+
+			body := w.Body.String()
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, body)
+			}
+			if !strings.Contains(body, "event: "+SubmissionEventTestCase) {
+				t.Errorf("Expected test case events, got: %s", body)
+			}
+			if strings.LastIndex(body, "event: ") != strings.Index(body, "event: "+SubmissionEventResult) {
+				t.Errorf("Expected stream to end with a result event, got: %s", body)
+			}
+
+			// Every test case is sent once and passes, a batch is split between its test cases
+			var indexes []int32
+			for _, event := range strings.Split(body, "\n\n") {
+				data, ok := strings.CutPrefix(event, "event: "+SubmissionEventTestCase+"\ndata: ")
+				if !ok {
+					continue
+				}
+				var testCaseEvent SubmissionTestCaseEvent
+				if err := json.Unmarshal([]byte(data), &testCaseEvent); err != nil {
+					t.Fatalf("Failed to decode test case event %q: %v", data, err)
+				}
+				if testCaseEvent.Status != sql.SubmissionStatusAccepted {
+					t.Errorf("Expected test case %d to be %s, got %s", testCaseEvent.Index, sql.SubmissionStatusAccepted, testCaseEvent.Status)
+				}
+				indexes = append(indexes, testCaseEvent.Index)
+			}
+			slices.Sort(indexes)
+			for i, index := range indexes {
+				if index != int32(i) {
+					t.Fatalf("Expected test case indexes 0 to %d once each, got %v", len(indexes)-1, indexes)
+				}
+			}
+			if testCase.batch && len(indexes) < 2 {
+				t.Errorf("Expected the batch to be split into its test cases, got indexes %v", indexes)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/resultcache"
 	"kadane.xyz/go-backend/v2/src/sql/sql"
 )
 
@@ -14,5 +15,16 @@ type Handler struct {
 	AWSRegion       string
 	AWSBucketAvatar string
 	CloudFrontUrl   string
-	Judge0Client    *judge0.Judge0Client
+	Executor        judge0.Executor
+	// SubmissionEvents streams submission progress, events are only read from the database when nil
+	SubmissionEvents *SubmissionEventHub
+	// Judge0CallbackURL is the base url judge0 reaches this api on, submissions are polled when empty
+	Judge0CallbackURL    string
+	Judge0CallbackSecret string
+	// BatchTestCases runs all test cases of a run or submission in one judge0 submission, see TemplateBatch
+	BatchTestCases bool
+	// ResultCache serves results of identical submissions without judge0, nothing is cached when nil
+	ResultCache resultcache.Cache
+	// Quotas limits the runs and submissions of each plan, nothing is limited when nil
+	Quotas *Quotas
 }
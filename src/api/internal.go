@@ -0,0 +1,46 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// PUT: /internal/judge0/submissions
+// Judge0 calls this once a submission created with a callback_url finishes, authenticated with the callback secret
+// as basic auth password
+func (h *Handler) Judge0SubmissionCallback(w http.ResponseWriter, r *http.Request) {
+	_, secret, _ := r.BasicAuth()
+	if h.Judge0CallbackSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.Judge0CallbackSecret)) != 1 {
+		apierror.SendError(w, http.StatusUnauthorized, "Invalid callback secret")
+		return
+	}
+
+	result, apiErr := DecodeJSONRequest[judge0.SubmissionResult](r)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	if result.Token == "" {
+		apierror.SendError(w, http.StatusBadRequest, "Missing submission token")
+		return
+	}
+
+	// Submissions are created base64 encoded so judge0 sends the callback encoded as well
+	result, err := judge0.DecodeSubmissionResult(result)
+	if err != nil {
+		apierror.SendError(w, http.StatusBadRequest, "Invalid submission result encoding")
+		return
+	}
+
+	apiErr = h.RecordSubmissionResult(r.Context(), result)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestJudge0SubmissionCallback(t *testing.T) {
+	callbackHandler := handler
+	callbackHandler.Judge0CallbackSecret = "secret"
+
+	finished := map[string]any{
+		"token":  "unknown-token",
+		"stdout": "WzAsIDFd", // base64 "[0, 1]"
+		"status": map[string]any{"id": 3, "description": "Accepted"},
+	}
+
+	testCases := []struct {
+		TestingCase
+		secret string
+	}{
+		{
+			TestingCase: TestingCase{name: "Callback without secret", body: finished, expectedStatus: http.StatusUnauthorized},
+		},
+		{
+			TestingCase: TestingCase{name: "Callback with invalid secret", body: finished, expectedStatus: http.StatusUnauthorized},
+			secret:      "wrong",
+		},
+		{
+			TestingCase: TestingCase{
+				name:           "Callback with secret in the query",
+				queryParams:    map[string]string{"secret": "secret"},
+				body:           finished,
+				expectedStatus: http.StatusUnauthorized,
+			},
+		},
+		{
+			TestingCase: TestingCase{
+				name:           "Callback without token",
+				body:           map[string]any{"status": map[string]any{"id": 3, "description": "Accepted"}},
+				expectedStatus: http.StatusBadRequest,
+			},
+			secret: "secret",
+		},
+		{
+			TestingCase: TestingCase{name: "Callback for unknown token", body: finished, expectedStatus: http.StatusNotFound},
+			secret:      "secret",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPut, Judge0CallbackPath, testCase.body)
+			request = applyQueryParams(request, testCase.queryParams)
+			if testCase.secret != "" {
+				request.SetBasicAuth("judge0", testCase.secret)
+			}
+
+			executeTestRequest(t, request, testCase.expectedStatus, callbackHandler.Judge0SubmissionCallback)
+		})
+	}
+}
+
+func TestJudge0CallbackURL(t *testing.T) {
+	callbackHandler := handler
+	callbackHandler.Judge0CallbackURL = "https://api.kadane.xyz"
+	callbackHandler.Judge0CallbackSecret = "s3cr/t"
+
+	callbackURL, err := url.Parse(callbackHandler.judge0CallbackURL())
+	if err != nil {
+		t.Fatalf("Failed to parse callback url: %v", err)
+	}
+	if secret, _ := callbackURL.User.Password(); secret != "s3cr/t" || callbackURL.RawQuery != "" {
+		t.Errorf("Expected the secret only as basic auth password, got %s", callbackURL.Redacted())
+	}
+	if callbackURL.Path != Judge0CallbackPath {
+		t.Errorf("Expected callback path %s, got %s", Judge0CallbackPath, callbackURL.Path)
+	}
+
+	callbackHandler.Judge0CallbackURL = ""
+	if callbackURL := callbackHandler.judge0CallbackURL(); callbackURL != "" {
+		t.Errorf("Expected no callback url without callbacks, got %s", callbackURL)
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+type LanguagesResponse struct {
+	Data []judge0.RegistryLanguage `json:"data"`
+}
+
+// ValidateLanguage checks that a language is registered and enabled for solving problems
+func ValidateLanguage(language string) *apierror.APIError {
+	entry, ok := judge0.Languages().Lookup(language)
+	if !ok {
+		return apierror.NewError(http.StatusBadRequest, "Invalid language: "+language)
+	}
+	if !entry.Enabled {
+		return apierror.NewError(http.StatusBadRequest, "Language is not available: "+language)
+	}
+	return nil
+}
+
+// GET: /languages
+func (h *Handler) GetLanguages(w http.ResponseWriter, r *http.Request) {
+	response := LanguagesResponse{
+		Data: judge0.Languages().Languages(),
+	}
+
+	SendJSONResponse(w, http.StatusOK, response)
+}
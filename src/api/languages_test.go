@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetLanguages(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name:           "Get languages",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequest(t, http.MethodGet, "/languages", nil)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.GetLanguages)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// ProblemLimits are the judge0 resource limits of a problem. Zero values fall back to judge0's defaults.
+type ProblemLimits struct {
+	CPUTimeLimit  float64 `json:"cpuTimeLimit,omitempty"`  // seconds
+	WallTimeLimit float64 `json:"wallTimeLimit,omitempty"` // seconds
+	MemoryLimit   int32   `json:"memoryLimit,omitempty"`   // kilobytes
+	StackLimit    int32   `json:"stackLimit,omitempty"`    // kilobytes
+	// TimeMultipliers scale the time limits per language, overriding the language registry defaults
+	TimeMultipliers map[string]float64 `json:"timeMultipliers,omitempty"`
+}
+
+// ProblemLimitsFromRow reads the limits stored with a problem
+func ProblemLimitsFromRow(problem sql.GetProblemRow) ProblemLimits {
+	limits := ProblemLimits{
+		CPUTimeLimit:  problem.CpuTimeLimit.Float64,
+		WallTimeLimit: problem.WallTimeLimit.Float64,
+		MemoryLimit:   problem.MemoryLimit.Int32,
+		StackLimit:    problem.StackLimit.Int32,
+	}
+	if len(problem.TimeLimitMultipliers) > 0 {
+		// A malformed override only loses the override, the language defaults still apply
+		_ = json.Unmarshal(problem.TimeLimitMultipliers, &limits.TimeMultipliers)
+	}
+	return limits
+}
+
+// Validate checks the limits against what judge0 accepts
+func (l ProblemLimits) Validate() *apierror.APIError {
+	if l.CPUTimeLimit < 0 || l.CPUTimeLimit > judge0.MaxCPUTimeLimit {
+		return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("CPU time limit must be between 0 and %g seconds", judge0.MaxCPUTimeLimit))
+	}
+	if l.WallTimeLimit < 0 || l.WallTimeLimit > judge0.MaxWallTimeLimit {
+		return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Wall time limit must be between 0 and %g seconds", judge0.MaxWallTimeLimit))
+	}
+	if l.MemoryLimit < 0 || l.MemoryLimit > judge0.MaxMemoryLimit {
+		return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Memory limit must be between 0 and %d KB", judge0.MaxMemoryLimit))
+	}
+	if l.StackLimit < 0 || l.StackLimit > judge0.MaxStackLimit {
+		return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Stack limit must be between 0 and %d KB", judge0.MaxStackLimit))
+	}
+	for language, multiplier := range l.TimeMultipliers {
+		if apiErr := ValidateLanguage(language); apiErr != nil {
+			return apiErr
+		}
+		if multiplier <= 0 {
+			return apierror.NewError(http.StatusBadRequest, "Time multiplier must be greater than 0 for "+language)
+		}
+	}
+	return nil
+}
+
+// TimeMultiplier returns the factor time limits are scaled by for a language
+func (l ProblemLimits) TimeMultiplier(language string) float64 {
+	if multiplier, ok := l.TimeMultipliers[language]; ok {
+		return multiplier
+	}
+	if entry, ok := judge0.Languages().Lookup(language); ok && entry.TimeMultiplier > 0 {
+		return entry.TimeMultiplier
+	}
+	return 1
+}
+
+// Apply sets the limits on a submission in the given language, capped at judge0's maximums
+func (l ProblemLimits) Apply(submission judge0.Submission, language string) judge0.Submission {
+	multiplier := l.TimeMultiplier(language)
+	if l.CPUTimeLimit > 0 {
+		submission.CPUTimeLimit = min(l.CPUTimeLimit*multiplier, judge0.MaxCPUTimeLimit)
+	}
+	if l.WallTimeLimit > 0 {
+		submission.WallTimeLimit = min(l.WallTimeLimit*multiplier, judge0.MaxWallTimeLimit)
+	}
+	submission.MemoryLimit = int(l.MemoryLimit)
+	submission.StackLimit = int(l.StackLimit)
+	return submission
+}
+
+// ApplyBatch sets the limits on a batch running count test cases, see TemplateBatch. The time limits are scaled by
+// the number of test cases up to judge0's maximums, or are the maximums when the problem has none, and each test
+// case is held to TestTimeLimit once the batch is split. Batches of BatchSize test cases stay under the maximums.
+func (l ProblemLimits) ApplyBatch(submission judge0.Submission, language string, count int) judge0.Submission {
+	multiplier := l.TimeMultiplier(language) * float64(count)
+	submission.CPUTimeLimit = judge0.MaxCPUTimeLimit
+	if l.CPUTimeLimit > 0 {
+		submission.CPUTimeLimit = min(l.CPUTimeLimit*multiplier, judge0.MaxCPUTimeLimit)
+	}
+	submission.WallTimeLimit = judge0.MaxWallTimeLimit
+	if l.WallTimeLimit > 0 {
+		submission.WallTimeLimit = min(l.WallTimeLimit*multiplier, judge0.MaxWallTimeLimit)
+	}
+	submission.MemoryLimit = int(l.MemoryLimit)
+	submission.StackLimit = int(l.StackLimit)
+	return submission
+}
+
+// BatchSize is how many of count test cases a batch runs, at most as many as get their whole time limits in a
+// language under judge0's maximums. Problems without time limits run every test case in one batch.
+func (l ProblemLimits) BatchSize(language string, count int) int {
+	size := count
+	multiplier := l.TimeMultiplier(language)
+	if l.CPUTimeLimit > 0 {
+		size = min(size, int(judge0.MaxCPUTimeLimit/(l.CPUTimeLimit*multiplier)))
+	}
+	if l.WallTimeLimit > 0 {
+		size = min(size, int(judge0.MaxWallTimeLimit/(l.WallTimeLimit*multiplier)))
+	}
+	return max(size, 1)
+}
+
+// TestTimeLimit is the time limit of a single test case in a language, zero when the problem has none
+func (l ProblemLimits) TestTimeLimit(language string) float64 {
+	if l.CPUTimeLimit <= 0 {
+		return 0
+	}
+	return min(l.CPUTimeLimit*l.TimeMultiplier(language), judge0.MaxCPUTimeLimit)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"kadane.xyz/go-backend/v2/src/db"
+	"kadane.xyz/go-backend/v2/src/judge0/judge0test"
 	"kadane.xyz/go-backend/v2/src/middleware"
 	"kadane.xyz/go-backend/v2/src/sql/sql"
 )
@@ -20,6 +21,10 @@ var clientToken middleware.ClientContext = middleware.ClientContext{
 
 var handler Handler
 
+// judge0Server is the fake judge0 instance the handler executes code against.
+// Every submission is accepted with the two sum answer of the sample data.
+var judge0Server *judge0test.Server
+
 // TestMain runs once for the entire package.
 // It sets up the container, runs all tests, and tears down afterward.
 func TestMain(m *testing.M) {
@@ -30,12 +35,16 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
+	// Setup the fake judge0 server
+	judge0Server = judge0test.NewServer(judge0test.Accept("[0, 1]"))
+
 	// Setup the handler
 	db := db.DBPool
 	queries := sql.New(db)
 	handler = Handler{
 		PostgresClient:  db,
 		PostgresQueries: queries,
+		Executor:        judge0Server.Judge0Client(),
 	}
 
 	// Run all tests in the package.
@@ -43,6 +52,7 @@ func TestMain(m *testing.M) {
 
 	// Clean up the container after all tests are done.
 	db.Close()
+	judge0Server.Close()
 
 	os.Exit(exitCode)
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// PlaygroundRequest runs a program as is, without a problem or the test case harness
+type PlaygroundRequest struct {
+	Language             string `json:"language"`
+	SourceCode           string `json:"sourceCode"`
+	Stdin                string `json:"stdin"`
+	CompilerOptions      string `json:"compilerOptions"`
+	CommandLineArguments string `json:"commandLineArguments"`
+}
+
+// PlaygroundResult is the raw outcome of a playground run
+type PlaygroundResult struct {
+	Language      string               `json:"language"`
+	Status        sql.SubmissionStatus `json:"status"` // Accepted unless it failed to compile or run
+	Stdout        string               `json:"stdout"`
+	Stderr        string               `json:"stderr"`
+	CompileOutput string               `json:"compileOutput"`
+	Message       string               `json:"message"`
+	ExitCode      int                  `json:"exitCode"`
+	Time          string               `json:"time"`
+	Memory        int                  `json:"memory"`
+}
+
+type PlaygroundResultResponse struct {
+	Data *PlaygroundResult `json:"data"`
+}
+
+// ValidatePlaygroundRequest decodes and validates a playground request
+func ValidatePlaygroundRequest(r *http.Request) (PlaygroundRequest, *apierror.APIError) {
+	request, apiErr := DecodeJSONRequest[PlaygroundRequest](r)
+	if apiErr != nil {
+		return PlaygroundRequest{}, apiErr
+	}
+
+	if request.Language == "" {
+		return PlaygroundRequest{}, apierror.NewError(http.StatusBadRequest, "Missing language")
+	}
+	if apiErr := ValidateLanguage(request.Language); apiErr != nil {
+		return PlaygroundRequest{}, apiErr
+	}
+	if request.SourceCode == "" {
+		return PlaygroundRequest{}, apierror.NewError(http.StatusBadRequest, "Missing source code")
+	}
+	if len(request.CompilerOptions) > judge0.MaxCompilerOptionsLength {
+		return PlaygroundRequest{}, apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Compiler options must be at most %d characters", judge0.MaxCompilerOptionsLength))
+	}
+	if len(request.CommandLineArguments) > judge0.MaxCommandLineArgumentsLength {
+		return PlaygroundRequest{}, apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Command line arguments must be at most %d characters", judge0.MaxCommandLineArgumentsLength))
+	}
+
+	return request, nil
+}
+
+// ExecutePlaygroundRun runs the program with judge0 and waits for its result
+func (h *Handler) ExecutePlaygroundRun(r *http.Request, request PlaygroundRequest) (*PlaygroundResultResponse, *apierror.APIError) {
+	submission := judge0.Submission{
+		LanguageID:           judge0.LanguageToLanguageID(request.Language),
+		SourceCode:           request.SourceCode,
+		Stdin:                request.Stdin,
+		CompilerOptions:      request.CompilerOptions,
+		CommandLineArguments: request.CommandLineArguments,
+	}
+
+	results, err := h.Executor.CreateSubmissionBatchAndWaitContext(r.Context(), []judge0.Submission{submission})
+	var batchErr *judge0.BatchError
+	if r.Context().Err() != nil {
+		// Client went away, judge0 polling already stopped
+		return nil, apierror.NewError(http.StatusRequestTimeout, "Run cancelled")
+	} else if apiErr := ExecutorError(err); apiErr != nil {
+		log.Printf("Playground run failed: %v", err)
+		return nil, apiErr
+	} else if errors.As(err, &batchErr) {
+		// Judge0 rejects options it doesn't allow, e.g. compiler options of interpreted languages
+		return nil, apierror.NewError(http.StatusBadRequest, "Invalid run: "+results[0].Message)
+	} else if err != nil || len(results) == 0 {
+		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create run")
+	}
+
+	result := results[0]
+	return &PlaygroundResultResponse{
+		Data: &PlaygroundResult{
+			Language:      request.Language,
+			Status:        sql.SubmissionStatus(result.Status.Description),
+			Stdout:        result.Stdout,
+			Stderr:        result.Stderr,
+			CompileOutput: result.CompileOutput,
+			Message:       result.Message,
+			ExitCode:      result.ExitCode,
+			Time:          result.Time,
+			Memory:        result.Memory,
+		},
+	}, nil
+}
+
+// POST: /runs/playground
+func (h *Handler) CreatePlaygroundRunRoute(w http.ResponseWriter, r *http.Request) {
+	// Only signed in users run code
+	if _, err := GetClientUserID(w, r); err != nil {
+		return
+	}
+
+	request, apiErr := ValidatePlaygroundRequest(r)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	ctx, release, ok := h.AcquireExecutionQuota(w, r, QuotaRuns)
+	if !ok {
+		return
+	}
+	defer release()
+
+	response, apiErr := h.ExecutePlaygroundRun(r.WithContext(ctx), request)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	SendJSONResponse(w, http.StatusCreated, response)
+}
@@ -2,11 +2,18 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"kadane.xyz/go-backend/v2/src/apierror"
 	"kadane.xyz/go-backend/v2/src/sql/sql"
 )
@@ -39,6 +46,12 @@ type ProblemRequest struct {
 	Points       int32                `json:"points"`
 	Solutions    map[string]string    `json:"solutions"` // ["language": "sourceCode"]
 	TestCases    []TestCase           `json:"testCases"`
+	Limits       ProblemLimits        `json:"limits"`
+	Checker      ProblemChecker       `json:"checker"`
+	// Signature declares the function, starter code is generated for the languages missing from code
+	Signature *ProblemSignature `json:"signature,omitempty"`
+	// Design declares the class of design problems, whose test cases are calls instead of inputs
+	Design *ProblemDesign `json:"design,omitempty"`
 }
 
 type Problem struct {
@@ -57,6 +70,9 @@ type Problem struct {
 	Solved        bool                  `json:"solved"`
 	TotalAttempts int32                 `json:"totalAttempts"`
 	TotalCorrect  int32                 `json:"totalCorrect"`
+	Limits        *ProblemLimits        `json:"limits,omitempty"`
+	Signature     *ProblemSignature     `json:"signature,omitempty"`
+	Design        *ProblemDesign        `json:"design,omitempty"`
 }
 
 type ProblemResponse struct {
@@ -212,111 +228,448 @@ func (h *Handler) GetProblemsRoute(w http.ResponseWriter, r *http.Request) {
 	SendJSONResponse(w, http.StatusOK, response)
 }
 
+// CreateProblemRequestValidate checks every field of a problem, the error lists each invalid one
 func CreateProblemRequestValidate(request ProblemRequest) *apierror.APIError {
+	var fields []apierror.FieldError
+	add := func(field, message string) {
+		fields = append(fields, apierror.FieldError{Field: field, Message: message})
+	}
+	check := func(field string, apiErr *apierror.APIError) {
+		if apiErr != nil {
+			add(field, apiErr.Message())
+		}
+	}
+
 	// Check problem fields
-	if request.Title == "" || request.Description == "" || request.FunctionName == "" || len(request.Solutions) == 0 {
-		return apierror.NewError(http.StatusBadRequest, "Title, description, function name, and solution are required")
+	if request.Title == "" {
+		add("title", "Title is required")
+	}
+	if request.Description == "" {
+		add("description", "Description is required")
+	}
+	if request.FunctionName == "" {
+		add("functionName", "Function name is required")
+	}
+	if len(request.Solutions) == 0 {
+		add("solutions", "Solution is required")
+	}
+	if len(request.Code) == 0 && request.Signature == nil {
+		add("code", "At least one code is required")
+	}
+	if request.Points < 0 {
+		add("points", "Points must not be negative")
 	}
 
-	if len(request.Code) == 0 {
-		return apierror.NewError(http.StatusBadRequest, "At least one code is required")
+	switch sql.ProblemDifficulty(request.Difficulty) {
+	case sql.ProblemDifficultyEasy, sql.ProblemDifficultyMedium, sql.ProblemDifficultyHard:
+	default:
+		add("difficulty", "Difficulty must be easy, medium or hard")
 	}
 
-	if request.Points < 0 {
-		return apierror.NewError(http.StatusBadRequest, "Points must be greater than 0")
+	check("limits", request.Limits.Validate())
+	check("checker", request.Checker.Validate())
+
+	if request.Signature != nil {
+		check("signature", request.Signature.Validate())
 	}
 
-	if len(request.Solutions) == 0 {
-		return apierror.NewError(http.StatusBadRequest, "Solution is required")
+	if request.Design != nil {
+		if request.Signature != nil {
+			add("design", "A problem has either a signature or a design")
+		} else {
+			check("design", request.Design.Validate(request.FunctionName))
+		}
 	}
 
+	// Test case inputs must have a known type and a value of it, and match the signature if there is one
+	for i, testCase := range request.TestCases {
+		field := fmt.Sprintf("testCases[%d]", i)
+		if testCase.Visibility != sql.VisibilityPublic && testCase.Visibility != sql.VisibilityPrivate {
+			add(field+".visibility", "Invalid test case: visibility must be public or private")
+		}
+		if request.Design != nil {
+			check(field, request.Design.ValidateTestCase(request.FunctionName, testCase))
+			continue
+		}
+		if len(testCase.Calls) > 0 {
+			add(field+".calls", "Invalid test case: only design problems have calls")
+			continue
+		}
+		if request.Signature != nil {
+			if apiErr := request.Signature.ValidateTestCase(testCase); apiErr != nil {
+				check(field+".input", apiErr)
+				continue
+			}
+		}
+		if _, err := TemplateStdin(testCase); err != nil {
+			add(field+".input", "Invalid test case: "+err.Error())
+		}
+	}
+
+	// Starter code and solutions must be in languages problems can be solved in
+	for _, language := range slices.Sorted(maps.Keys(request.Code)) {
+		check("code."+language, ValidateLanguage(language))
+	}
+	for _, language := range slices.Sorted(maps.Keys(request.Solutions)) {
+		if apiErr := ValidateLanguage(language); apiErr != nil {
+			check("solutions."+language, apiErr)
+			continue
+		}
+		if request.Signature != nil {
+			if err := request.Signature.Check(language, request.Solutions[language], request.FunctionName); err != nil {
+				add("solutions."+language, "Solution doesn't match the signature: "+err.Error())
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return apierror.NewFieldsError(http.StatusBadRequest, fields)
+	}
 	return nil
 }
 
-func (h *Handler) CreateProblem(request ProblemRequest) (*CreateProblemResponse, *apierror.APIError) {
-	problemID, err := h.PostgresQueries.CreateProblem(context.Background(), sql.CreateProblemParams{
-		Title:        request.Title,
-		Description:  request.Description,
-		FunctionName: request.FunctionName,
-		Points:       request.Points,
-		Tags:         request.Tags,
-		Difficulty:   sql.ProblemDifficulty(request.Difficulty),
-	})
+// CreateProblem creates the problem with its parts and first version in one transaction, nothing is created
+// when any of them fails
+func (h *Handler) CreateProblem(ctx context.Context, request ProblemRequest, createdBy string) (*CreateProblemResponse, *apierror.APIError) {
+	params, parts, err := ProblemColumns(request)
 	if err != nil {
 		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create problem")
 	}
 
+	var problemID int32
+	apiErr := h.WithTx(ctx, func(queries *sql.Queries) *apierror.APIError {
+		var err error
+		problemID, err = queries.CreateProblem(ctx, params)
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create problem")
+		}
+		if apiErr := createProblemParts(ctx, queries, problemID, parts); apiErr != nil {
+			return apiErr
+		}
+		return createProblemVersion(ctx, queries, problemID, 1, request, createdBy)
+	})
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return &CreateProblemResponse{
+		Data: CreateProblemData{
+			ProblemID: strconv.Itoa(int(problemID)),
+		},
+	}, nil
+}
+
+// ProblemColumns returns the problem columns of a request, along with the request including the starter code
+// generated from its signature
+func ProblemColumns(request ProblemRequest) (sql.CreateProblemParams, ProblemRequest, error) {
+	var timeLimitMultipliers []byte
+	if len(request.Limits.TimeMultipliers) > 0 {
+		var err error
+		timeLimitMultipliers, err = json.Marshal(request.Limits.TimeMultipliers)
+		if err != nil {
+			return sql.CreateProblemParams{}, request, err
+		}
+	}
+
+	var signature []byte
+	if request.Signature != nil {
+		var err error
+		signature, err = json.Marshal(request.Signature)
+		if err != nil {
+			return sql.CreateProblemParams{}, request, err
+		}
+
+		// Languages without hand written starter code get a generated stub
+		code := ProblemRequestCode{}
+		for language, stub := range request.Signature.Stubs(request.FunctionName) {
+			code[language] = stub
+		}
+		for language, starter := range request.Code {
+			code[language] = starter
+		}
+		request.Code = code
+	}
+
+	var design []byte
+	if request.Design != nil {
+		var err error
+		design, err = json.Marshal(request.Design)
+		if err != nil {
+			return sql.CreateProblemParams{}, request, err
+		}
+	}
+
+	limits := request.Limits
+	checkerMode, checkerEpsilon, checkerLanguage, checkerSourceCode := request.Checker.Params()
+	return sql.CreateProblemParams{
+		Title:                request.Title,
+		Description:          request.Description,
+		FunctionName:         request.FunctionName,
+		Points:               request.Points,
+		Tags:                 request.Tags,
+		Difficulty:           sql.ProblemDifficulty(request.Difficulty),
+		CpuTimeLimit:         pgtype.Float8{Float64: limits.CPUTimeLimit, Valid: limits.CPUTimeLimit > 0},
+		WallTimeLimit:        pgtype.Float8{Float64: limits.WallTimeLimit, Valid: limits.WallTimeLimit > 0},
+		MemoryLimit:          pgtype.Int4{Int32: limits.MemoryLimit, Valid: limits.MemoryLimit > 0},
+		StackLimit:           pgtype.Int4{Int32: limits.StackLimit, Valid: limits.StackLimit > 0},
+		TimeLimitMultipliers: timeLimitMultipliers,
+		Checker:              checkerMode,
+		CheckerEpsilon:       checkerEpsilon,
+		CheckerLanguage:      checkerLanguage,
+		CheckerSourceCode:    checkerSourceCode,
+		Signature:            signature,
+		Design:               design,
+	}, request, nil
+}
+
+// createProblemParts creates the hints, starter code, test cases and solutions of a problem
+func createProblemParts(ctx context.Context, queries *sql.Queries, problemID int32, request ProblemRequest) *apierror.APIError {
 	for _, hint := range request.Hints {
-		err = h.PostgresQueries.CreateProblemHint(context.Background(), sql.CreateProblemHintParams{
+		err := queries.CreateProblemHint(ctx, sql.CreateProblemHintParams{
 			ProblemID:   problemID,
 			Description: hint.Description,
 			Answer:      hint.Answer,
 		})
 		if err != nil {
-			return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create hint")
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create hint")
 		}
 	}
 
 	for language, code := range request.Code {
-		err = h.PostgresQueries.CreateProblemCode(context.Background(), sql.CreateProblemCodeParams{
+		err := queries.CreateProblemCode(ctx, sql.CreateProblemCodeParams{
 			ProblemID: problemID,
 			Language:  sql.ProblemLanguage(language),
 			Code:      code,
 		})
 		if err != nil {
-			return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create code")
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create code")
 		}
 	}
 
 	for _, testCase := range request.TestCases {
-		testCaseID, err := h.PostgresQueries.CreateProblemTestCase(context.Background(), sql.CreateProblemTestCaseParams{
+		var calls []byte
+		if len(testCase.Calls) > 0 {
+			var err error
+			calls, err = json.Marshal(testCase.Calls)
+			if err != nil {
+				return apierror.NewError(http.StatusInternalServerError, "Failed to create test case")
+			}
+		}
+
+		testCaseID, err := queries.CreateProblemTestCase(ctx, sql.CreateProblemTestCaseParams{
 			Description: testCase.Description,
 			ProblemID:   problemID,
 			Visibility:  sql.Visibility(testCase.Visibility),
+			Calls:       calls,
 		})
 		if err != nil {
-			return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create test case")
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create test case")
 		}
 
 		for _, input := range testCase.Input {
-			_, err = h.PostgresQueries.CreateProblemTestCaseInput(context.Background(), sql.CreateProblemTestCaseInputParams{
+			_, err = queries.CreateProblemTestCaseInput(ctx, sql.CreateProblemTestCaseInputParams{
 				ProblemTestCaseID: testCaseID.ID,
 				Value:             input.Value,
 				Type:              sql.ProblemTestCaseType(input.Type),
 				Name:              input.Name,
 			})
 			if err != nil {
-				return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create test case input")
+				return apierror.NewError(http.StatusInternalServerError, "Failed to create test case input")
 			}
 		}
 
-		_, err = h.PostgresQueries.CreateProblemTestCaseOutput(context.Background(), sql.CreateProblemTestCaseOutputParams{
+		_, err = queries.CreateProblemTestCaseOutput(ctx, sql.CreateProblemTestCaseOutputParams{
 			ProblemTestCaseID: testCaseID.ID,
 			Value:             testCase.Output,
 		})
 		if err != nil {
-			return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create test case output")
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create test case output")
 		}
 	}
 
 	for language, code := range request.Solutions {
-		_, err = h.PostgresQueries.CreateProblemSolution(context.Background(), sql.CreateProblemSolutionParams{
+		_, err := queries.CreateProblemSolution(ctx, sql.CreateProblemSolutionParams{
 			ProblemID: problemID,
 			Language:  sql.ProblemLanguage(language),
 			Code:      code,
 		})
 		if err != nil {
-			return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create solution")
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create solution")
 		}
 	}
 
-	return &CreateProblemResponse{
-		Data: CreateProblemData{
-			ProblemID: strconv.Itoa(int(problemID)),
-		},
+	return nil
+}
+
+// UpdateProblem replaces a problem, its hints, starter code, test cases and solutions with the request and
+// records it as the problem's next version in one transaction. Returns the new version.
+func (h *Handler) UpdateProblem(ctx context.Context, problemID int32, request ProblemRequest, updatedBy string) (int32, *apierror.APIError) {
+	params, parts, err := ProblemColumns(request)
+	if err != nil {
+		return 0, apierror.NewError(http.StatusInternalServerError, "Failed to update problem")
+	}
+
+	var version int32
+	apiErr := h.WithTx(ctx, func(queries *sql.Queries) *apierror.APIError {
+		var err error
+		version, err = queries.UpdateProblem(ctx, sql.UpdateProblemParams{
+			Title:                params.Title,
+			Description:          params.Description,
+			FunctionName:         params.FunctionName,
+			Points:               params.Points,
+			Tags:                 params.Tags,
+			Difficulty:           params.Difficulty,
+			CpuTimeLimit:         params.CpuTimeLimit,
+			WallTimeLimit:        params.WallTimeLimit,
+			MemoryLimit:          params.MemoryLimit,
+			StackLimit:           params.StackLimit,
+			TimeLimitMultipliers: params.TimeLimitMultipliers,
+			Checker:              params.Checker,
+			CheckerEpsilon:       params.CheckerEpsilon,
+			CheckerLanguage:      params.CheckerLanguage,
+			CheckerSourceCode:    params.CheckerSourceCode,
+			Signature:            params.Signature,
+			Design:               params.Design,
+			ID:                   problemID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierror.NewError(http.StatusNotFound, "Problem not found")
+		}
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to update problem")
+		}
+
+		// Parts are replaced rather than matched up, the previous ones are kept with the previous version
+		if err := queries.DeleteProblemHints(ctx, problemID); err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to update hints")
+		}
+		if err := queries.DeleteProblemCodes(ctx, problemID); err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to update code")
+		}
+		if err := queries.DeleteProblemTestCases(ctx, problemID); err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to update test cases")
+		}
+		if err := queries.DeleteProblemSolutions(ctx, problemID); err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to update solutions")
+		}
+		if apiErr := createProblemParts(ctx, queries, problemID, parts); apiErr != nil {
+			return apiErr
+		}
+		return createProblemVersion(ctx, queries, problemID, version, request, updatedBy)
+	})
+	if apiErr != nil {
+		return 0, apiErr
+	}
+
+	return version, nil
+}
+
+// createProblemVersion records the request a version of a problem was created or edited with
+func createProblemVersion(ctx context.Context, queries *sql.Queries, problemID, version int32, request ProblemRequest, createdBy string) *apierror.APIError {
+	problem, err := json.Marshal(request)
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to create problem version")
+	}
+
+	err = queries.CreateProblemVersion(ctx, sql.CreateProblemVersionParams{
+		ProblemID: problemID,
+		Version:   version,
+		Problem:   problem,
+		CreatedBy: pgtype.Text{String: createdBy, Valid: createdBy != ""},
+	})
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to create problem version")
+	}
+	return nil
+}
+
+// GetProblemRequest reads a problem that isn't retired back as the request creating it, with every test case
+// and solution
+func (h *Handler) GetProblemRequest(ctx context.Context, problemID int32) (ProblemRequest, *apierror.APIError) {
+	problem, err := h.PostgresQueries.GetProblem(ctx, sql.GetProblemParams{ProblemID: problemID})
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && problem.RetiredAt.Valid) {
+		return ProblemRequest{}, apierror.NewError(http.StatusNotFound, "Problem not found")
+	}
+	if err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusInternalServerError, "Failed to get problem")
+	}
+
+	problemTestCases, err := h.PostgresQueries.GetProblemTestCases(ctx, sql.GetProblemTestCasesParams{
+		ProblemID: problemID,
+	})
+	if err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusInternalServerError, "Failed to get problem test cases")
+	}
+	testCases, err := TestCasesFromRows(problemTestCases)
+	if err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusInternalServerError, "Failed to get problem test cases")
+	}
+
+	var hints []ProblemRequestHint
+	if hintsJson, err := json.Marshal(problem.Hints); err == nil {
+		_ = json.Unmarshal(hintsJson, &hints)
+	}
+
+	return ProblemRequest{
+		Title:        problem.Title,
+		Description:  problem.Description.String,
+		FunctionName: problem.FunctionName,
+		Tags:         problem.Tags,
+		Difficulty:   string(problem.Difficulty),
+		Code:         InterfaceToMap(problem.Code),
+		Hints:        hints,
+		Points:       problem.Points,
+		Solutions:    InterfaceToMap(problem.Solutions),
+		TestCases:    testCases,
+		Limits:       ProblemLimitsFromRow(problem),
+		Checker:      ProblemCheckerFromRow(problem),
+		Signature:    ProblemSignatureFromRow(problem.Signature),
+		Design:       ProblemDesignFromRow(problem.Design),
 	}, nil
 }
 
+// PatchProblemRequest applies the fields of a patch to a problem request, each replacing the whole field.
+// Starter code generated from the signature is generated again when the signature or function name changes.
+func PatchProblemRequest(request ProblemRequest, patch map[string]json.RawMessage) (ProblemRequest, *apierror.APIError) {
+	_, signaturePatched := patch["signature"]
+	_, functionNamePatched := patch["functionName"]
+	if _, codePatched := patch["code"]; (signaturePatched || functionNamePatched) && !codePatched && request.Signature != nil {
+		code := ProblemRequestCode{}
+		stubs := request.Signature.Stubs(request.FunctionName)
+		for language, starter := range request.Code {
+			if starter != stubs[language] {
+				code[language] = starter
+			}
+		}
+		request.Code = code
+	}
+
+	current, err := json.Marshal(request)
+	if err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusInternalServerError, "Failed to patch problem")
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusInternalServerError, "Failed to patch problem")
+	}
+	for field, value := range patch {
+		if _, ok := fields[field]; !ok && field != "signature" && field != "design" {
+			return ProblemRequest{}, apierror.NewError(http.StatusBadRequest, "Unknown problem field: "+field)
+		}
+		fields[field] = value
+	}
+
+	patched, err := json.Marshal(fields)
+	if err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusInternalServerError, "Failed to patch problem")
+	}
+	var patchedRequest ProblemRequest
+	if err := json.Unmarshal(patched, &patchedRequest); err != nil {
+		return ProblemRequest{}, apierror.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	return patchedRequest, nil
+}
+
 // GET: /problems/{problemId}
 func (h *Handler) GetProblem(w http.ResponseWriter, r *http.Request) {
 	userId, err := GetClientUserID(w, r)
@@ -341,9 +694,14 @@ func (h *Handler) GetProblem(w http.ResponseWriter, r *http.Request) {
 		apierror.SendError(w, http.StatusInternalServerError, "Failed to get problem")
 		return
 	}
+	if problem.RetiredAt.Valid {
+		apierror.SendError(w, http.StatusNotFound, "Problem not found")
+		return
+	}
 
 	// test cases should not contain visibility on response
 	codeMap := InterfaceToMap(problem.Code)
+	limits := ProblemLimitsFromRow(problem)
 	response := ProblemResponse{
 		Data: Problem{
 			ID:            problem.ID,
@@ -360,6 +718,9 @@ func (h *Handler) GetProblem(w http.ResponseWriter, r *http.Request) {
 			Solved:        problem.Solved,
 			TotalAttempts: problem.TotalAttempts,
 			TotalCorrect:  problem.TotalCorrect,
+			Limits:        &limits,
+			Signature:     ProblemSignatureFromRow(problem.Signature),
+			Design:        ProblemDesignFromRow(problem.Design),
 		},
 	}
 
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"slices"
 	"testing"
 )
 
@@ -109,3 +110,89 @@ func TestGetProblems(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateProblemRequestValidate(t *testing.T) {
+	valid := ProblemRequest{
+		Title:        "Two Sum",
+		Description:  "Return the indices of the two numbers that add up to target.",
+		FunctionName: "twoSum",
+		Difficulty:   "easy",
+		Code:         ProblemRequestCode{"python": "class Solution:\n    def twoSum(self, nums, target):\n        pass"},
+		Solutions:    map[string]string{"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]"},
+		TestCases:    []TestCase{adminTwoSumTestCase},
+	}
+
+	testCases := []struct {
+		name           string
+		request        func(ProblemRequest) ProblemRequest
+		expectedFields []string
+	}{
+		{
+			name:    "Valid problem",
+			request: func(p ProblemRequest) ProblemRequest { return p },
+		},
+		{
+			name: "List every invalid field",
+			request: func(p ProblemRequest) ProblemRequest {
+				p.Title = ""
+				p.Difficulty = "impossible"
+				p.Solutions = map[string]string{"cobol": "twoSum"}
+				return p
+			},
+			expectedFields: []string{"title", "difficulty", "solutions.cobol"},
+		},
+		{
+			name: "Point at the invalid test case",
+			request: func(p ProblemRequest) ProblemRequest {
+				hidden := adminTwoSumTestCase
+				hidden.Visibility = "secret"
+				invalidInput := adminTwoSumTestCase
+				invalidInput.Input = []TestCaseInput{{Name: "c", Type: CharType, Value: "ab"}}
+				p.TestCases = []TestCase{adminTwoSumTestCase, hidden, invalidInput}
+				return p
+			},
+			expectedFields: []string{"testCases[1].visibility", "testCases[2].input"},
+		},
+		{
+			name: "Reject a signature along with a design",
+			request: func(p ProblemRequest) ProblemRequest {
+				p.Signature = &adminTwoSumSignature
+				p.Design = &adminCounterDesign
+				p.TestCases = nil
+				return p
+			},
+			expectedFields: []string{"design"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			apiErr := CreateProblemRequestValidate(testCase.request(valid))
+			if len(testCase.expectedFields) == 0 {
+				if apiErr != nil {
+					t.Fatalf("Expected a valid problem, got %s", apiErr.Message())
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatalf("Expected invalid fields %v, got none", testCase.expectedFields)
+			}
+			if apiErr.StatusCode() != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, apiErr.StatusCode())
+			}
+
+			var fields []string
+			for _, field := range apiErr.Fields() {
+				fields = append(fields, field.Field)
+			}
+			if !slices.Equal(fields, testCase.expectedFields) {
+				t.Errorf("Expected invalid fields %v, got %v", testCase.expectedFields, fields)
+			}
+			if apiErr.Message() != apiErr.Fields()[0].Message {
+				t.Errorf("Expected the message of the first field, got %s", apiErr.Message())
+			}
+		})
+	}
+}
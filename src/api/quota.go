@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/middleware"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// QuotaKind is the kind of execution a quota counts
+type QuotaKind string
+
+const (
+	QuotaRuns        QuotaKind = "runs" // runs and playground runs
+	QuotaSubmissions QuotaKind = "submissions"
+)
+
+// PlanQuota limits the executions of an account, zero means unlimited
+type PlanQuota struct {
+	RunsPerMinute        int
+	RunsPerDay           int
+	SubmissionsPerMinute int
+	SubmissionsPerDay    int
+	Concurrent           int // runs and submissions executing at once, a submission until it is graded
+}
+
+// DefaultPlanQuotas are the quotas of each plan, accounts without a known plan get the free one
+var DefaultPlanQuotas = map[sql.AccountPlan]PlanQuota{
+	sql.AccountPlanFree: {RunsPerMinute: 10, RunsPerDay: 300, SubmissionsPerMinute: 5, SubmissionsPerDay: 100, Concurrent: 1},
+	sql.AccountPlanPlus: {RunsPerMinute: 30, RunsPerDay: 1500, SubmissionsPerMinute: 15, SubmissionsPerDay: 500, Concurrent: 2},
+	sql.AccountPlanPro:  {RunsPerMinute: 60, RunsPerDay: 5000, SubmissionsPerMinute: 30, SubmissionsPerDay: 2000, Concurrent: 4},
+}
+
+// PlanPriority is the priority executions of a plan wait for judge0 with, see judge0.Scheduler
+func PlanPriority(plan sql.AccountPlan) int {
+	switch plan {
+	case sql.AccountPlanPro:
+		return 2
+	case sql.AccountPlanPlus:
+		return 1
+	}
+	return 0
+}
+
+// quotaConcurrentRetryAfter is how long an account at its concurrency cap is told to wait
+const quotaConcurrentRetryAfter = 2 * time.Second
+
+// QuotaUsage is how many executions of a kind an account made in the last minute and day, counted in the database
+// so every instance of the api shares them. The resets are how long until the oldest execution leaves each period.
+type QuotaUsage struct {
+	Minute      int
+	Day         int
+	MinuteReset time.Duration
+	DayReset    time.Duration
+}
+
+// Quotas enforces the quotas of the plans on the usage of each account. Executions in flight are counted in
+// memory, so the concurrency cap of an account applies to each instance of the api on its own.
+type Quotas struct {
+	Plans map[sql.AccountPlan]PlanQuota
+
+	mu      sync.Mutex
+	running map[string]int // executions of each account in flight in this instance
+}
+
+// NewQuotas creates quotas of the plans, DefaultPlanQuotas when nil
+func NewQuotas(plans map[sql.AccountPlan]PlanQuota) *Quotas {
+	if plans == nil {
+		plans = DefaultPlanQuotas
+	}
+	return &Quotas{
+		Plans:   plans,
+		running: make(map[string]int),
+	}
+}
+
+// Acquire allows an execution of the account if the plan allows one more on top of its usage, pending are its
+// submissions still being graded. Returns a release to call once the execution is over, or how long to wait before
+// trying again.
+func (q *Quotas) Acquire(accountID string, plan sql.AccountPlan, kind QuotaKind, usage QuotaUsage, pending int) (func(), time.Duration, bool) {
+	quota, ok := q.Plans[plan]
+	if !ok {
+		quota = q.Plans[sql.AccountPlanFree]
+	}
+	perMinute, perDay := quota.RunsPerMinute, quota.RunsPerDay
+	if kind == QuotaSubmissions {
+		perMinute, perDay = quota.SubmissionsPerMinute, quota.SubmissionsPerDay
+	}
+
+	var retryAfter time.Duration
+	if perMinute > 0 && usage.Minute >= perMinute {
+		retryAfter = max(retryAfter, usage.MinuteReset, time.Second)
+	}
+	if perDay > 0 && usage.Day >= perDay {
+		retryAfter = max(retryAfter, usage.DayReset, time.Second)
+	}
+	if retryAfter > 0 {
+		return nil, retryAfter, false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if quota.Concurrent > 0 && q.running[accountID]+pending >= quota.Concurrent {
+		return nil, quotaConcurrentRetryAfter, false
+	}
+	q.running[accountID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			if q.running[accountID]--; q.running[accountID] <= 0 {
+				delete(q.running, accountID)
+			}
+		})
+	}, 0, true
+}
+
+// quotaUsage counts the executions of a kind the account made, they are recorded in account_execution as they are
+// allowed
+func quotaUsage(ctx context.Context, queries *sql.Queries, accountID string, kind QuotaKind) (QuotaUsage, error) {
+	row, err := queries.GetQuotaUsage(ctx, sql.GetQuotaUsageParams{AccountID: accountID, Kind: string(kind)})
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	return QuotaUsage{
+		Minute:      int(row.Minute),
+		Day:         int(row.Day),
+		MinuteReset: time.Duration(row.MinuteReset * float64(time.Second)),
+		DayReset:    time.Duration(row.DayReset * float64(time.Second)),
+	}, nil
+}
+
+// AcquireExecutionQuota counts an execution against the quota of the client's plan, sending a 429 with a
+// Retry-After header when the plan doesn't allow it. Returns the context executing with the plan's priority
+// and a release to call once the request is done with judge0. Admins have no quota.
+func (h *Handler) AcquireExecutionQuota(w http.ResponseWriter, r *http.Request, kind QuotaKind) (context.Context, func(), bool) {
+	client := r.Context().Value(middleware.ClientTokenKey).(middleware.ClientContext)
+	ctx := judge0.WithPriority(r.Context(), PlanPriority(client.Plan))
+	if h.Quotas == nil || client.Admin {
+		return ctx, func() {}, true
+	}
+
+	var release func()
+	var retryAfter time.Duration
+	apiErr := h.WithTx(r.Context(), func(queries *sql.Queries) *apierror.APIError {
+		// Parallel requests of the account, on any instance, wait here until this one is recorded
+		if err := queries.LockAccountQuota(r.Context(), client.UserID); err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to check quota")
+		}
+
+		// Submissions are graded after their request, so they keep counting while they are in the database
+		pending, err := queries.CountPendingSubmissions(r.Context(), sql.CountPendingSubmissionsParams{
+			AccountID:     client.UserID,
+			WindowSeconds: int32((submissionCallbackGracePeriod + submissionQueueTimeout) / time.Second),
+		})
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to check quota")
+		}
+		usage, err := quotaUsage(r.Context(), queries, client.UserID, kind)
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to check quota")
+		}
+
+		var ok bool
+		release, retryAfter, ok = h.Quotas.Acquire(client.UserID, client.Plan, kind, usage, int(pending))
+		if !ok {
+			return nil
+		}
+		err = queries.CreateAccountExecution(r.Context(), sql.CreateAccountExecutionParams{AccountID: client.UserID, Kind: string(kind)})
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to check quota")
+		}
+		return nil
+	})
+	if apiErr != nil {
+		if release != nil {
+			release()
+		}
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return nil, nil, false
+	}
+	if release == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		apierror.SendError(w, http.StatusTooManyRequests, fmt.Sprintf("Too many %s, try again later", kind))
+		return nil, nil, false
+	}
+	return ctx, release, true
+}
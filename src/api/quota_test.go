@@ -0,0 +1,79 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestQuotas(t *testing.T) {
+	plans := map[sql.AccountPlan]PlanQuota{
+		sql.AccountPlanFree: {RunsPerMinute: 2, RunsPerDay: 3, SubmissionsPerMinute: 1, Concurrent: 1},
+		sql.AccountPlanPro:  {RunsPerMinute: 4, Concurrent: 2},
+	}
+
+	testCases := []struct {
+		name               string
+		plan               sql.AccountPlan
+		kind               QuotaKind
+		usage              QuotaUsage
+		pending            int
+		expectedOk         bool
+		expectedRetryAfter time.Duration
+	}{
+		{name: "Allow within the quota", plan: sql.AccountPlanFree, kind: QuotaRuns, usage: QuotaUsage{Minute: 1, Day: 1}, expectedOk: true},
+		{name: "Limit runs per minute", plan: sql.AccountPlanFree, kind: QuotaRuns, usage: QuotaUsage{Minute: 2, Day: 2, MinuteReset: 20 * time.Second, DayReset: 23 * time.Hour}, expectedRetryAfter: 20 * time.Second},
+		{name: "Limit runs per day", plan: sql.AccountPlanFree, kind: QuotaRuns, usage: QuotaUsage{Minute: 1, Day: 3, MinuteReset: 20 * time.Second, DayReset: 21 * time.Hour}, expectedRetryAfter: 21 * time.Hour},
+		{name: "Wait for the longest limit", plan: sql.AccountPlanFree, kind: QuotaRuns, usage: QuotaUsage{Minute: 2, Day: 3, MinuteReset: 20 * time.Second, DayReset: 21 * time.Hour}, expectedRetryAfter: 21 * time.Hour},
+		{name: "Wait at least a second", plan: sql.AccountPlanFree, kind: QuotaRuns, usage: QuotaUsage{Minute: 2, Day: 2}, expectedRetryAfter: time.Second},
+		{name: "Limit submissions apart from runs", plan: sql.AccountPlanFree, kind: QuotaSubmissions, usage: QuotaUsage{Minute: 1, Day: 1, MinuteReset: time.Minute}, expectedRetryAfter: time.Minute},
+		{name: "Count pending submissions as running", plan: sql.AccountPlanFree, kind: QuotaRuns, pending: 1, expectedRetryAfter: quotaConcurrentRetryAfter},
+		{name: "Higher plans allow more", plan: sql.AccountPlanPro, kind: QuotaRuns, usage: QuotaUsage{Minute: 3, Day: 100}, pending: 1, expectedOk: true},
+		{name: "Unknown plans get the free quota", plan: sql.AccountPlan("team"), kind: QuotaRuns, usage: QuotaUsage{Minute: 2, Day: 2, MinuteReset: time.Minute}, expectedRetryAfter: time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			quotas := NewQuotas(plans)
+			release, retryAfter, ok := quotas.Acquire("123abc", testCase.plan, testCase.kind, testCase.usage, testCase.pending)
+			if ok {
+				release()
+			}
+
+			if ok != testCase.expectedOk {
+				t.Fatalf("Expected ok %v, got %v", testCase.expectedOk, ok)
+			}
+			if retryAfter != testCase.expectedRetryAfter {
+				t.Errorf("Expected retry after %s, got %s", testCase.expectedRetryAfter, retryAfter)
+			}
+		})
+	}
+
+	t.Run("Limit concurrent executions until released", func(t *testing.T) {
+		t.Parallel()
+
+		quotas := NewQuotas(plans)
+		release, _, ok := quotas.Acquire("123abc", sql.AccountPlanPro, QuotaRuns, QuotaUsage{}, 0)
+		if !ok {
+			t.Fatal("Expected the first execution to be allowed")
+		}
+		if _, _, ok := quotas.Acquire("123abc", sql.AccountPlanPro, QuotaSubmissions, QuotaUsage{}, 1); ok {
+			t.Fatal("Expected executions over the concurrency cap to be limited")
+		}
+		if _, _, ok := quotas.Acquire("456def", sql.AccountPlanFree, QuotaRuns, QuotaUsage{}, 0); !ok {
+			t.Fatal("Expected other accounts to be allowed")
+		}
+
+		release()
+		release()
+		if _, _, ok := quotas.Acquire("123abc", sql.AccountPlanPro, QuotaSubmissions, QuotaUsage{}, 1); !ok {
+			t.Fatal("Expected the released slot to be free")
+		}
+		if running := quotas.running["123abc"]; running != 1 {
+			t.Errorf("Expected 1 running execution after releasing twice, got %d", running)
+		}
+	})
+}
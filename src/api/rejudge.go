@@ -0,0 +1,406 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// RejudgeRequest selects the submissions a rejudge job grades again, a submission must match every filter set
+type RejudgeRequest struct {
+	ProblemID     int32      `json:"problemId,omitempty"`
+	SubmissionIDs []string   `json:"submissionIds,omitempty"`
+	Since         *time.Time `json:"since,omitempty"` // submissions created at or after
+}
+
+// RejudgeSubmission is the verdict of a submission before and after a rejudge
+type RejudgeSubmission struct {
+	SubmissionID       string               `json:"submissionId"`
+	AccountID          string               `json:"accountId"`
+	ProblemID          int32                `json:"problemId"`
+	OldStatus          sql.SubmissionStatus `json:"oldStatus"`
+	OldPassedTestCases int32                `json:"oldPassedTestCases"`
+	NewStatus          sql.SubmissionStatus `json:"newStatus,omitempty"` // empty until graded again
+	NewPassedTestCases *int32               `json:"newPassedTestCases,omitempty"`
+	Error              string               `json:"error,omitempty"` // the submission kept its verdict
+	RejudgedAt         *time.Time           `json:"rejudgedAt,omitempty"`
+}
+
+type RejudgeJob struct {
+	ID            string              `json:"id"`
+	Status        sql.RejudgeStatus   `json:"status"`
+	ProblemID     int32               `json:"problemId,omitempty"`
+	SubmissionIDs []string            `json:"submissionIds,omitempty"`
+	Since         *time.Time          `json:"since,omitempty"`
+	Total         int32               `json:"total"`
+	Completed     int32               `json:"completed"`
+	Changed       int32               `json:"changed"`
+	Failed        int32               `json:"failed"`
+	CreatedBy     string              `json:"createdBy"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	FinishedAt    *time.Time          `json:"finishedAt,omitempty"`
+	Submissions   []RejudgeSubmission `json:"submissions,omitempty"`
+}
+
+type RejudgeJobResponse struct {
+	Data RejudgeJob `json:"data"`
+}
+
+// ValidateRejudgeRequest checks a rejudge request selects submissions and converts its filters
+func ValidateRejudgeRequest(request RejudgeRequest) (sql.CreateRejudgeSubmissionsParams, *apierror.APIError) {
+	if request.ProblemID == 0 && len(request.SubmissionIDs) == 0 && request.Since == nil {
+		return sql.CreateRejudgeSubmissionsParams{}, apierror.NewError(http.StatusBadRequest, "A rejudge needs a problem, submissions or a date")
+	}
+	if request.ProblemID < 0 {
+		return sql.CreateRejudgeSubmissionsParams{}, apierror.NewError(http.StatusBadRequest, "Invalid problem ID")
+	}
+
+	var params sql.CreateRejudgeSubmissionsParams
+	if request.ProblemID > 0 {
+		params.ProblemID = pgtype.Int4{Int32: request.ProblemID, Valid: true}
+	}
+	for _, id := range request.SubmissionIDs {
+		submissionId, err := uuid.Parse(id)
+		if err != nil {
+			return sql.CreateRejudgeSubmissionsParams{}, apierror.NewError(http.StatusBadRequest, "Invalid submission ID: "+id)
+		}
+		params.SubmissionIds = append(params.SubmissionIds, pgtype.UUID{Bytes: submissionId, Valid: true})
+	}
+	if request.Since != nil {
+		params.Since = pgtype.Timestamp{Time: request.Since.UTC(), Valid: true}
+	}
+	return params, nil
+}
+
+// RejudgeJobFromRow converts a database rejudge job to the API response format
+func RejudgeJobFromRow(job sql.RejudgeJob, submissions []sql.GetRejudgeSubmissionsRow) RejudgeJob {
+	response := RejudgeJob{
+		ID:        uuid.UUID(job.ID.Bytes).String(),
+		Status:    job.Status,
+		ProblemID: job.ProblemID.Int32,
+		Total:     job.Total,
+		Completed: job.Completed,
+		Changed:   job.Changed,
+		Failed:    job.Failed,
+		CreatedBy: job.CreatedBy.String,
+		CreatedAt: job.CreatedAt.Time,
+	}
+	for _, id := range job.SubmissionIds {
+		response.SubmissionIDs = append(response.SubmissionIDs, uuid.UUID(id.Bytes).String())
+	}
+	if job.Since.Valid {
+		response.Since = &job.Since.Time
+	}
+	if job.FinishedAt.Valid {
+		response.FinishedAt = &job.FinishedAt.Time
+	}
+
+	for _, row := range submissions {
+		submission := RejudgeSubmission{
+			SubmissionID:       uuid.UUID(row.SubmissionID.Bytes).String(),
+			AccountID:          row.AccountID,
+			ProblemID:          row.ProblemID,
+			OldStatus:          row.OldStatus,
+			OldPassedTestCases: row.OldPassedTestCases,
+			Error:              row.Error.String,
+		}
+		if row.NewStatus.Valid {
+			submission.NewStatus = row.NewStatus.SubmissionStatus
+		}
+		if row.NewPassedTestCases.Valid {
+			submission.NewPassedTestCases = &row.NewPassedTestCases.Int32
+		}
+		if row.RejudgedAt.Valid {
+			submission.RejudgedAt = &row.RejudgedAt.Time
+		}
+		response.Submissions = append(response.Submissions, submission)
+	}
+	return response
+}
+
+// rejudgeProblem is a problem with its current test cases, fetched once per job
+type rejudgeProblem struct {
+	problem   sql.GetProblemRow
+	testCases []TestCase
+	apiErr    *apierror.APIError
+}
+
+// rejudgeJobLease is how long a run keeps a rejudge job without renewing its lease, it is renewed before each
+// submission is graded again
+const rejudgeJobLease = 5 * time.Minute
+
+// RunRejudgeJob grades every submission of a rejudge job again, one at a time so regular submissions keep
+// their share of judge0. It runs in the background, detached from the request that created the job, as the
+// owner of its lease until another run claims the job.
+func (h *Handler) RunRejudgeJob(jobId pgtype.UUID, owner uuid.UUID) {
+	ctx := context.Background()
+
+	rows, err := h.PostgresQueries.GetRejudgeSubmissions(ctx, jobId)
+	if err != nil {
+		log.Printf("Failed to get submissions of rejudge job %s: %v", uuid.UUID(jobId.Bytes), err)
+		h.completeRejudgeJob(ctx, jobId, sql.RejudgeStatusFailed)
+		return
+	}
+
+	problems := make(map[int32]rejudgeProblem)
+	for _, row := range rows {
+		if row.RejudgedAt.Valid {
+			continue
+		}
+
+		renewed, err := h.PostgresQueries.RenewRejudgeJobLease(ctx, sql.RenewRejudgeJobLeaseParams{
+			LeaseSeconds: int32(rejudgeJobLease / time.Second),
+			ID:           jobId,
+			LeaseOwner:   pgtype.UUID{Bytes: owner, Valid: true},
+		})
+		if err != nil {
+			log.Printf("Failed to renew lease of rejudge job %s: %v", uuid.UUID(jobId.Bytes), err)
+		} else if renewed == 0 {
+			log.Printf("Rejudge job %s was claimed by another run", uuid.UUID(jobId.Bytes))
+			return
+		}
+
+		// Retired problems are rejudged too, GetProblem finds them and only submitting to them is refused
+		problem, ok := problems[row.ProblemID]
+		if !ok {
+			problem.problem, problem.testCases, problem.apiErr = h.FetchProblemAndTestCases(ctx, row.ProblemID, "")
+			problems[row.ProblemID] = problem
+		}
+
+		params := sql.UpdateRejudgeSubmissionParams{JobID: jobId, SubmissionID: row.SubmissionID}
+		apiErr := problem.apiErr
+		var status sql.SubmissionStatus
+		var passedTestCases int32
+		if apiErr == nil {
+			status, passedTestCases, apiErr = h.RejudgeSubmission(ctx, row, problem.problem, problem.testCases)
+		}
+		if apiErr != nil {
+			log.Printf("Failed to rejudge submission %s: %s", uuid.UUID(row.SubmissionID.Bytes), apiErr.Message())
+			params.Error = pgtype.Text{String: apiErr.Message(), Valid: true}
+		} else {
+			params.NewStatus = sql.NullSubmissionStatus{SubmissionStatus: status, Valid: true}
+			params.NewPassedTestCases = pgtype.Int4{Int32: passedTestCases, Valid: true}
+
+			// Solved problems follow the accepted submissions of the account
+			err := h.PostgresQueries.SyncAccountSolvedProblem(ctx, sql.SyncAccountSolvedProblemParams{
+				AccountID: row.AccountID,
+				ProblemID: row.ProblemID,
+			})
+			if err != nil {
+				log.Printf("Failed to update solved problem %d of account %s: %v", row.ProblemID, row.AccountID, err)
+			}
+		}
+
+		recorded, err := h.PostgresQueries.UpdateRejudgeSubmission(ctx, params)
+		if err != nil {
+			log.Printf("Failed to record rejudge of submission %s: %v", uuid.UUID(row.SubmissionID.Bytes), err)
+		}
+		// Another run of the job recorded the submission first and counted it already
+		if recorded == 0 {
+			continue
+		}
+		err = h.PostgresQueries.UpdateRejudgeJobProgress(ctx, sql.UpdateRejudgeJobProgressParams{
+			Changed: apiErr == nil && status != row.OldStatus,
+			Failed:  apiErr != nil,
+			ID:      jobId,
+		})
+		if err != nil {
+			log.Printf("Failed to update progress of rejudge job %s: %v", uuid.UUID(jobId.Bytes), err)
+		}
+	}
+
+	h.completeRejudgeJob(ctx, jobId, sql.RejudgeStatusCompleted)
+}
+
+// ResumeRejudgeJobs runs the rejudge jobs a server stopped in the middle of, one after another. Each job is claimed
+// once its lease ran out, so jobs other instances still run are left to them. Submissions a job already graded
+// again are skipped.
+func (h *Handler) ResumeRejudgeJobs(ctx context.Context) error {
+	for {
+		owner := uuid.New()
+		jobId, err := h.PostgresQueries.ClaimRejudgeJob(ctx, sql.ClaimRejudgeJobParams{
+			LeaseOwner:   pgtype.UUID{Bytes: owner, Valid: true},
+			LeaseSeconds: int32(rejudgeJobLease / time.Second),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error claiming rejudge job: %w", err)
+		}
+		log.Printf("Resuming rejudge job %s", uuid.UUID(jobId.Bytes))
+		h.RunRejudgeJob(jobId, owner)
+	}
+}
+
+func (h *Handler) completeRejudgeJob(ctx context.Context, jobId pgtype.UUID, status sql.RejudgeStatus) {
+	err := h.PostgresQueries.CompleteRejudgeJob(ctx, sql.CompleteRejudgeJobParams{Status: status, ID: jobId})
+	if err != nil {
+		log.Printf("Failed to complete rejudge job %s: %v", uuid.UUID(jobId.Bytes), err)
+	}
+}
+
+// RejudgeSubmission runs a stored submission against the problem's current test cases and checker, replacing its
+// verdict and test case results. Returns the new status and the number of passed test cases.
+func (h *Handler) RejudgeSubmission(ctx context.Context, row sql.GetRejudgeSubmissionsRow, problem sql.GetProblemRow, testCases []TestCase) (sql.SubmissionStatus, int32, *apierror.APIError) {
+	language := judge0.LanguageIDToLanguage(int(row.LanguageID))
+	if language == "" {
+		return "", 0, apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Unknown language ID %d", row.LanguageID))
+	}
+
+	submissions, batches, apiErr := h.PrepareSubmissions(SubmissionRequest{
+		Language:   language,
+		SourceCode: row.SubmittedCode,
+		ProblemID:  row.ProblemID,
+	}, testCases, problem)
+	if apiErr != nil {
+		return "", 0, apiErr
+	}
+
+	responses, err := h.ExecuteSubmissions(ctx, row.ProblemID, submissions, batches == nil)
+	var batchErr *judge0.BatchError
+	if apiErr := ExecutorError(err); apiErr != nil {
+		return "", 0, apiErr
+	} else if errors.As(err, &batchErr) {
+		// Rejected test cases come back as internal errors, the rest are still graded
+		log.Printf("Rejudge of submission %s partially failed: %v", uuid.UUID(row.SubmissionID.Bytes), batchErr)
+	} else if err != nil {
+		return "", 0, apierror.NewError(http.StatusInternalServerError, "Failed to run submission")
+	}
+
+	responses = SplitJudge0Results(responses, batches)
+	statuses := h.GradeTestCases(ctx, ProblemCheckerFromRow(problem), testCases, responses)
+	submission, failedTestCase, passedTestCases := AggregateSubmissionResults(testCases, responses, statuses, language)
+
+	failedTestCaseJson, err := json.Marshal(failedTestCase)
+	if err != nil {
+		return "", 0, apierror.NewError(http.StatusInternalServerError, "Failed to complete submission")
+	}
+
+	// Test cases may have been added or removed, so the old results are replaced rather than updated
+	if err := h.PostgresQueries.DeleteSubmissionTestResults(ctx, row.SubmissionID); err != nil {
+		return "", 0, apierror.NewError(http.StatusInternalServerError, "Failed to store submission results")
+	}
+	for _, testResult := range SubmissionTestResults(row.SubmissionID, testCases, responses, statuses) {
+		if err := h.PostgresQueries.CreateSubmissionTestResult(ctx, testResult); err != nil {
+			return "", 0, apierror.NewError(http.StatusInternalServerError, "Failed to store submission results")
+		}
+	}
+
+	err = h.PostgresQueries.RejudgeSubmission(ctx, sql.RejudgeSubmissionParams{
+		Stdout:          submission.Stdout,
+		Time:            submission.Time,
+		Memory:          int32(submission.Memory),
+		Stderr:          submission.Stderr,
+		CompileOutput:   submission.CompileOutput,
+		Message:         submission.Message,
+		Status:          submission.Status,
+		FailedTestCase:  failedTestCaseJson,
+		PassedTestCases: passedTestCases,
+		TotalTestCases:  int32(len(testCases)),
+		ProblemVersion:  pgtype.Int4{Int32: problem.Version, Valid: true},
+		ID:              row.SubmissionID,
+	})
+	if err != nil {
+		return "", 0, apierror.NewError(http.StatusInternalServerError, "Failed to complete submission")
+	}
+
+	return submission.Status, passedTestCases, nil
+}
+
+// POST: /admin/rejudges
+// The job runs in the background, its progress is at GET /admin/rejudges/{rejudgeId}
+func (h *Handler) CreateRejudgeJob(w http.ResponseWriter, r *http.Request) {
+	userId, err := GetClientUserID(w, r)
+	if err != nil {
+		return
+	}
+
+	request, apiErr := DecodeJSONRequest[RejudgeRequest](r)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	params, apiErr := ValidateRejudgeRequest(request)
+	if apiErr != nil {
+		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
+		return
+	}
+
+	owner := uuid.New()
+	job, err := h.PostgresQueries.CreateRejudgeJob(r.Context(), sql.CreateRejudgeJobParams{
+		ProblemID:     params.ProblemID,
+		SubmissionIds: params.SubmissionIds,
+		Since:         params.Since,
+		CreatedBy:     userId,
+		LeaseOwner:    pgtype.UUID{Bytes: owner, Valid: true},
+		LeaseSeconds:  int32(rejudgeJobLease / time.Second),
+	})
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to create rejudge")
+		return
+	}
+
+	// A problem is rejudged after its test cases change
+	if params.ProblemID.Valid {
+		h.InvalidateProblemResults(r.Context(), params.ProblemID.Int32)
+	}
+
+	// The old verdicts are recorded up front, the job grades these submissions even if more match later
+	params.JobID = job.ID
+	total, err := h.PostgresQueries.CreateRejudgeSubmissions(r.Context(), params)
+	if err == nil {
+		job.Total = int32(total)
+		err = h.PostgresQueries.UpdateRejudgeJobTotal(r.Context(), sql.UpdateRejudgeJobTotalParams{Total: job.Total, ID: job.ID})
+	}
+	if err != nil {
+		h.completeRejudgeJob(context.Background(), job.ID, sql.RejudgeStatusFailed)
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to create rejudge")
+		return
+	}
+
+	go h.RunRejudgeJob(job.ID, owner)
+
+	SendJSONResponse(w, http.StatusAccepted, RejudgeJobResponse{Data: RejudgeJobFromRow(job, nil)})
+}
+
+// GET: /admin/rejudges/{rejudgeId}
+func (h *Handler) GetRejudgeJob(w http.ResponseWriter, r *http.Request) {
+	idUUID, err := uuid.Parse(chi.URLParam(r, "rejudgeId"))
+	if err != nil {
+		apierror.SendError(w, http.StatusBadRequest, "Invalid rejudge ID")
+		return
+	}
+	jobId := pgtype.UUID{Bytes: idUUID, Valid: true}
+
+	job, err := h.PostgresQueries.GetRejudgeJob(r.Context(), jobId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.SendError(w, http.StatusNotFound, "Rejudge not found")
+		return
+	}
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to get rejudge")
+		return
+	}
+
+	submissions, err := h.PostgresQueries.GetRejudgeSubmissions(r.Context(), jobId)
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to get rejudge")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, RejudgeJobResponse{Data: RejudgeJobFromRow(job, submissions)})
+}
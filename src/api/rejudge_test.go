@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestCreateRejudgeJob(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []TestingCase{
+		{
+			name:           "Rejudge problem",
+			body:           RejudgeRequest{ProblemID: 1},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Rejudge submissions since a date",
+			body:           RejudgeRequest{ProblemID: 1, Since: &since},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Rejudge without filters",
+			body:           RejudgeRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Rejudge invalid submission id",
+			body:           RejudgeRequest{SubmissionIDs: []string{"not-a-uuid"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Rejudge invalid problem id",
+			body:           RejudgeRequest{ProblemID: -1},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPost, "/admin/rejudges", testCase.body)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.CreateRejudgeJob)
+		})
+	}
+}
+
+func TestGetRejudgeJob(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name:           "Get invalid rejudge id",
+			urlParams:      map[string]string{"rejudgeId": "not-a-uuid"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Get missing rejudge",
+			urlParams:      map[string]string{"rejudgeId": "00000000-0000-0000-0000-000000000000"},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequest(t, http.MethodGet, "/admin/rejudges/{rejudgeId}", nil)
+			request = applyURLParams(request, testCase.urlParams)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.GetRejudgeJob)
+		})
+	}
+}
+
+// A job left running by a restart is finished by ResumeRejudgeJobs, retired problems are rejudged like the others
+func TestResumeRejudgeJobs(t *testing.T) {
+	ctx := context.Background()
+
+	created, apiErr := handler.CreateProblem(ctx, ProblemRequest{
+		Title:        "Resumed rejudge",
+		Description:  "Return the indices of the two numbers that add up to target.",
+		FunctionName: "twoSum",
+		Difficulty:   "easy",
+		Code:         ProblemRequestCode{"python": "class Solution:\n    def twoSum(self, nums, target):\n        pass"},
+		Solutions:    map[string]string{"python": "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]"},
+		TestCases:    []TestCase{adminTwoSumTestCase},
+	}, clientToken.UserID)
+	if apiErr != nil {
+		t.Fatalf("Failed to create problem: %s", apiErr.Message())
+	}
+	problemId, err := strconv.Atoi(created.Data.ProblemID)
+	if err != nil {
+		t.Fatalf("Invalid problem ID %q: %v", created.Data.ProblemID, err)
+	}
+	if _, err := handler.PostgresQueries.RetireProblem(ctx, int32(problemId)); err != nil {
+		t.Fatalf("Failed to retire problem: %v", err)
+	}
+
+	submissionId := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	_, err = handler.PostgresQueries.CreateSubmission(ctx, sql.CreateSubmissionParams{
+		ID:             submissionId,
+		Status:         sql.SubmissionStatusWrongAnswer,
+		LanguageID:     int32(judge0.LanguageToLanguageID("python")),
+		LanguageName:   "python",
+		AccountID:      clientToken.UserID,
+		ProblemID:      int32(problemId),
+		SubmittedCode:  "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+		FailedTestCase: []byte("{}"),
+		TotalTestCases: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create submission: %v", err)
+	}
+
+	// The job is created like CreateRejudgeJob does, the server stops before running it and its lease runs out
+	job, err := handler.PostgresQueries.CreateRejudgeJob(ctx, sql.CreateRejudgeJobParams{
+		SubmissionIds: []pgtype.UUID{submissionId},
+		CreatedBy:     clientToken.UserID,
+		LeaseOwner:    pgtype.UUID{Bytes: uuid.New(), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rejudge job: %v", err)
+	}
+	total, err := handler.PostgresQueries.CreateRejudgeSubmissions(ctx, sql.CreateRejudgeSubmissionsParams{
+		JobID:         job.ID,
+		SubmissionIds: []pgtype.UUID{submissionId},
+	})
+	if err != nil || total != 1 {
+		t.Fatalf("Failed to record rejudge submissions: %d, %v", total, err)
+	}
+
+	// A job another instance still runs keeps its lease and isn't resumed
+	leased, err := handler.PostgresQueries.CreateRejudgeJob(ctx, sql.CreateRejudgeJobParams{
+		SubmissionIds: []pgtype.UUID{submissionId},
+		CreatedBy:     clientToken.UserID,
+		LeaseOwner:    pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		LeaseSeconds:  int32(rejudgeJobLease / time.Second),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rejudge job: %v", err)
+	}
+
+	if err := handler.ResumeRejudgeJobs(ctx); err != nil {
+		t.Fatalf("Failed to resume rejudge jobs: %v", err)
+	}
+
+	job, err = handler.PostgresQueries.GetRejudgeJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rejudge job: %v", err)
+	}
+	if job.Status != sql.RejudgeStatusCompleted || job.Completed != 1 || job.Changed != 1 || job.Failed != 0 {
+		t.Errorf("Expected a completed job with one changed submission, got %s with %d completed, %d changed and %d failed", job.Status, job.Completed, job.Changed, job.Failed)
+	}
+
+	leased, err = handler.PostgresQueries.GetRejudgeJob(ctx, leased.ID)
+	if err != nil || leased.Status != sql.RejudgeStatusRunning {
+		t.Errorf("Expected the leased job to keep running, got %s: %v", leased.Status, err)
+	}
+
+	rows, err := handler.PostgresQueries.GetRejudgeSubmissions(ctx, job.ID)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("Failed to get rejudge submissions: %d, %v", len(rows), err)
+	}
+	if rows[0].NewStatus.SubmissionStatus != sql.SubmissionStatusAccepted {
+		t.Errorf("Expected the submission to be %s, got %q: %s", sql.SubmissionStatusAccepted, rows[0].NewStatus.SubmissionStatus, rows[0].Error.String)
+	}
+}
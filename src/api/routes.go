@@ -75,6 +75,10 @@ func RegisterApiRoutes(h *Handler, r chi.Router) {
 				r.Get("/", h.GetFriendsUsername)
 			})
 		})
+		//languages
+		r.Route("/languages", func(r chi.Router) {
+			r.Get("/", h.GetLanguages)
+		})
 		//problems
 		r.Route("/problems", func(r chi.Router) {
 			r.Get("/", h.GetProblemsRoute)
@@ -86,6 +90,8 @@ func RegisterApiRoutes(h *Handler, r chi.Router) {
 		r.Route("/submissions", func(r chi.Router) {
 			r.Route("/{token}", func(r chi.Router) {
 				r.Get("/", h.GetSubmission)
+				r.Get("/events", h.GetSubmissionEvents)
+				r.Get("/results", h.GetSubmissionResults)
 			})
 			r.Route("/username/{username}", func(r chi.Router) {
 				r.Get("/", h.GetSubmissionsByUsername)
@@ -103,6 +109,7 @@ func RegisterApiRoutes(h *Handler, r chi.Router) {
 		//runs
 		r.Route("/runs", func(r chi.Router) {
 			r.Post("/", h.CreateRunRoute)
+			r.Post("/playground", h.CreatePlaygroundRunRoute)
 		})
 		//starred
 		r.Route("/starred", func(r chi.Router) {
@@ -124,9 +131,23 @@ func RegisterApiRoutes(h *Handler, r chi.Router) {
 				r.Get("/", h.GetAdminProblems)
 				r.Post("/", h.CreateAdminProblem)
 				r.Post("/run", h.CreateAdminProblemRun)
+				r.Route("/{problemId}", func(r chi.Router) {
+					r.Put("/", h.UpdateAdminProblem)
+					r.Patch("/", h.PatchAdminProblem)
+					r.Delete("/", h.DeleteAdminProblem)
+					r.Get("/versions", h.GetAdminProblemVersions)
+				})
+			})
+			r.Route("/rejudges", func(r chi.Router) {
+				r.Post("/", h.CreateRejudgeJob)
+				r.Get("/{rejudgeId}", h.GetRejudgeJob)
 			})
 			r.Get("/validate", h.GetAdminValidation)
 		})
+		// internal services, authenticated by the handlers themselves
+		r.Route("/internal", func(r chi.Router) {
+			r.Put("/judge0/submissions", h.Judge0SubmissionCallback)
+		})
 	})
 	//generate a route to catch anything not defined and error/block spam
 	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
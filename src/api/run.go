@@ -1,8 +1,14 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -28,9 +34,11 @@ type RunTestCase struct {
 	Memory         int                  `json:"memory"`
 	Status         sql.SubmissionStatus `json:"status"` // Accepted, Wrong Answer, etc
 	Input          []TestCaseInput      `json:"input,omitempty"`
-	Output         string               `json:"output"`         // User code output
-	CompileOutput  string               `json:"compileOutput"`  // Compile output
-	ExpectedOutput string               `json:"expectedOutput"` // Solution code output
+	Calls          []DesignCall         `json:"calls,omitempty"`  // design problems
+	Output         string               `json:"output"`           // User code output
+	CompileOutput  string               `json:"compileOutput"`    // Compile output
+	ExpectedOutput string               `json:"expectedOutput"`   // Solution code output
+	Hidden         bool                 `json:"hidden,omitempty"` // hidden test cases only show their verdict
 }
 
 type RunResult struct {
@@ -121,9 +129,8 @@ func ValidateRunRequest(w http.ResponseWriter, r *http.Request) (RunRequest, *ap
 
 	// Check if language is valid
 	if runRequest.Language != "" {
-		lang := string(sql.ProblemLanguage(runRequest.Language))
-		if runRequest.Language != lang {
-			return RunRequest{}, apierror.NewError(http.StatusBadRequest, "Invalid language: "+runRequest.Language)
+		if apiErr := ValidateLanguage(runRequest.Language); apiErr != nil {
+			return RunRequest{}, apiErr
 		}
 	}
 
@@ -144,58 +151,108 @@ func (h *Handler) FetchAndValidateProblem(r *http.Request, userId string, runReq
 	if err != nil {
 		return sql.GetProblemRow{}, apierror.NewError(http.StatusInternalServerError, "Failed to get problem")
 	}
+	if problem.RetiredAt.Valid {
+		return sql.GetProblemRow{}, apierror.NewError(http.StatusNotFound, "Problem not found")
+	}
 
-	// Check if function name is valid
-	if !strings.Contains(runRequest.SourceCode, problem.FunctionName) {
+	// Check the solution declares the function, with the declared parameters if the problem has a signature
+	if signature := ProblemSignatureFromRow(problem.Signature); signature != nil {
+		if err := signature.Check(runRequest.Language, runRequest.SourceCode, problem.FunctionName); err != nil {
+			return sql.GetProblemRow{}, apierror.NewError(http.StatusBadRequest, "Solution doesn't match the signature: "+err.Error())
+		}
+	} else if !strings.Contains(runRequest.SourceCode, problem.FunctionName) {
 		return sql.GetProblemRow{}, apierror.NewError(http.StatusBadRequest, "Correct function name: "+problem.FunctionName+" not found in "+runRequest.Language+" source code")
 	}
 
 	return problem, nil
 }
 
-// PrepareJudge0Submissions creates submissions for Judge0 from test cases
-func (h *Handler) PrepareJudge0Submissions(runRequest RunRequest, testCases []TestCase, problem sql.GetProblemRow) ([]judge0.Submission, *apierror.APIError) {
+// PrepareJudge0Submissions creates submissions for Judge0 from test cases, or batches of them with the test cases of
+// each when batching is enabled, see SplitJudge0Results
+func (h *Handler) PrepareJudge0Submissions(runRequest RunRequest, testCases []TestCase, problem sql.GetProblemRow) ([]judge0.Submission, []BatchTestCases, *apierror.APIError) {
 	var judge0Submissions []judge0.Submission // submissions for judge0 to run
+	limits := ProblemLimitsFromRow(problem)
+	design := ProblemDesignFromRow(problem.Design)
+	templateInput := TemplateInput{
+		Language:     runRequest.Language,
+		SourceCode:   runRequest.SourceCode,
+		FunctionName: problem.FunctionName,
+		Signature:    ProblemSignatureFromRow(problem.Signature),
+		Design:       design,
+		Problem: Problem{
+			Title:       problem.Title,
+			Description: problem.Description.String,
+			Tags:        problem.Tags,
+			Difficulty:  problem.Difficulty,
+			Hints:       problem.Hints,
+			Points:      problem.Points,
+			Solved:      problem.Solved,
+		},
+	}
 
 	for _, testCase := range testCases {
-		solutionRun := TemplateCreate(TemplateInput{
-			Language:     runRequest.Language,
-			SourceCode:   runRequest.SourceCode,
-			FunctionName: problem.FunctionName,
-			TestCase:     testCase,
-			Problem: Problem{
-				Title:       problem.Title,
-				Description: problem.Description.String,
-				Tags:        problem.Tags,
-				Difficulty:  problem.Difficulty,
-				Hints:       problem.Hints,
-				Points:      problem.Points,
-				Solved:      problem.Solved,
-			},
-		})
-		judge0Submissions = append(judge0Submissions, solutionRun)
+		if design != nil {
+			if apiErr := design.ValidateTestCase(problem.FunctionName, testCase); apiErr != nil {
+				return nil, nil, apiErr
+			}
+		}
+	}
+
+	if h.batchTestCases(testCases) {
+		batchSubmissions, batches, err := TemplateBatches(templateInput, testCases, limits)
+		if err != nil {
+			return nil, nil, apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+		}
+		if batches != nil {
+			return batchSubmissions, batches, nil
+		}
+		// The time limits leave no room for more than one test case per batch, so they run on their own
+	}
+
+	for _, testCase := range testCases {
+		templateInput.TestCase = testCase
+		solutionRun, err := TemplateCreate(templateInput)
+		if err != nil {
+			return nil, nil, apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+		}
+		judge0Submissions = append(judge0Submissions, limits.Apply(solutionRun, runRequest.Language))
 	}
 
 	// Validate submissions before sending
 	if len(judge0Submissions) == 0 {
-		return nil, apierror.NewError(http.StatusBadRequest, "Failed to create runs")
+		return nil, nil, apierror.NewError(http.StatusBadRequest, "Failed to create runs")
 	}
 
-	return judge0Submissions, nil
+	return judge0Submissions, nil, nil
 }
 
-// ProcessTestCaseResults processes each test case result and determines overall status
-func ProcessTestCaseResults(testCases []TestCase, judge0Responses []judge0.SubmissionResult) ([]RunTestCase, map[string]int, []string) {
+// batchTestCases reports whether the test cases run in batches, see TemplateBatches
+func (h *Handler) batchTestCases(testCases []TestCase) bool {
+	return h.BatchTestCases && len(testCases) > 1
+}
+
+// SplitJudge0Results splits the results of batches into the results of their test cases, results of test cases run
+// on their own (without batches) are returned as is
+func SplitJudge0Results(judge0Responses []judge0.SubmissionResult, batches []BatchTestCases) []judge0.SubmissionResult {
+	if batches == nil {
+		return judge0Responses
+	}
+	var results []judge0.SubmissionResult
+	for i, batch := range batches {
+		results = append(results, TemplateBatchResults(judge0Responses[i], batch)...)
+	}
+	return results
+}
+
+// ProcessTestCaseResults records each graded test case result and counts the statuses
+func ProcessTestCaseResults(testCases []TestCase, judge0Responses []judge0.SubmissionResult, statuses []sql.SubmissionStatus) ([]RunTestCase, map[string]int, []string) {
 	testCaseResults := make([]RunTestCase, len(judge0Responses))
 	statusMap := make(map[string]int)
 	expectedOutput := make([]string, len(testCases))
 
 	for i, resp := range judge0Responses {
 		expectedOutput[i] = testCases[i].Output
-		actualOutput := resp.Stdout
-
-		// Determine status using helper function
-		status := DetermineTestCaseStatus(resp, actualOutput, testCases[i].Output)
+		status := statuses[i]
 
 		// Record test case result
 		testCaseResults[i] = RunTestCase{
@@ -203,6 +260,7 @@ func ProcessTestCaseResults(testCases []TestCase, judge0Responses []judge0.Submi
 			Memory:         int(resp.Memory),
 			Status:         status,
 			Input:          testCases[i].Input,
+			Calls:          testCases[i].Calls,
 			Output:         resp.Stdout,
 			CompileOutput:  resp.CompileOutput,
 			ExpectedOutput: testCases[i].Output,
@@ -215,38 +273,13 @@ func ProcessTestCaseResults(testCases []TestCase, judge0Responses []judge0.Submi
 	return testCaseResults, statusMap, expectedOutput
 }
 
-// DetermineTestCaseStatus determines the status of a single test case
-func DetermineTestCaseStatus(response judge0.SubmissionResult, actualOutput, expectedOutput string) sql.SubmissionStatus {
-	if response.Status.Description != "Accepted" {
-		return sql.SubmissionStatus(response.Status.Description)
-	}
-
-	normalizedActual := NormalizeOutput(actualOutput)
-	normalizedExpected := NormalizeOutput(expectedOutput)
-
-	if normalizedActual != normalizedExpected || response.CompileOutput != "" {
-		return sql.SubmissionStatusWrongAnswer
-	}
-
-	return sql.SubmissionStatusAccepted
-}
-
-// NormalizeOutput standardizes output for comparison
-// New helper function extracted from prior code
-func NormalizeOutput(output string) string {
-	result := output
-	// Remove spaces from array elements
-	if strings.Contains(result, "[") {
-		result = strings.ReplaceAll(result, " ", "")
-	}
-	// Remove newlines
-	return strings.ReplaceAll(result, "\n", "")
-}
-
 // EvaluateRunResults processes judge0 responses and creates final result
-func (h *Handler) EvaluateRunResults(userId string, runRequest RunRequest, testCases []TestCase, judge0Responses []judge0.SubmissionResult) (*RunResult, *apierror.APIError) {
+func (h *Handler) EvaluateRunResults(ctx context.Context, userId string, runRequest RunRequest, problem sql.GetProblemRow, testCases []TestCase, judge0Responses []judge0.SubmissionResult) (*RunResult, *apierror.APIError) {
+	// Check the outputs with the problem's checker
+	statuses := h.GradeTestCases(ctx, ProblemCheckerFromRow(problem), testCases, judge0Responses)
+
 	// Process each test case
-	testCaseResults, statusMap, expectedOutput := ProcessTestCaseResults(testCases, judge0Responses)
+	testCaseResults, statusMap, expectedOutput := ProcessTestCaseResults(testCases, judge0Responses, statuses)
 
 	// Determine overall status
 	overallStatus := DetermineOverallStatus(statusMap, len(testCases))
@@ -267,6 +300,75 @@ func (h *Handler) EvaluateRunResults(userId string, runRequest RunRequest, testC
 	return &finalResult, nil
 }
 
+// PublicTestCases reads the public test cases returned with a problem
+func PublicTestCases(problem sql.GetProblemRow) ([]TestCase, *apierror.APIError) {
+	var testCases []TestCase
+	data, err := json.Marshal(problem.TestCases)
+	if err == nil {
+		err = json.Unmarshal(data, &testCases)
+	}
+	if err != nil {
+		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to get problem test cases")
+	}
+	if len(testCases) == 0 {
+		return nil, apierror.NewError(http.StatusBadRequest, "No test cases found")
+	}
+	return testCases, nil
+}
+
+// ReferenceSolution picks the reference solution expected outputs of runs are computed with, preferring the one in
+// the run's language. Returns false when the problem has no solution.
+func ReferenceSolution(problem sql.GetProblemRow, language string) (string, string, bool) {
+	solutions := InterfaceToMap(problem.Solutions)
+	if code, ok := solutions[language]; ok {
+		return language, code, true
+	}
+	languages := slices.Sorted(maps.Keys(solutions))
+	if len(languages) == 0 {
+		return "", "", false
+	}
+	return languages[0], solutions[languages[0]], true
+}
+
+// ProblemTestCaseOutputs gives test cases with the inputs of one of the problem's public test cases its stored
+// output, which checkers read as the author wrote it, e.g. every valid answer of an any checker. It returns the
+// indexes of the other test cases, whose expected outputs are computed by the reference solution.
+func ProblemTestCaseOutputs(problem sql.GetProblemRow, testCases []TestCase) ([]TestCase, []int) {
+	problemTestCases, _ := PublicTestCases(problem)
+
+	withOutputs := slices.Clone(testCases)
+	var indexes []int
+	for i, testCase := range withOutputs {
+		problemIndex := slices.IndexFunc(problemTestCases, func(problemTestCase TestCase) bool {
+			return slices.Equal(problemTestCase.Input, testCase.Input)
+		})
+		if problemIndex < 0 {
+			indexes = append(indexes, i)
+			continue
+		}
+		withOutputs[i].Output = problemTestCases[problemIndex].Output
+	}
+	return withOutputs, indexes
+}
+
+// ReferenceOutputs sets the expected output of the test cases at indexes to what the reference solution printed for
+// them, responses holds one result per index. A test case the reference solution fails on is invalid, e.g. its
+// input breaks the problem's constraints.
+func ReferenceOutputs(testCases []TestCase, indexes []int, responses []judge0.SubmissionResult) ([]TestCase, *apierror.APIError) {
+	withOutputs := slices.Clone(testCases)
+	for i, index := range indexes {
+		switch status := sql.SubmissionStatus(responses[i].Status.Description); status {
+		case sql.SubmissionStatusAccepted:
+			withOutputs[index].Output = strings.TrimSpace(responses[i].Stdout)
+		case sql.SubmissionStatusInternalError:
+			return nil, apierror.NewError(http.StatusInternalServerError, "Failed to run the reference solution")
+		default:
+			return nil, apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Invalid test case %d: the reference solution failed with %s", index+1, status))
+		}
+	}
+	return withOutputs, nil
+}
+
 // DetermineOverallStatus calculates the overall submission status
 func DetermineOverallStatus(statusMap map[string]int, totalTestCases int) sql.SubmissionStatus {
 	if statusMap[string(sql.SubmissionStatusAccepted)] == totalTestCases {
@@ -290,20 +392,68 @@ func (h *Handler) ExecuteCodeRun(r *http.Request, userId string, runRequest RunR
 		return nil, apiErr
 	}
 
+	// Runs without test cases of their own use the problem's public ones, hidden test cases are only for submissions
+	var referenceIndexes []int
+	if len(runRequest.TestCases) == 0 {
+		if runRequest.TestCases, apiErr = PublicTestCases(problem); apiErr != nil {
+			return nil, apiErr
+		}
+	} else {
+		runRequest.TestCases, referenceIndexes = ProblemTestCaseOutputs(problem, runRequest.TestCases)
+	}
+
 	// Create submissions for judge0
-	submissions, apiErr := h.PrepareJudge0Submissions(runRequest, runRequest.TestCases, problem)
+	submissions, batches, apiErr := h.PrepareJudge0Submissions(runRequest, runRequest.TestCases, problem)
 	if apiErr != nil {
 		return nil, apiErr
 	}
 
-	// Send submissions to judge0
-	judge0Responses, err := h.Judge0Client.CreateSubmissionBatchAndWait(submissions)
-	if err != nil {
+	// Expected outputs of the user's own test cases are computed by the problem's reference solution alongside the
+	// user's code
+	testCases := runRequest.TestCases
+	referenceLanguage, referenceCode, hasReference := ReferenceSolution(problem, runRequest.Language)
+	hasReference = hasReference && len(referenceIndexes) > 0
+	var referenceTestCases []TestCase
+	var referenceSubmissions []judge0.Submission
+	var referenceBatches []BatchTestCases
+	if hasReference {
+		for _, index := range referenceIndexes {
+			referenceTestCases = append(referenceTestCases, testCases[index])
+		}
+		referenceSubmissions, referenceBatches, apiErr = h.PrepareJudge0Submissions(RunRequest{Language: referenceLanguage, SourceCode: referenceCode}, referenceTestCases, problem)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	// Send submissions to judge0, batches are never cached
+	cached := batches == nil && referenceBatches == nil
+	judge0Responses, err := h.ExecuteSubmissions(r.Context(), problem.ID, append(submissions, referenceSubmissions...), cached)
+	var batchErr *judge0.BatchError
+	if r.Context().Err() != nil {
+		// Client went away, judge0 polling already stopped
+		return nil, apierror.NewError(http.StatusRequestTimeout, "Run cancelled")
+	} else if apiErr := ExecutorError(err); apiErr != nil {
+		log.Printf("Run of problem %d failed: %v", runRequest.ProblemID, err)
+		return nil, apiErr
+	} else if errors.As(err, &batchErr) {
+		// Rejected test cases come back as internal errors, the rest are still graded
+		log.Printf("Run of problem %d partially failed: %v", runRequest.ProblemID, batchErr)
+	} else if err != nil {
 		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create solution submission")
 	}
 
+	referenceResponses := judge0Responses[len(submissions):]
+	judge0Responses = SplitJudge0Results(judge0Responses[:len(submissions)], batches)
+	if hasReference {
+		referenceResponses = SplitJudge0Results(referenceResponses, referenceBatches)
+		if testCases, apiErr = ReferenceOutputs(testCases, referenceIndexes, referenceResponses); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
 	// Process results
-	runResult, apiErr := h.EvaluateRunResults(userId, runRequest, runRequest.TestCases, judge0Responses)
+	runResult, apiErr := h.EvaluateRunResults(r.Context(), userId, runRequest, problem, testCases, judge0Responses)
 	if apiErr != nil {
 		return nil, apiErr
 	}
@@ -325,7 +475,13 @@ func (h *Handler) CreateRunRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, apiErr := h.ExecuteCodeRun(r, userId, body)
+	ctx, release, ok := h.AcquireExecutionQuota(w, r, QuotaRuns)
+	if !ok {
+		return
+	}
+	defer release()
+
+	response, apiErr := h.ExecuteCodeRun(r.WithContext(ctx), userId, body)
 	if apiErr != nil {
 		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
 		return
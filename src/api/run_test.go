@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestCreateRun(t *testing.T) {
+	twoSum := TestCase{
+		Input: []TestCaseInput{
+			{Name: "nums", Type: IntArrayType, Value: "[2, 7, 11, 15]"},
+			{Name: "target", Type: IntType, Value: "9"},
+		},
+		Output: "[0, 1]",
+	}
+
+	testCases := []TestingCase{
+		{
+			name: "Run two sum",
+			body: RunRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				ProblemID:  1,
+				TestCases:  []TestCase{twoSum},
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Run custom test case against the reference solution",
+			body: RunRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				ProblemID:  1,
+				TestCases: []TestCase{{
+					Input: []TestCaseInput{
+						{Name: "nums", Type: IntArrayType, Value: "[3, 3]"},
+						{Name: "target", Type: IntType, Value: "6"},
+					},
+				}},
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Run public test cases",
+			body: RunRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				ProblemID:  1,
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Run without problem id",
+			body: RunRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				TestCases:  []TestCase{twoSum},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run without source code",
+			body: RunRequest{
+				Language:  "python",
+				ProblemID: 1,
+				TestCases: []TestCase{twoSum},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run without function name",
+			body: RunRequest{
+				Language:   "python",
+				SourceCode: "print([0, 1])",
+				ProblemID:  1,
+				TestCases:  []TestCase{twoSum},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPost, "/runs", testCase.body)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.CreateRunRoute)
+		})
+	}
+}
+
+func TestCreatePlaygroundRun(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name: "Run program with stdin",
+			body: PlaygroundRequest{
+				Language:   "python",
+				SourceCode: "print(input())",
+				Stdin:      "hello",
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Run program with compiler options and arguments",
+			body: PlaygroundRequest{
+				Language:             "cpp",
+				SourceCode:           "int main(int argc, char** argv) { return 0; }",
+				CompilerOptions:      "-O2",
+				CommandLineArguments: "a b",
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Run program without language",
+			body: PlaygroundRequest{
+				SourceCode: "print(1)",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run program without source code",
+			body: PlaygroundRequest{
+				Language: "python",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Run program with too long compiler options",
+			body: PlaygroundRequest{
+				Language:        "cpp",
+				SourceCode:      "int main() { return 0; }",
+				CompilerOptions: strings.Repeat("-O2 ", 200),
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPost, "/runs/playground", testCase.body)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.CreatePlaygroundRunRoute)
+		})
+	}
+}
+
+func TestProblemTestCaseOutputs(t *testing.T) {
+	t.Parallel()
+
+	stored := TestCase{
+		Input:  []TestCaseInput{{Name: "n", Type: IntType, Value: "4"}},
+		Output: "[0, 1]\n[1, 0]",
+	}
+	problem := sql.GetProblemRow{TestCases: []TestCase{stored}}
+
+	testCases, indexes := ProblemTestCaseOutputs(problem, []TestCase{
+		{Input: []TestCaseInput{{Name: "n", Type: IntType, Value: "5"}}},
+		{Input: stored.Input},
+	})
+	if !slices.Equal(indexes, []int{0}) {
+		t.Errorf("Expected only the user's test case to need a reference output, got indexes %v", indexes)
+	}
+	if testCases[1].Output != stored.Output {
+		t.Errorf("Expected the stored output %q, got %q", stored.Output, testCases[1].Output)
+	}
+
+	response := judge0.SubmissionResult{Stdout: "[0, 2]\n"}
+	response.Status.Description = string(sql.SubmissionStatusAccepted)
+	withOutputs, apiErr := ReferenceOutputs(testCases, indexes, []judge0.SubmissionResult{response})
+	if apiErr != nil {
+		t.Fatalf("Failed to set reference outputs: %s", apiErr.Message())
+	}
+	if withOutputs[0].Output != "[0, 2]" || withOutputs[1].Output != stored.Output {
+		t.Errorf("Expected outputs [0, 2] and the stored output, got %q and %q", withOutputs[0].Output, withOutputs[1].Output)
+	}
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/apierror"
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// SignatureParameter is a parameter of the function solutions implement
+type SignatureParameter struct {
+	Name string       `json:"name"`
+	Type TestCaseType `json:"type"`
+}
+
+// ProblemSignature declares the function solutions implement. It types the test case inputs in the
+// templates, generates the starter code and is checked against submitted solutions.
+type ProblemSignature struct {
+	Parameters []SignatureParameter `json:"parameters"`
+	ReturnType TestCaseType         `json:"returnType"`
+}
+
+// signatureTypes are the types parameters and return values may have
+var signatureTypes = []TestCaseType{
+	IntType, LongType, FloatType, DoubleType, StringType, CharType, BoolType,
+	IntArrayType, FloatArrayType, DoubleArrayType, StringArrayType, BoolArrayType,
+	Int2DArrayType, String2DArrayType, ListNodeType, TreeNodeType, GraphType, MapType,
+}
+
+var signatureIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ProblemSignatureFromRow reads the signature stored with a problem, problems created without one have none
+func ProblemSignatureFromRow(signature []byte) *ProblemSignature {
+	if len(signature) == 0 {
+		return nil
+	}
+	var decoded ProblemSignature
+	if err := json.Unmarshal(signature, &decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+// Validate checks the parameter names and types, boolean spellings are normalized to bool
+func (s *ProblemSignature) Validate() *apierror.APIError {
+	names := map[string]bool{}
+	for i, parameter := range s.Parameters {
+		if !signatureIdentifier.MatchString(parameter.Name) {
+			return apierror.NewError(http.StatusBadRequest, "Invalid signature parameter name: "+parameter.Name)
+		}
+		if names[parameter.Name] {
+			return apierror.NewError(http.StatusBadRequest, "Duplicate signature parameter name: "+parameter.Name)
+		}
+		names[parameter.Name] = true
+
+		parameterType := templateInputType(parameter.Type)
+		if !slices.Contains(signatureTypes, parameterType) {
+			return apierror.NewError(http.StatusBadRequest, "Invalid signature parameter type: "+string(parameter.Type))
+		}
+		if parameterType != parameter.Type {
+			s.Parameters[i].Type = parameterType
+		}
+	}
+
+	if returnType := templateInputType(s.ReturnType); returnType != s.ReturnType {
+		s.ReturnType = returnType
+	}
+	if !slices.Contains(signatureTypes, s.ReturnType) {
+		return apierror.NewError(http.StatusBadRequest, "Invalid signature return type: "+string(s.ReturnType))
+	}
+	return nil
+}
+
+// ValidateTestCase checks that a test case has an input of the declared type for every parameter
+func (s *ProblemSignature) ValidateTestCase(testCase TestCase) *apierror.APIError {
+	if len(testCase.Input) != len(s.Parameters) {
+		return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Invalid test case: expected %d inputs, found %d", len(s.Parameters), len(testCase.Input)))
+	}
+	for i, input := range testCase.Input {
+		if templateInputType(input.Type) != s.Parameters[i].Type {
+			return apierror.NewError(http.StatusBadRequest, fmt.Sprintf("Invalid test case: input %s should be %s, found %s", s.Parameters[i].Name, s.Parameters[i].Type, input.Type))
+		}
+	}
+	return nil
+}
+
+// TestCase types the test case inputs with the declared parameter types
+func (s *ProblemSignature) TestCase(testCase TestCase) TestCase {
+	inputs := make([]TestCaseInput, len(testCase.Input))
+	for i, input := range testCase.Input {
+		if i < len(s.Parameters) {
+			input.Type = s.Parameters[i].Type
+		}
+		inputs[i] = input
+	}
+	testCase.Input = inputs
+	return testCase
+}
+
+// signatureStubs generate the starter code of a language, an empty stub means the language can't express the signature
+var signatureStubs = map[string]func(functionName string, signature ProblemSignature) string{
+	"c":          templateCStub,
+	"cpp":        templateCppStub,
+	"csharp":     templateCsharpStub,
+	"go":         templateGoStub,
+	"java":       templateJavaStub,
+	"javascript": templateJavascriptStub,
+	"kotlin":     templateKotlinStub,
+	"python":     templatePythonStub,
+	"ruby":       templateRubyStub,
+	"rust":       templateRustStub,
+	"typescript": templateTypescriptStub,
+}
+
+// Stub generates the starter code of a language in the style of leetcode's
+func (s *ProblemSignature) Stub(language string, functionName string) string {
+	stub, ok := signatureStubs[language]
+	if !ok {
+		return ""
+	}
+	return stub(functionName, *s)
+}
+
+// Stubs generates the starter code of every enabled language
+func (s *ProblemSignature) Stubs(functionName string) map[string]string {
+	stubs := map[string]string{}
+	for _, language := range judge0.Languages().Languages() {
+		if !language.Enabled {
+			continue
+		}
+		if stub := s.Stub(language.Language, functionName); stub != "" {
+			stubs[language.Language] = stub
+		}
+	}
+	return stubs
+}
+
+// signatureDeclarations match up to the opening parenthesis of a function declaration in each language.
+// C like languages start with the return type, a call like "return f(" isn't a declaration.
+var signatureDeclarations = map[string]string{
+	"c":          `(?m)(\w+|[>\]*&])[\s*&]*\b%s\s*\(`,
+	"cpp":        `(?m)(\w+|[>\]*&])[\s*&]*\b%s\s*\(`,
+	"csharp":     `(?m)(\w+|[>\]*&])[\s*&]*\b%s\s*\(`,
+	"java":       `(?m)(\w+|[>\]*&])[\s*&]*\b%s\s*\(`,
+	"go":         `(?m)()\bfunc\s+(?:\([^)]*\)\s*)?%s\s*\(`,
+	"kotlin":     `(?m)()\bfun\s+%s\s*\(`,
+	"rust":       `(?m)()\bfn\s+%s\s*\(`,
+	"python":     `(?m)()\bdef\s+%s\s*\(`,
+	"ruby":       `(?m)()\bdef\s+(?:self\.)?%s\b\s*\(?`,
+	"javascript": `(?m)()(?:\bfunction\s+%[1]s|\b%[1]s\s*=\s*(?:async\s+)?(?:function\b\s*)?|\b%[1]s\s*:\s*function\b\s*|^[ \t]*(?:(?:public|private|static|async)\s+)*%[1]s)\s*\(`,
+	"typescript": `(?m)()(?:\bfunction\s+%[1]s|\b%[1]s\s*=\s*(?:async\s+)?(?:function\b\s*)?|\b%[1]s\s*:\s*function\b\s*|^[ \t]*(?:(?:public|private|static|async)\s+)*%[1]s)\s*\(`,
+}
+
+// signatureTypeNames are the parameter types solutions must declare in languages whose templates decode by type.
+// Python, javascript and ruby aren't typed, and typescript's types are erased, so only the parameters are counted.
+var signatureTypeNames = map[string]func(TestCaseType) []string{
+	"c":      templateCParameterTypes,
+	"cpp":    func(t TestCaseType) []string { return []string{templateCppTypes[t]} },
+	"csharp": func(t TestCaseType) []string { return []string{templateCsharpTypes[t]} },
+	"go":     func(t TestCaseType) []string { return []string{templateGoTypes[t]} },
+	"java":   func(t TestCaseType) []string { return []string{templateJavaTypes[t]} },
+	"kotlin": func(t TestCaseType) []string { return []string{templateKotlinTypes[t]} },
+	"rust":   func(t TestCaseType) []string { return []string{templateRustTypes[t]} },
+}
+
+// Check finds the solution's declaration of the function and compares its parameters with the signature
+func (s *ProblemSignature) Check(language string, sourceCode string, functionName string) error {
+	pattern, ok := signatureDeclarations[language]
+	if !ok {
+		return fmt.Errorf("unsupported language %s", language)
+	}
+
+	parameters, found := signatureParameters(language, regexp.MustCompile(fmt.Sprintf(pattern, regexp.QuoteMeta(functionName))), sourceCode)
+	if !found {
+		return fmt.Errorf("function %s not found in %s source code", functionName, language)
+	}
+
+	var expected []string
+	typeNames, typed := signatureTypeNames[language]
+	for _, parameter := range s.Parameters {
+		if typed {
+			expected = append(expected, typeNames(parameter.Type)...)
+		} else {
+			expected = append(expected, string(parameter.Type))
+		}
+	}
+	if language == "c" {
+		if templateCReturnKind(s.ReturnType) == "" {
+			return fmt.Errorf("c solutions can't return a %s", s.ReturnType)
+		}
+		expected = append(expected, templateCReturnParameterTypes(s.ReturnType)...)
+	}
+
+	if len(parameters) != len(expected) {
+		return fmt.Errorf("%s should take %d parameters, found %d", functionName, len(expected), len(parameters))
+	}
+	if !typed {
+		return nil
+	}
+	for i, parameter := range parameters {
+		if signatureNormalizeType(string(parameter.Type)) != signatureNormalizeType(expected[i]) {
+			return fmt.Errorf("parameter %s of %s should be %s, found %s", parameter.Name, functionName, expected[i], parameter.Type)
+		}
+	}
+	return nil
+}
+
+// signatureParameters finds the declaration and splits its parameter list into names and types
+func signatureParameters(language string, declaration *regexp.Regexp, sourceCode string) ([]SignatureParameter, bool) {
+	for _, match := range declaration.FindAllStringSubmatchIndex(sourceCode, -1) {
+		if match[2] >= 0 && slices.Contains([]string{"return", "new", "else"}, sourceCode[match[2]:match[3]]) {
+			continue
+		}
+		// leetcode's starter code mentions functions in comments
+		line := strings.TrimSpace(sourceCode[strings.LastIndex(sourceCode[:match[0]], "\n")+1 : match[0]])
+		if strings.Contains(line, "//") || strings.HasPrefix(line, "*") || strings.HasPrefix(line, "/*") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rest := sourceCode[match[1]:]
+		var list string
+		if strings.HasSuffix(sourceCode[match[0]:match[1]], "(") {
+			list = signatureParenthesized(rest)
+		} else {
+			// ruby methods may leave out the parentheses
+			list, _, _ = strings.Cut(rest, "\n")
+		}
+
+		var parameters []SignatureParameter
+		for _, parameter := range signatureSplit(list) {
+			if parameter = strings.TrimSpace(parameter); parameter != "" {
+				parameters = append(parameters, signatureParameter(language, parameter))
+			}
+		}
+		return signatureReceivers(language, parameters), true
+	}
+	return nil, false
+}
+
+// signatureParenthesized returns the text up to the parenthesis closing the one just opened
+func signatureParenthesized(text string) string {
+	depth := 1
+	for i, r := range text {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+// signatureSplit splits a parameter list at the commas outside of brackets
+func signatureSplit(list string) []string {
+	var parameters []string
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			if depth > 0 && !(r == '>' && i > 0 && list[i-1] == '-') {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parameters = append(parameters, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parameters, list[start:])
+}
+
+// signatureParameter reads the name and type of a declared parameter, default values are dropped
+func signatureParameter(language string, parameter string) SignatureParameter {
+	if name, _, found := strings.Cut(parameter, "="); found && language != "go" {
+		parameter = strings.TrimSpace(name)
+	}
+
+	switch language {
+	case "python", "ruby", "javascript":
+		name, _, _ := strings.Cut(parameter, ":")
+		return SignatureParameter{Name: strings.TrimSpace(name)}
+	case "typescript", "kotlin", "rust":
+		name, parameterType, _ := strings.Cut(parameter, ":")
+		name = strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "mut ")), "?")
+		return SignatureParameter{Name: name, Type: TestCaseType(strings.TrimSpace(parameterType))}
+	case "go":
+		fields := strings.Fields(parameter)
+		if len(fields) == 1 {
+			return SignatureParameter{Name: fields[0]} // grouped, typed by the parameters after it
+		}
+		return SignatureParameter{Name: fields[0], Type: TestCaseType(strings.Join(fields[1:], " "))}
+	}
+
+	// C like languages declare the type first, the name is the last identifier
+	name := regexp.MustCompile(`\w+$`).FindString(parameter)
+	return SignatureParameter{Name: name, Type: TestCaseType(strings.TrimSpace(strings.TrimSuffix(parameter, name)))}
+}
+
+// signatureReceivers drops python's self and rust's receiver, and types go's grouped parameters
+func signatureReceivers(language string, parameters []SignatureParameter) []SignatureParameter {
+	switch language {
+	case "python":
+		if len(parameters) > 0 && (parameters[0].Name == "self" || parameters[0].Name == "cls") {
+			parameters = parameters[1:]
+		}
+	case "rust":
+		if len(parameters) > 0 && strings.HasSuffix(parameters[0].Name, "self") {
+			parameters = parameters[1:]
+		}
+	case "go":
+		for i := len(parameters) - 2; i >= 0; i-- {
+			if parameters[i].Type == "" {
+				parameters[i].Type = parameters[i+1].Type
+			}
+		}
+	}
+	return parameters
+}
+
+var signatureTypeNoise = regexp.MustCompile(`\b(?:const|final|mut|struct)\b|(?:\w+::)+|\b(?:java\.util|System\.Collections\.Generic)\.|[\s&]`)
+
+// signatureNormalizeType drops what doesn't change the type a template passes, like const, references and namespaces
+func signatureNormalizeType(typeName string) string {
+	return signatureTypeNoise.ReplaceAllString(typeName, "")
+}
+
+// signatureStubParameters joins the parameters declared by parameter
+func signatureStubParameters(signature ProblemSignature, parameter func(SignatureParameter) string) string {
+	parameters := make([]string, len(signature.Parameters))
+	for i, p := range signature.Parameters {
+		parameters[i] = parameter(p)
+	}
+	return strings.Join(parameters, ", ")
+}
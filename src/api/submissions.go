@@ -3,16 +3,22 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"kadane.xyz/go-backend/v2/src/apierror"
 	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/resultcache"
 	"kadane.xyz/go-backend/v2/src/sql/sql"
 )
 
@@ -32,6 +38,7 @@ type Submission struct {
 	SubmittedCode   string      `json:"submittedCode"`
 	SubmittedStdin  string      `json:"submittedStdin"`
 	ProblemID       int32       `json:"problemId"`
+	ProblemVersion  int32       `json:"problemVersion,omitempty"` // judged against, zero before problems were versioned
 	CreatedAt       time.Time   `json:"createdAt"`
 	Starred         bool        `json:"starred"`
 	FailedTestCase  RunTestCase `json:"failedTestCase,omitempty"`
@@ -61,9 +68,8 @@ func ValidateSubmissionRequest(request SubmissionRequest) *apierror.APIError {
 	}
 
 	// Check if language is valid
-	lang := string(sql.ProblemLanguage(request.Language))
-	if request.Language == "" || request.Language != lang {
-		return apierror.NewError(http.StatusBadRequest, "Invalid language: "+request.Language)
+	if apiErr := ValidateLanguage(request.Language); apiErr != nil {
+		return apiErr
 	}
 
 	if request.SourceCode == "" {
@@ -96,7 +102,16 @@ func (h *Handler) FetchProblemAndTestCases(ctx context.Context, problemID int32,
 		return sql.GetProblemRow{}, nil, apierror.NewError(http.StatusBadRequest, "No test cases found")
 	}
 
-	// Convert database test cases to our internal format
+	testCases, err := TestCasesFromRows(problemTestCases)
+	if err != nil {
+		return sql.GetProblemRow{}, nil, apierror.NewError(http.StatusInternalServerError, "Failed to get problem test cases")
+	}
+
+	return problem, testCases, nil
+}
+
+// TestCasesFromRows converts the stored test cases of a problem to our internal format
+func TestCasesFromRows(problemTestCases []sql.GetProblemTestCasesRow) ([]TestCase, error) {
 	var testCases []TestCase
 	for _, testCase := range problemTestCases {
 		var testCaseInput []TestCaseInput
@@ -117,51 +132,77 @@ func (h *Handler) FetchProblemAndTestCases(ctx context.Context, problemID int32,
 			testCaseInput = []TestCaseInput{}
 		}
 
+		// Design problems call methods instead of passing inputs
+		var calls []DesignCall
+		if len(testCase.Calls) > 0 {
+			if err := json.Unmarshal(testCase.Calls, &calls); err != nil {
+				return nil, err
+			}
+		}
+
 		testCases = append(testCases, TestCase{
-			Input:  testCaseInput,
-			Output: testCase.Output,
+			Description: testCase.Description,
+			Input:       testCaseInput,
+			Calls:       calls,
+			Output:      testCase.Output,
+			Visibility:  testCase.Visibility,
 		})
 	}
-
-	if len(testCases) == 0 {
-		return sql.GetProblemRow{}, nil, apierror.NewError(http.StatusBadRequest, "No test cases found")
-	}
-
-	return problem, testCases, nil
+	return testCases, nil
 }
 
-// PrepareSubmissions creates Judge0 submissions for each test case
-func (h *Handler) PrepareSubmissions(request SubmissionRequest, testCases []TestCase, problem sql.GetProblemRow) ([]judge0.Submission, *apierror.APIError) {
+// PrepareSubmissions creates Judge0 submissions for each test case, or batches of them with the test cases of each
+// when batching is enabled, see TemplateBatches
+func (h *Handler) PrepareSubmissions(request SubmissionRequest, testCases []TestCase, problem sql.GetProblemRow) ([]judge0.Submission, []BatchTestCases, *apierror.APIError) {
 	var submissions []judge0.Submission
+	limits := ProblemLimitsFromRow(problem)
+	templateInput := TemplateInput{
+		Language:     request.Language,
+		SourceCode:   request.SourceCode,
+		FunctionName: problem.FunctionName,
+		Signature:    ProblemSignatureFromRow(problem.Signature),
+		Design:       ProblemDesignFromRow(problem.Design),
+		Problem: Problem{
+			Title:       problem.Title,
+			Description: problem.Description.String,
+			Tags:        problem.Tags,
+			Difficulty:  problem.Difficulty,
+			Hints:       problem.Hints,
+			Points:      problem.Points,
+			Solved:      problem.Solved,
+		},
+	}
+
+	if h.batchTestCases(testCases) {
+		batchSubmissions, batches, err := TemplateBatches(templateInput, testCases, limits)
+		if err != nil {
+			return nil, nil, apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+		}
+		if batches != nil {
+			return batchSubmissions, batches, nil
+		}
+		// The time limits leave no room for more than one test case per batch, so they run on their own
+	}
 
 	for _, testCase := range testCases {
-		submissionRun := TemplateCreate(TemplateInput{
-			Language:     request.Language,
-			SourceCode:   request.SourceCode,
-			FunctionName: problem.FunctionName,
-			TestCase:     testCase,
-			Problem: Problem{
-				Title:       problem.Title,
-				Description: problem.Description.String,
-				Tags:        problem.Tags,
-				Difficulty:  problem.Difficulty,
-				Hints:       problem.Hints,
-				Points:      problem.Points,
-				Solved:      problem.Solved,
-			},
-		})
-		submissions = append(submissions, submissionRun)
+		templateInput.TestCase = testCase
+		submissionRun, err := TemplateCreate(templateInput)
+		if err != nil {
+			return nil, nil, apierror.NewError(http.StatusBadRequest, "Invalid test case: "+err.Error())
+		}
+		submissions = append(submissions, limits.Apply(submissionRun, request.Language))
 	}
 
 	if len(submissions) == 0 {
-		return nil, apierror.NewError(http.StatusBadRequest, "Failed to create submissions")
+		return nil, nil, apierror.NewError(http.StatusBadRequest, "Failed to create submissions")
 	}
 
-	return submissions, nil
+	return submissions, nil, nil
 }
 
-// EvaluateTestResults processes judge0 responses and finds any failures
-func EvaluateTestResults(testCases []TestCase, responses []judge0.SubmissionResult) (int32, RunTestCase, *Submission, int, float64) {
+// EvaluateTestResults processes graded judge0 responses of a submission in language, counting the passed test cases
+// and finding the first failure. A failing hidden test case is redacted to its verdict, see HiddenTestCaseMessage.
+func EvaluateTestResults(testCases []TestCase, responses []judge0.SubmissionResult, statuses []sql.SubmissionStatus, language string) (int32, RunTestCase, *Submission, int, float64) {
 	var totalMemory int
 	var totalTime float64
 	var failedSubmission *Submission
@@ -169,20 +210,12 @@ func EvaluateTestResults(testCases []TestCase, responses []judge0.SubmissionResu
 	var failedTestCase RunTestCase
 
 	for i, resp := range responses {
-		language := judge0.LanguageIDToLanguage(int(resp.Language.ID))
-
-		// Normalize outputs before comparison
-		actualOutput := NormalizeOutput(resp.Stdout)
-		expectedOutput := NormalizeOutput(testCases[i].Output)
-
-		// Check for failures
-		if actualOutput == "" || actualOutput != expectedOutput || resp.CompileOutput != "" {
-			var submissionStatus sql.SubmissionStatus
-			if resp.Status.Description != "Accepted" {
-				submissionStatus = sql.SubmissionStatus(resp.Status.Description)
-			} else {
-				submissionStatus = sql.SubmissionStatusWrongAnswer
+		// Check for failures, the first one is reported and the test cases after it are still counted
+		if statuses[i] != sql.SubmissionStatusAccepted {
+			if failedSubmission != nil {
+				continue
 			}
+			submissionStatus := statuses[i]
 
 			failedSubmission = &Submission{
 				Status:        submissionStatus,
@@ -205,7 +238,19 @@ func EvaluateTestResults(testCases []TestCase, responses []judge0.SubmissionResu
 				ExpectedOutput: testCases[i].Output,
 			}
 
-			break
+			if testCases[i].Visibility == sql.VisibilityPrivate {
+				// What the solution printed could give the input away too
+				failedSubmission.Stdout, failedSubmission.Stderr = "", ""
+				failedSubmission.Message = HiddenTestCaseMessage(i)
+				failedTestCase = RunTestCase{
+					Time:   resp.Time,
+					Memory: resp.Memory,
+					Status: submissionStatus,
+					Hidden: true,
+				}
+			}
+
+			continue
 		}
 
 		passedTestCases++
@@ -219,6 +264,40 @@ func EvaluateTestResults(testCases []TestCase, responses []judge0.SubmissionResu
 	return passedTestCases, failedTestCase, failedSubmission, totalMemory, totalTime
 }
 
+// submissionTestResultOutputLimit is how many bytes of stdout and stderr are kept per test case
+const submissionTestResultOutputLimit = 4096
+
+// SubmissionTestResults creates the stored verdicts of every test case of a submission
+func SubmissionTestResults(submissionId pgtype.UUID, testCases []TestCase, responses []judge0.SubmissionResult, statuses []sql.SubmissionStatus) []sql.CreateSubmissionTestResultParams {
+	testResults := make([]sql.CreateSubmissionTestResultParams, len(responses))
+	for i, resp := range responses {
+		testResults[i] = sql.CreateSubmissionTestResultParams{
+			SubmissionID:  submissionId,
+			TestCaseIndex: int32(i),
+			Status:        statuses[i],
+			Time:          resp.Time,
+			Memory:        int32(resp.Memory),
+			Stdout:        truncateOutput(resp.Stdout, submissionTestResultOutputLimit),
+			Stderr:        truncateOutput(resp.Stderr, submissionTestResultOutputLimit),
+			Hidden:        testCases[i].Visibility == sql.VisibilityPrivate,
+		}
+	}
+	return testResults
+}
+
+// truncateOutput cuts output to at most limit bytes without splitting a character
+func truncateOutput(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	return strings.ToValidUTF8(output[:limit], "")
+}
+
+// HiddenTestCaseMessage is all a submission shows of the hidden test case at an index it failed
+func HiddenTestCaseMessage(index int) string {
+	return fmt.Sprintf("Hidden test case %d failed", index+1)
+}
+
 // CreateDatabaseSubmission prepares the database record for a submission
 func CreateDatabaseSubmission(userId string, problem sql.GetProblemRow, request SubmissionRequest,
 	submission Submission, failedTestCase RunTestCase,
@@ -233,6 +312,7 @@ func CreateDatabaseSubmission(userId string, problem sql.GetProblemRow, request
 		ID:              pgtype.UUID{Bytes: submissionId, Valid: true},
 		AccountID:       userId,
 		ProblemID:       problem.ID,
+		ProblemVersion:  pgtype.Int4{Int32: problem.Version, Valid: true},
 		SubmittedCode:   request.SourceCode,
 		Status:          submission.Status,
 		Stdout:          submission.Stdout,
@@ -249,45 +329,16 @@ func CreateDatabaseSubmission(userId string, problem sql.GetProblemRow, request
 	}, nil
 }
 
-// ProcessSubmission handles the submission workflow
-func (h *Handler) ProcessSubmission(ctx context.Context, request SubmissionRequest, userId string) (*SubmissionResponse, *apierror.APIError) {
-	// Fetch problem and test cases
-	problem, testCases, apiErr := h.FetchProblemAndTestCases(ctx, request.ProblemID, userId)
-	if apiErr != nil {
-		return nil, apiErr
-	}
-
-	// Prepare submissions for judge0
-	submissions, apiErr := h.PrepareSubmissions(request, testCases, problem)
-	if apiErr != nil {
-		return nil, apiErr
-	}
-
-	// Total test cases count
-	totalTestCases := int32(len(testCases))
-
-	// Submit to judge0
-	submissionResponses, err := h.Judge0Client.CreateSubmissionBatchAndWait(submissions)
-	if err != nil {
-		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
-	}
-
-	if len(submissionResponses) == 0 {
-		return nil, apierror.NewError(http.StatusBadRequest, "Failed to create submissions")
-	}
-
+// AggregateSubmissionResults summarizes the judge0 results of every test case into a single submission.
+// The first failing test case is used if any, otherwise time and memory are averaged over all test cases.
+func AggregateSubmissionResults(testCases []TestCase, responses []judge0.SubmissionResult, statuses []sql.SubmissionStatus, language string) (Submission, RunTestCase, int32) {
 	// Evaluate the test results
 	passedTestCases, failedTestCase, failedSubmission, totalMemory, totalTime :=
-		EvaluateTestResults(testCases, submissionResponses)
-
-	// Verify passed test cases count
-	if passedTestCases > totalTestCases {
-		return nil, apierror.NewError(http.StatusInternalServerError, "Passed test cases greater than total test cases")
-	}
+		EvaluateTestResults(testCases, responses, statuses, language)
 
 	// Create the averaged submission
-	count := len(submissionResponses)
-	lastResp := submissionResponses[len(submissionResponses)-1]
+	count := len(responses)
+	lastResp := responses[len(responses)-1]
 
 	// If any test failed, use its details, otherwise use averages
 	memory := totalMemory / count
@@ -299,6 +350,12 @@ func (h *Handler) ProcessSubmission(ctx context.Context, request SubmissionReque
 		Stderr:        lastResp.Stderr,
 		CompileOutput: lastResp.CompileOutput,
 		Message:       lastResp.Message,
+		Language:      language,
+	}
+
+	// Hidden test cases don't show their output even when they pass
+	if testCases[len(responses)-1].Visibility == sql.VisibilityPrivate {
+		avgSubmission.Stdout, avgSubmission.Stderr = "", ""
 	}
 
 	// Use failed submission if available
@@ -306,47 +363,87 @@ func (h *Handler) ProcessSubmission(ctx context.Context, request SubmissionReque
 		avgSubmission = *failedSubmission
 	}
 
-	// Store language ID and name for db
-	lastLanguageID := int32(lastResp.Language.ID)
-	lastLanguageName := lastResp.Language.Name
+	return avgSubmission, failedTestCase, passedTestCases
+}
 
-	// Create database record
+// ProcessSubmission queues the submission with judge0 and returns it while it is still "In Queue".
+// Grading finishes in the background once every judge0 result is recorded, either by a judge0
+// callback or by polling judge0 when callbacks are not configured.
+func (h *Handler) ProcessSubmission(ctx context.Context, request SubmissionRequest, userId string) (*SubmissionResponse, *apierror.APIError) {
+	// Fetch problem and test cases
+	problem, testCases, apiErr := h.FetchProblemAndTestCases(ctx, request.ProblemID, userId)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if problem.RetiredAt.Valid {
+		return nil, apierror.NewError(http.StatusNotFound, "Problem not found")
+	}
+
+	// Solutions of problems with a signature must declare the function with its parameters
+	if signature := ProblemSignatureFromRow(problem.Signature); signature != nil {
+		if err := signature.Check(request.Language, request.SourceCode, problem.FunctionName); err != nil {
+			return nil, apierror.NewError(http.StatusBadRequest, "Solution doesn't match the signature: "+err.Error())
+		}
+	}
+
+	// Prepare submissions for judge0
+	submissions, batches, apiErr := h.PrepareSubmissions(request, testCases, problem)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// Total test cases count
+	totalTestCases := int32(len(testCases))
+
+	// Create database record before queueing so judge0 callbacks always find it
 	submissionId := uuid.New()
+	queuedSubmission := Submission{Status: sql.SubmissionStatusInQueue}
+	languageID := int32(judge0.LanguageToLanguageID(request.Language))
 	dbSubmission, err := CreateDatabaseSubmission(
-		userId, problem, request, avgSubmission,
-		failedTestCase, passedTestCases, totalTestCases,
-		lastLanguageID, lastLanguageName, submissionId)
+		userId, problem, request, queuedSubmission,
+		RunTestCase{}, 0, totalTestCases,
+		languageID, request.Language, submissionId)
 	if err != nil {
 		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
 	}
 
-	// Save to database
-	_, err = h.PostgresQueries.CreateSubmission(ctx, dbSubmission)
+	created, err := h.PostgresQueries.CreateSubmission(ctx, dbSubmission)
 	if err != nil {
 		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
 	}
 
-	// Prepare response
-	language := judge0.LanguageIDToLanguage(int(lastLanguageID))
+	// Judge0 runs queued submissions in its own order, so they wait for it by plan until they are graded
+	ctx, err = h.holdSubmissionSlot(ctx, submissionId)
+	if err != nil {
+		h.FailSubmission(context.Background(), submissionId, "Failed to queue submission")
+		return nil, apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
+	}
+
+	// Submit to judge0
+	apiErr = h.QueueSubmission(ctx, submissionId, testCases, submissions, batches)
+	if apiErr != nil {
+		h.FailSubmission(context.Background(), submissionId, "Failed to queue submission")
+		return nil, apiErr
+	}
+
+	// Without callbacks poll judge0 right away, otherwise only reconcile results whose callback never arrived
+	pollDelay := time.Duration(0)
+	if h.Judge0CallbackURL != "" {
+		pollDelay = submissionCallbackGracePeriod
+	}
+	go h.PollSubmission(submissionId, pollDelay)
 
 	response := SubmissionResponse{
 		Data: Submission{
 			Id:              submissionId.String(),
-			Stdout:          avgSubmission.Stdout,
-			Time:            avgSubmission.Time,
-			Memory:          avgSubmission.Memory,
-			Stderr:          avgSubmission.Stderr,
-			CompileOutput:   avgSubmission.CompileOutput,
-			Message:         avgSubmission.Message,
-			Status:          avgSubmission.Status,
-			Language:        language,
+			Status:          created.Status,
+			Language:        request.Language,
 			AccountID:       userId,
 			SubmittedCode:   request.SourceCode,
 			SubmittedStdin:  "",
 			ProblemID:       request.ProblemID,
-			CreatedAt:       time.Now(),
-			FailedTestCase:  failedTestCase,
-			PassedTestCases: passedTestCases,
+			CreatedAt:       created.CreatedAt.Time,
+			PassedTestCases: 0,
 			TotalTestCases:  totalTestCases,
 		},
 	}
@@ -354,6 +451,436 @@ func (h *Handler) ProcessSubmission(ctx context.Context, request SubmissionReque
 	return &response, nil
 }
 
+// default values for background grading of submissions
+const (
+	submissionPollInterval        = 500 * time.Millisecond
+	submissionPollSlowInterval    = 5 * time.Second
+	submissionPollTimeout         = 60 * time.Second
+	submissionQueueTimeout        = 30 * time.Minute
+	submissionCallbackGracePeriod = 30 * time.Second
+)
+
+// Judge0CallbackPath is the internal route judge0 sends finished submissions to
+const Judge0CallbackPath = "/v1/internal/judge0/submissions"
+
+// QueueSubmission creates the judge0 submissions of every test case in batches and records their tokens. Batches
+// of test cases, see TemplateBatches, record theirs with the token of their submission at the index of their first
+// test case. Results cached for identical judge0 submissions are recorded right away under a token of their own
+// instead.
+func (h *Handler) QueueSubmission(ctx context.Context, submissionId uuid.UUID, testCases []TestCase, submissions []judge0.Submission, batches []BatchTestCases) *apierror.APIError {
+	batched := batches != nil
+	testCaseIndexes := make([]int, len(submissions))
+	if batched {
+		for i := 1; i < len(batches); i++ {
+			testCaseIndexes[i] = testCaseIndexes[i-1] + len(batches[i-1].TestCases)
+		}
+	} else {
+		for i := range testCaseIndexes {
+			testCaseIndexes[i] = i
+		}
+	}
+
+	createToken := func(index int, token string, cacheKey string) *apierror.APIError {
+		var testCaseJson []byte
+		var err error
+		if batched {
+			testCaseJson, err = json.Marshal(batches[index])
+		} else {
+			testCaseJson, err = json.Marshal(testCases[index])
+		}
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
+		}
+
+		err = h.PostgresQueries.CreateSubmissionJudge0Token(ctx, sql.CreateSubmissionJudge0TokenParams{
+			Token:         token,
+			SubmissionID:  pgtype.UUID{Bytes: submissionId, Valid: true},
+			TestCaseIndex: int32(testCaseIndexes[index]),
+			TestCase:      testCaseJson,
+			Batch:         batched,
+			CacheKey:      pgtype.Text{String: cacheKey, Valid: cacheKey != ""},
+		})
+		if err != nil {
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
+		}
+		return nil
+	}
+
+	// Split the cached results from the submissions judge0 runs
+	keys := make([]string, len(submissions))
+	var cached []judge0.SubmissionResult
+	var missing []int
+	for i, submission := range submissions {
+		// Batches carry a nonce of their own and never match a cached result
+		if h.ResultCache == nil || batched {
+			missing = append(missing, i)
+			continue
+		}
+		keys[i] = resultcache.Key(submission)
+		result, ok := h.ResultCache.Get(ctx, keys[i])
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		result.Token = "cached-" + uuid.NewString()
+		if apiErr := createToken(i, result.Token, ""); apiErr != nil {
+			return apiErr
+		}
+		cached = append(cached, result)
+	}
+
+	for start := 0; start < len(missing); start += judge0.MaxBatchSize {
+		end := min(start+judge0.MaxBatchSize, len(missing))
+
+		batch := make([]judge0.Submission, 0, end-start)
+		for _, index := range missing[start:end] {
+			submission := judge0.EncodeSubmissionInputs(submissions[index])
+			submission.CallbackURL = h.judge0CallbackURL()
+			batch = append(batch, submission)
+		}
+
+		// A rejected test case fails the whole submission, so any error is fatal here
+		resp, err := h.Executor.CreateSubmissionBatchContext(ctx, batch)
+		if err != nil {
+			log.Printf("Failed to queue submission %s: %v", submissionId, err)
+			if apiErr := ExecutorError(err); apiErr != nil {
+				return apiErr
+			}
+			return apierror.NewError(http.StatusInternalServerError, "Failed to create submission")
+		}
+
+		for i, submissionResp := range resp.Submissions {
+			index := missing[start+i]
+			if apiErr := createToken(index, submissionResp.Token, keys[index]); apiErr != nil {
+				return apiErr
+			}
+		}
+	}
+
+	// Every token exists by now, so grading waits for the submissions judge0 still runs
+	for _, result := range cached {
+		if apiErr := h.RecordSubmissionResult(ctx, result); apiErr != nil {
+			return apiErr
+		}
+	}
+
+	return nil
+}
+
+// holdSubmissionSlot keeps a slot of the executor for the submission until releaseSubmissionSlot, executors without
+// slots return the context as is
+func (h *Handler) holdSubmissionSlot(ctx context.Context, submissionId uuid.UUID) (context.Context, error) {
+	holder, ok := h.Executor.(judge0.SlotHolder)
+	if !ok {
+		return ctx, nil
+	}
+	return holder.Hold(ctx, submissionId.String())
+}
+
+// releaseSubmissionSlot gives back the slot held for the submission, if this instance holds one
+func (h *Handler) releaseSubmissionSlot(submissionId uuid.UUID) {
+	if holder, ok := h.Executor.(judge0.SlotHolder); ok {
+		holder.Release(submissionId.String())
+	}
+}
+
+// judge0CallbackURL is the callback url of judge0 submissions, empty without callbacks. The secret is sent as basic
+// auth credentials, so it reaches the api in the Authorization header rather than in the logged request line.
+func (h *Handler) judge0CallbackURL() string {
+	if h.Judge0CallbackURL == "" {
+		return ""
+	}
+	callbackURL, err := url.Parse(h.Judge0CallbackURL + Judge0CallbackPath)
+	if err != nil {
+		log.Printf("Invalid judge0 callback url %s: %v", h.Judge0CallbackURL, err)
+		return ""
+	}
+	callbackURL.User = url.UserPassword("judge0", h.Judge0CallbackSecret)
+	return callbackURL.String()
+}
+
+// RecordSubmissionResult grades a finished judge0 result and stores it with its statuses, then grades the submission
+// once every test case has a result. Each result is graded only here, events and GradeSubmission read the statuses.
+func (h *Handler) RecordSubmissionResult(ctx context.Context, result judge0.SubmissionResult) *apierror.APIError {
+	// Judge0 statuses below 3 are still queued or processing
+	if result.Status.ID < 3 {
+		return nil
+	}
+
+	token, err := h.PostgresQueries.GetSubmissionJudge0Token(ctx, result.Token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierror.NewError(http.StatusNotFound, "Submission token not found")
+	}
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to record submission result")
+	}
+	// Callbacks and polling can deliver the same result twice, it was graded with the first one
+	if token.Result != nil {
+		return nil
+	}
+	submissionId := token.SubmissionID
+
+	testCases, results, err := Judge0TokenResults(token.TestCase, token.Batch, result)
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to unmarshal test case")
+	}
+	problemChecker, err := h.SubmissionChecker(ctx, submissionId)
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to get problem checker")
+	}
+	statuses := h.GradeTestCases(ctx, problemChecker, testCases, results)
+
+	resultJson, err := json.Marshal(result)
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to record submission result")
+	}
+	statusesJson, err := json.Marshal(statuses)
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to record submission result")
+	}
+
+	recorded, err := h.PostgresQueries.UpdateSubmissionJudge0TokenResult(ctx, sql.UpdateSubmissionJudge0TokenResultParams{
+		Result:   resultJson,
+		Statuses: statusesJson,
+		Token:    result.Token,
+	})
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to record submission result")
+	}
+	// A concurrent delivery of the same result was recorded first
+	if recorded == 0 {
+		return nil
+	}
+
+	// Deterministic results are cached for identical submissions, see QueueSubmission
+	if h.ResultCache != nil && token.CacheKey.Valid && resultcache.Cacheable(result) {
+		h.ResultCache.Set(ctx, token.CacheKey.String, token.ProblemID, result)
+	}
+
+	// Stream the test case verdicts to anyone watching the submission
+	for i, status := range statuses {
+		h.SubmissionEvents.Publish(submissionId.Bytes, SubmissionEvent{
+			Type: SubmissionEventTestCase,
+			Data: NewSubmissionTestCaseEvent(token.TestCaseIndex+int32(i), results[i], status),
+		})
+	}
+
+	err = h.PostgresQueries.UpdateSubmissionStatus(ctx, sql.UpdateSubmissionStatusParams{
+		Status: sql.SubmissionStatusProcessing,
+		ID:     submissionId,
+	})
+	if err != nil {
+		return apierror.NewError(http.StatusInternalServerError, "Failed to update submission status")
+	}
+
+	_, apiErr := h.GradeSubmission(ctx, submissionId)
+	return apiErr
+}
+
+// GradeSubmission completes the submission if every test case has a judge0 result and reports whether it did
+func (h *Handler) GradeSubmission(ctx context.Context, submissionId pgtype.UUID) (bool, *apierror.APIError) {
+	tokens, err := h.PostgresQueries.GetSubmissionJudge0Tokens(ctx, submissionId)
+	if err != nil {
+		return false, apierror.NewError(http.StatusInternalServerError, "Failed to get submission results")
+	}
+	if len(tokens) == 0 {
+		return false, nil
+	}
+
+	testCases := make([]TestCase, 0, len(tokens))
+	responses := make([]judge0.SubmissionResult, 0, len(tokens))
+	statuses := make([]sql.SubmissionStatus, 0, len(tokens))
+	var problemChecker *ProblemChecker
+	for _, token := range tokens {
+		// Still waiting on judge0
+		if token.Result == nil {
+			return false, nil
+		}
+
+		var result judge0.SubmissionResult
+		if err := json.Unmarshal(token.Result, &result); err != nil {
+			return false, apierror.NewError(http.StatusInternalServerError, "Failed to unmarshal submission result")
+		}
+		tokenTestCases, results, err := Judge0TokenResults(token.TestCase, token.Batch, result)
+		if err != nil {
+			return false, apierror.NewError(http.StatusInternalServerError, "Failed to unmarshal test case")
+		}
+		tokenStatuses, err := Judge0TokenStatuses(token.Statuses, len(results))
+		if err != nil {
+			return false, apierror.NewError(http.StatusInternalServerError, "Failed to unmarshal submission statuses")
+		}
+
+		// Results recorded before their statuses were stored are graded here
+		if tokenStatuses == nil {
+			if problemChecker == nil {
+				submissionChecker, err := h.SubmissionChecker(ctx, submissionId)
+				if err != nil {
+					return false, apierror.NewError(http.StatusInternalServerError, "Failed to get problem checker")
+				}
+				problemChecker = &submissionChecker
+			}
+			tokenStatuses = h.GradeTestCases(ctx, *problemChecker, tokenTestCases, results)
+		}
+
+		testCases = append(testCases, tokenTestCases...)
+		responses = append(responses, results...)
+		statuses = append(statuses, tokenStatuses...)
+	}
+
+	// Every verdict is kept, not only the first failure, and stored before the submission completes
+	for _, testResult := range SubmissionTestResults(submissionId, testCases, responses, statuses) {
+		if err := h.PostgresQueries.CreateSubmissionTestResult(ctx, testResult); err != nil {
+			return false, apierror.NewError(http.StatusInternalServerError, "Failed to store submission results")
+		}
+	}
+
+	// Judge0 callbacks don't include the language, so it comes from the submission
+	languageID, err := h.PostgresQueries.GetSubmissionLanguage(ctx, submissionId)
+	if err != nil {
+		return false, apierror.NewError(http.StatusInternalServerError, "Failed to get submission language")
+	}
+	language := judge0.LanguageIDToLanguage(int(languageID))
+
+	submission, failedTestCase, passedTestCases := AggregateSubmissionResults(testCases, responses, statuses, language)
+
+	failedTestCaseJson, err := json.Marshal(failedTestCase)
+	if err != nil {
+		return false, apierror.NewError(http.StatusInternalServerError, "Failed to complete submission")
+	}
+
+	// Only submissions still in queue or processing are updated, so grading twice is harmless
+	err = h.PostgresQueries.CompleteSubmission(ctx, sql.CompleteSubmissionParams{
+		Stdout:          submission.Stdout,
+		Time:            submission.Time,
+		Memory:          int32(submission.Memory),
+		Stderr:          submission.Stderr,
+		CompileOutput:   submission.CompileOutput,
+		Message:         submission.Message,
+		Status:          submission.Status,
+		FailedTestCase:  failedTestCaseJson,
+		PassedTestCases: passedTestCases,
+		ID:              submissionId,
+	})
+	if err != nil {
+		return false, apierror.NewError(http.StatusInternalServerError, "Failed to complete submission")
+	}
+
+	h.releaseSubmissionSlot(submissionId.Bytes)
+	h.SubmissionEvents.Publish(submissionId.Bytes, SubmissionEvent{Type: SubmissionEventResult})
+
+	return true, nil
+}
+
+// FailSubmission completes a submission that could not be graded with an internal error
+func (h *Handler) FailSubmission(ctx context.Context, submissionId uuid.UUID, message string) {
+	h.releaseSubmissionSlot(submissionId)
+	failedTestCaseJson, _ := json.Marshal(RunTestCase{})
+
+	err := h.PostgresQueries.CompleteSubmission(ctx, sql.CompleteSubmissionParams{
+		Message:        message,
+		Status:         sql.SubmissionStatusInternalError,
+		FailedTestCase: failedTestCaseJson,
+		ID:             pgtype.UUID{Bytes: submissionId, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to fail submission %s: %v", submissionId, err)
+		return
+	}
+
+	h.SubmissionEvents.Publish(submissionId, SubmissionEvent{Type: SubmissionEventResult})
+}
+
+// PollSubmission fetches pending judge0 results after the delay until the submission is graded.
+// It runs in the background, detached from the request that created the submission. The submission fails once judge0
+// hasn't reported it queued or processing for submissionPollTimeout, a backed up judge0 is waited on for up to
+// submissionQueueTimeout.
+func (h *Handler) PollSubmission(submissionId uuid.UUID, delay time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), delay+submissionQueueTimeout)
+	defer cancel()
+	// Graded by another instance, or failing to complete, the slot of the submission is still given back
+	defer h.releaseSubmissionSlot(submissionId)
+
+	id := pgtype.UUID{Bytes: submissionId, Valid: true}
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return
+	}
+
+	polledAt := time.Now()
+	deadline := polledAt.Add(submissionPollTimeout)
+	for {
+		tokens, err := h.PostgresQueries.GetSubmissionJudge0Tokens(ctx, id)
+		if err != nil {
+			log.Printf("Failed to get judge0 tokens for submission %s: %v", submissionId, err)
+		}
+
+		var pending []string
+		for _, token := range tokens {
+			if token.Result == nil {
+				pending = append(pending, token.Token)
+			}
+		}
+
+		for start := 0; start < len(pending); start += judge0.MaxBatchSize {
+			end := min(start+judge0.MaxBatchSize, len(pending))
+
+			results, err := h.Executor.GetSubmissionBatchContext(ctx, pending[start:end])
+			if err != nil {
+				log.Printf("Failed to get judge0 submissions for submission %s: %v", submissionId, err)
+				continue
+			}
+			for _, result := range results {
+				// Judge0 still has the submission, it is only backed up
+				if result.Status.ID < 3 {
+					deadline = time.Now().Add(submissionPollTimeout)
+				}
+				if apiErr := h.RecordSubmissionResult(ctx, result); apiErr != nil {
+					log.Printf("Failed to record judge0 submission %s: %s", result.Token, apiErr.Message())
+				}
+			}
+		}
+
+		graded, apiErr := h.GradeSubmission(ctx, id)
+		if graded {
+			return
+		}
+		if apiErr != nil {
+			log.Printf("Failed to grade submission %s: %s", submissionId, apiErr.Message())
+		}
+		if time.Now().After(deadline) {
+			h.FailSubmission(context.Background(), submissionId, "Submission timed out waiting for judge0")
+			return
+		}
+
+		// Submissions judge0 takes long on are polled less often
+		interval := submissionPollInterval
+		if time.Since(polledAt) > submissionPollTimeout {
+			interval = submissionPollSlowInterval
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			h.FailSubmission(context.Background(), submissionId, "Submission timed out waiting for judge0")
+			return
+		}
+	}
+}
+
+// ResumeSubmissions polls the submissions left in queue or processing when the server stopped, their callbacks may
+// never arrive. Submissions another instance still polls are graded once either way.
+func (h *Handler) ResumeSubmissions(ctx context.Context) error {
+	submissionIds, err := h.PostgresQueries.GetPendingSubmissions(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching pending submissions: %w", err)
+	}
+	for _, submissionId := range submissionIds {
+		go h.PollSubmission(submissionId.Bytes, 0)
+	}
+	return nil
+}
+
 func (h *Handler) CreateSubmissionRoute(w http.ResponseWriter, r *http.Request) {
 	// Get userid from middleware context
 	userId, err := GetClientUserID(w, r)
@@ -373,13 +900,51 @@ func (h *Handler) CreateSubmissionRoute(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	response, apiErr := h.ProcessSubmission(r.Context(), request, userId)
+	// Once queued the submission counts against the concurrency cap until it is graded
+	ctx, release, ok := h.AcquireExecutionQuota(w, r, QuotaSubmissions)
+	if !ok {
+		return
+	}
+	response, apiErr := h.ProcessSubmission(ctx, request, userId)
+	release()
 	if apiErr != nil {
 		apierror.SendError(w, apiErr.StatusCode(), apiErr.Message())
 		return
 	}
 
-	SendJSONResponse(w, http.StatusOK, response)
+	SendJSONResponse(w, http.StatusAccepted, response)
+}
+
+// SubmissionFromRow converts a database submission to the API response format
+func SubmissionFromRow(result sql.GetSubmissionByIDRow) (Submission, error) {
+	var failedTestCase RunTestCase
+	if result.FailedTestCase != nil {
+		if err := json.Unmarshal(result.FailedTestCase, &failedTestCase); err != nil {
+			return Submission{}, err
+		}
+	}
+
+	return Submission{
+		Id:              uuid.UUID(result.ID.Bytes).String(),
+		Stdout:          result.Stdout.String,
+		Time:            result.Time.String,
+		Memory:          int(result.Memory.Int32),
+		Stderr:          result.Stderr.String,
+		CompileOutput:   result.CompileOutput.String,
+		Message:         result.Message.String,
+		Status:          result.Status,
+		Language:        judge0.LanguageIDToLanguage(int(result.LanguageID)),
+		AccountID:       result.AccountID,
+		SubmittedCode:   result.SubmittedCode,
+		SubmittedStdin:  result.SubmittedStdin.String,
+		ProblemID:       result.ProblemID,
+		ProblemVersion:  result.ProblemVersion.Int32,
+		CreatedAt:       result.CreatedAt.Time,
+		Starred:         result.Starred,
+		FailedTestCase:  failedTestCase,
+		PassedTestCases: result.PassedTestCases.Int32,
+		TotalTestCases:  result.TotalTestCases.Int32,
+	}, nil
 }
 
 func (h *Handler) GetSubmission(w http.ResponseWriter, r *http.Request) {
@@ -410,29 +975,92 @@ func (h *Handler) GetSubmission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	submission, err := SubmissionFromRow(result)
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to unmarshal failed test case")
+		return
+	}
+
 	response := SubmissionResponse{
-		Data: Submission{
-			Id:             token,
-			Stdout:         result.Stdout.String,
-			Time:           result.Time.String,
-			Memory:         int(result.Memory.Int32),
-			Stderr:         result.Stderr.String,
-			CompileOutput:  result.CompileOutput.String,
-			Message:        result.Message.String,
-			Status:         result.Status,
-			Language:       judge0.LanguageIDToLanguage(int(result.LanguageID)),
-			AccountID:      userId,
-			SubmittedCode:  result.SubmittedCode,
-			SubmittedStdin: result.SubmittedStdin.String,
-			ProblemID:      result.ProblemID,
-			CreatedAt:      result.CreatedAt.Time,
-			Starred:        result.Starred,
-		},
+		Data: submission,
 	}
 
 	SendJSONResponse(w, http.StatusOK, response)
 }
 
+// SubmissionTestResult is the verdict of a single test case of a submission
+type SubmissionTestResult struct {
+	Index  int32                `json:"index"`
+	Status sql.SubmissionStatus `json:"status"`
+	Time   string               `json:"time"`
+	Memory int32                `json:"memory"`
+	Stdout string               `json:"stdout"`
+	Stderr string               `json:"stderr"`
+	Hidden bool                 `json:"hidden,omitempty"` // only admins see the output of hidden test cases
+}
+
+type SubmissionTestResultsResponse struct {
+	Data []SubmissionTestResult `json:"data"`
+}
+
+// GET: /submissions/{token}/results
+// Results are only shown to the submission's author and admins
+func (h *Handler) GetSubmissionResults(w http.ResponseWriter, r *http.Request) {
+	userId, err := GetClientUserID(w, r)
+	if err != nil {
+		return
+	}
+	admin := GetClientAdmin(w, r)
+
+	idUUID, err := uuid.Parse(chi.URLParam(r, "token"))
+	if err != nil {
+		apierror.SendError(w, http.StatusBadRequest, "Invalid submission ID")
+		return
+	}
+	submissionId := pgtype.UUID{Bytes: idUUID, Valid: true}
+
+	submission, err := h.PostgresQueries.GetSubmissionByID(r.Context(), sql.GetSubmissionByIDParams{
+		ID:     submissionId,
+		UserID: userId,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.SendError(w, http.StatusNotFound, "Submission not found")
+		return
+	}
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to get submission")
+		return
+	}
+	if submission.AccountID != userId && !admin {
+		apierror.SendError(w, http.StatusForbidden, "Only the author of a submission can see its results")
+		return
+	}
+
+	rows, err := h.PostgresQueries.GetSubmissionTestResults(r.Context(), submissionId)
+	if err != nil {
+		apierror.SendError(w, http.StatusInternalServerError, "Failed to get submission results")
+		return
+	}
+
+	testResults := make([]SubmissionTestResult, len(rows))
+	for i, row := range rows {
+		testResults[i] = SubmissionTestResult{
+			Index:  row.TestCaseIndex,
+			Status: row.Status,
+			Time:   row.Time,
+			Memory: row.Memory,
+			Stdout: row.Stdout,
+			Stderr: row.Stderr,
+			Hidden: row.Hidden,
+		}
+		if row.Hidden && !admin {
+			testResults[i].Stdout, testResults[i].Stderr = "", ""
+		}
+	}
+
+	SendJSONResponse(w, http.StatusOK, SubmissionTestResultsResponse{Data: testResults})
+}
+
 func (h *Handler) GetSubmissionsByUsername(w http.ResponseWriter, r *http.Request) {
 	// Get userid from middleware context
 	userId, err := GetClientUserID(w, r)
@@ -509,6 +1137,8 @@ func ExtractSubmissionQueryParams(r *http.Request) (SubmissionQueryParams, *apie
 	if status != "" {
 		// Check if status is valid
 		validStatuses := []sql.SubmissionStatus{
+			sql.SubmissionStatusInQueue,
+			sql.SubmissionStatusProcessing,
 			sql.SubmissionStatusAccepted,
 			sql.SubmissionStatusWrongAnswer,
 			sql.SubmissionStatusTimeLimitExceeded,
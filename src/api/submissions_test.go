@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+func TestCreateSubmission(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name: "Submit two sum",
+			body: SubmissionRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				ProblemID:  1,
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "Submit without problem id",
+			body: SubmissionRequest{
+				Language:   "python",
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Submit without language",
+			body: SubmissionRequest{
+				SourceCode: "class Solution:\n    def twoSum(self, nums, target):\n        return [0, 1]",
+				ProblemID:  1,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Submit without source code",
+			body: SubmissionRequest{
+				Language:  "python",
+				ProblemID: 1,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequestWithBody(t, http.MethodPost, "/submissions", testCase.body)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.CreateSubmissionRoute)
+		})
+	}
+}
+
+func TestEvaluateTestResults(t *testing.T) {
+	result := func(status sql.SubmissionStatus, stdout string) judge0.SubmissionResult {
+		var result judge0.SubmissionResult
+		result.Status.Description = string(status)
+		result.Stdout = stdout
+		return result
+	}
+	input := []TestCaseInput{{Name: "n", Type: IntType, Value: "7"}}
+
+	testCases := []struct {
+		name            string
+		visibility      sql.Visibility
+		expectedMessage string
+		expectedHidden  bool
+	}{
+		{
+			name:       "Failed public test case",
+			visibility: sql.VisibilityPublic,
+		},
+		{
+			name:            "Failed hidden test case",
+			visibility:      sql.VisibilityPrivate,
+			expectedMessage: "Hidden test case 2 failed",
+			expectedHidden:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			testCases := []TestCase{
+				{Input: input, Output: "1", Visibility: sql.VisibilityPublic},
+				{Input: input, Output: "7", Visibility: testCase.visibility},
+			}
+			responses := []judge0.SubmissionResult{result(sql.SubmissionStatusAccepted, "1"), result(sql.SubmissionStatusWrongAnswer, "7 is 8")}
+			statuses := []sql.SubmissionStatus{sql.SubmissionStatusAccepted, sql.SubmissionStatusWrongAnswer}
+
+			passed, failedTestCase, failedSubmission, _, _ := EvaluateTestResults(testCases, responses, statuses, "python")
+			if passed != 1 || failedSubmission == nil {
+				t.Fatalf("Expected the second test case to fail, passed %d", passed)
+			}
+			if failedSubmission.Language != "python" {
+				t.Errorf("Expected the language of the submission, got %q", failedSubmission.Language)
+			}
+			if failedTestCase.Hidden != testCase.expectedHidden || failedSubmission.Message != testCase.expectedMessage {
+				t.Errorf("Expected hidden %t with message %q, got %t with %q", testCase.expectedHidden, testCase.expectedMessage, failedTestCase.Hidden, failedSubmission.Message)
+			}
+			if testCase.expectedHidden && (failedTestCase.Input != nil || failedTestCase.ExpectedOutput != "" || failedSubmission.Stdout != "") {
+				t.Errorf("Expected hidden test case to be redacted, got %+v", failedTestCase)
+			}
+		})
+	}
+}
+
+func TestGetSubmissionResults(t *testing.T) {
+	testCases := []TestingCase{
+		{
+			name:           "Get results of invalid submission id",
+			urlParams:      map[string]string{"token": "not-a-uuid"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Get results of missing submission",
+			urlParams:      map[string]string{"token": "00000000-0000-0000-0000-000000000000"},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := newTestRequest(t, http.MethodGet, "/submissions/{token}/results", nil)
+			request = applyURLParams(request, testCase.urlParams)
+
+			executeTestRequest(t, request, testCase.expectedStatus, handler.GetSubmissionResults)
+		})
+	}
+}
@@ -0,0 +1,741 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// templateCDecoders are the prelude functions decoding each test case type
+var templateCDecoders = map[TestCaseType]struct{ cType, decoder string }{
+	IntType:         {"int", "kadane_int"},
+	FloatType:       {"float", "kadane_float"},
+	DoubleType:      {"double", "kadane_double"},
+	StringType:      {"char*", "kadane_string"},
+	BoolType:        {"bool", "kadane_bool"},
+	IntArrayType:    {"int*", "kadane_int_array"},
+	FloatArrayType:  {"float*", "kadane_float_array"},
+	DoubleArrayType: {"double*", "kadane_double_array"},
+	StringArrayType: {"char**", "kadane_string_array"},
+	BoolArrayType:   {"bool*", "kadane_bool_array"},
+	"null":          {"void*", "kadane_null"},
+
+	LongType:          {"long long", "kadane_long"},
+	CharType:          {"char", "kadane_char"},
+	Int2DArrayType:    {"int**", "kadane_int_matrix"},
+	String2DArrayType: {"char***", "kadane_string_matrix"},
+	ListNodeType:      {"struct ListNode*", "kadane_list"},
+	TreeNodeType:      {"struct TreeNode*", "kadane_tree"},
+	GraphType:         {"int**", "kadane_int_matrix"},
+	MapType:           {"int*", "kadane_map"},
+}
+
+// Convert the test case inputs to variables decoded from stdin, returns their declarations and the arguments.
+// C arrays don't know their length, so every array is followed by its size like on leetcode. 2D arrays and
+// graphs are followed by their size and the size of every row, maps by their keys and size after the values.
+func TemplateCInputs(testCase TestCase) (string, string) {
+	return templateInputs(testCase.Input, 0, templateCArgument)
+}
+
+// templateCArgument declares the variables decoded from the input at an index of the arguments
+func templateCArgument(index int, input TestCaseInput) (string, []string) {
+	name := fmt.Sprintf("kadaneArg%d", index)
+	inputType := templateInputType(input.Type)
+	decoder := templateCDecoders[inputType]
+	switch inputType {
+	case Int2DArrayType, String2DArrayType, GraphType:
+		return fmt.Sprintf("\tint %[1]sSize = 0;\n\tint* %[1]sColSize = NULL;\n\t%[2]s %[1]s = %[3]s(kadane_arg(%[4]d), &%[1]sSize, &%[1]sColSize);", name, decoder.cType, decoder.decoder, index),
+			[]string{name, name + "Size", name + "ColSize"}
+	case MapType:
+		return fmt.Sprintf("\tint %[1]sSize = 0;\n\tchar** %[1]sKeys = NULL;\n\t%[2]s %[1]s = %[3]s(kadane_arg(%[4]d), &%[1]sKeys, &%[1]sSize);", name, decoder.cType, decoder.decoder, index),
+			[]string{name + "Keys", name, name + "Size"}
+	}
+	if strings.HasSuffix(string(inputType), "[]") {
+		return fmt.Sprintf("\tint %[1]sSize = 0;\n\t%[2]s %[1]s = %[3]s(kadane_arg(%[4]d), &%[1]sSize);", name, decoder.cType, decoder.decoder, index),
+			[]string{name, name + "Size"}
+	}
+	return fmt.Sprintf("\t%s %s = %s(kadane_arg(%d));", decoder.cType, name, decoder.decoder, index), []string{name}
+}
+
+// What a C solution returns, arrays take a trailing int* returnSize argument and 2D arrays
+// an int** returnColumnSizes argument after it
+const (
+	templateCValue  = "value"
+	templateCArray  = "array"
+	templateCMatrix = "matrix"
+)
+
+// templateCReturns finds what the solution returns from its return type. char* is a string,
+// char** an array of strings and pointers to structs are linked lists or trees.
+func templateCReturns(functionName string, sourceCode string) string {
+	pattern := regexp.MustCompile(`([A-Za-z_][\w\s]*?)\s*(\*+)\s*` + regexp.QuoteMeta(functionName) + `\s*\(`)
+	match := pattern.FindStringSubmatch(sourceCode)
+	if match == nil {
+		return templateCValue
+	}
+	baseType := strings.Fields(match[1])
+	depth := len(match[2])
+	if len(baseType) > 0 && (baseType[len(baseType)-1] == "char" || slices.Contains(baseType, "struct")) {
+		depth-- // the pointer is the value
+	}
+
+	switch {
+	case depth <= 0:
+		return templateCValue
+	case depth == 1:
+		return templateCArray
+	}
+	return templateCMatrix
+}
+
+// templateCReturnKind is what a solution returns for a declared return type, maps have no C return convention
+func templateCReturnKind(returnType TestCaseType) string {
+	switch returnType {
+	case Int2DArrayType, String2DArrayType, GraphType:
+		return templateCMatrix
+	case MapType:
+		return ""
+	}
+	if strings.HasSuffix(string(returnType), "[]") {
+		return templateCArray
+	}
+	return templateCValue
+}
+
+// templateCParameterTypes are the C parameters an input of the type is passed as, see TemplateCInputs
+func templateCParameterTypes(inputType TestCaseType) []string {
+	cType := templateCDecoders[inputType].cType
+	switch inputType {
+	case Int2DArrayType, String2DArrayType, GraphType:
+		return []string{cType, "int", "int*"}
+	case MapType:
+		return []string{"char**", cType, "int"}
+	}
+	if strings.HasSuffix(string(inputType), "[]") {
+		return []string{cType, "int"}
+	}
+	return []string{cType}
+}
+
+// templateCReturnParameterTypes are the parameters following the inputs that hold the returned sizes
+func templateCReturnParameterTypes(returnType TestCaseType) []string {
+	switch templateCReturnKind(returnType) {
+	case templateCArray:
+		return []string{"int*"}
+	case templateCMatrix:
+		return []string{"int*", "int**"}
+	}
+	return nil
+}
+
+// templateCStub is leetcode's C starter code, arrays are followed by their sizes
+func templateCStub(functionName string, signature ProblemSignature) string {
+	if templateCReturnKind(signature.ReturnType) == "" {
+		return ""
+	}
+
+	var parameters []string
+	for _, parameter := range signature.Parameters {
+		types := templateCParameterTypes(parameter.Type)
+		names := []string{parameter.Name, parameter.Name + "Size", parameter.Name + "ColSize"}
+		if parameter.Type == MapType {
+			names = []string{parameter.Name + "Keys", parameter.Name, parameter.Name + "Size"}
+		}
+		for i, parameterType := range types {
+			parameters = append(parameters, parameterType+" "+names[i])
+		}
+	}
+	returnParameters := []string{"int* returnSize", "int** returnColumnSizes"}
+	parameters = append(parameters, returnParameters[:len(templateCReturnParameterTypes(signature.ReturnType))]...)
+
+	var note string
+	if templateCReturnKind(signature.ReturnType) != templateCValue {
+		note = "/**\n * Note: The returned array must be malloced, assume caller calls free().\n */\n"
+	}
+	return fmt.Sprintf("%s%s %s(%s) {\n    \n}", note, templateCDecoders[signature.ReturnType].cType, functionName, strings.Join(parameters, ", "))
+}
+
+// templateCPrelude reads JSON arguments and writes JSON results, it is declared before the solution
+const templateCPrelude = `
+#include <stdio.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <string.h>
+#include <ctype.h>
+#include <math.h>
+#include <limits.h>
+#include <time.h>
+
+enum { KADANE_NULL, KADANE_BOOL, KADANE_NUMBER, KADANE_STRING, KADANE_ARRAY, KADANE_OBJECT };
+
+struct ListNode;
+struct TreeNode;
+
+typedef struct KadaneJson {
+	int kind;
+	bool boolean;
+	char* text; // number literal or string value
+	struct KadaneJson* items; // array elements or object values
+	char** keys; // object keys
+	int size;
+} KadaneJson;
+
+static char* kadane_input;
+static size_t kadane_position;
+static KadaneJson kadane_args;
+
+static void kadane_fail(const char* message) {
+	fprintf(stderr, "%s\n", message);
+	exit(1);
+}
+
+static char kadane_peek(void) {
+	while (kadane_input[kadane_position] && isspace((unsigned char)kadane_input[kadane_position])) kadane_position++;
+	return kadane_input[kadane_position];
+}
+
+static void kadane_expect(char c) {
+	if (kadane_peek() != c) kadane_fail("invalid input");
+	kadane_position++;
+}
+
+static void kadane_utf8(char* out, size_t* length, unsigned long c) {
+	if (c < 0x80) {
+		out[(*length)++] = (char)c;
+	} else if (c < 0x800) {
+		out[(*length)++] = (char)(0xC0 | (c >> 6));
+		out[(*length)++] = (char)(0x80 | (c & 0x3F));
+	} else if (c < 0x10000) {
+		out[(*length)++] = (char)(0xE0 | (c >> 12));
+		out[(*length)++] = (char)(0x80 | ((c >> 6) & 0x3F));
+		out[(*length)++] = (char)(0x80 | (c & 0x3F));
+	} else {
+		out[(*length)++] = (char)(0xF0 | (c >> 18));
+		out[(*length)++] = (char)(0x80 | ((c >> 12) & 0x3F));
+		out[(*length)++] = (char)(0x80 | ((c >> 6) & 0x3F));
+		out[(*length)++] = (char)(0x80 | (c & 0x3F));
+	}
+}
+
+static unsigned long kadane_hex4(void) {
+	char digits[5] = {0};
+	for (int i = 0; i < 4; i++) {
+		if (!kadane_input[kadane_position]) kadane_fail("invalid input");
+		digits[i] = kadane_input[kadane_position++];
+	}
+	return strtoul(digits, NULL, 16);
+}
+
+// Strings are never longer than their JSON literal
+static char* kadane_parse_string(void) {
+	kadane_expect('"');
+	char* out = malloc(strlen(kadane_input + kadane_position) + 1);
+	size_t length = 0;
+	while (kadane_input[kadane_position] && kadane_input[kadane_position] != '"') {
+		char c = kadane_input[kadane_position++];
+		if (c != '\\') {
+			out[length++] = c;
+			continue;
+		}
+		c = kadane_input[kadane_position++];
+		switch (c) {
+		case 'n': out[length++] = '\n'; break;
+		case 't': out[length++] = '\t'; break;
+		case 'r': out[length++] = '\r'; break;
+		case 'b': out[length++] = '\b'; break;
+		case 'f': out[length++] = '\f'; break;
+		case 'u': {
+			unsigned long code = kadane_hex4();
+			if (code >= 0xD800 && code < 0xDC00 && kadane_input[kadane_position] == '\\' && kadane_input[kadane_position + 1] == 'u') {
+				kadane_position += 2;
+				code = 0x10000 + ((code - 0xD800) << 10) + (kadane_hex4() - 0xDC00);
+			}
+			kadane_utf8(out, &length, code);
+			break;
+		}
+		case '\0': kadane_fail("invalid input");
+		default: out[length++] = c;
+		}
+	}
+	kadane_expect('"');
+	out[length] = '\0';
+	return out;
+}
+
+static KadaneJson kadane_parse(void) {
+	KadaneJson v = {0};
+	char c = kadane_peek();
+	if (c == '[' || c == '{') {
+		bool object = c == '{';
+		char end = object ? '}' : ']';
+		int capacity = 4;
+		v.kind = object ? KADANE_OBJECT : KADANE_ARRAY;
+		v.items = malloc(sizeof(KadaneJson) * capacity);
+		v.keys = object ? malloc(sizeof(char*) * capacity) : NULL;
+		kadane_position++;
+		if (kadane_peek() == end) {
+			kadane_position++;
+			return v;
+		}
+		while (true) {
+			if (v.size == capacity) {
+				capacity *= 2;
+				v.items = realloc(v.items, sizeof(KadaneJson) * capacity);
+				if (object) v.keys = realloc(v.keys, sizeof(char*) * capacity);
+			}
+			if (object) {
+				v.keys[v.size] = kadane_parse_string();
+				kadane_expect(':');
+			}
+			v.items[v.size++] = kadane_parse();
+			if (kadane_peek() == ',') {
+				kadane_position++;
+				continue;
+			}
+			kadane_expect(end);
+			return v;
+		}
+	}
+	if (c == '"') {
+		v.kind = KADANE_STRING;
+		v.text = kadane_parse_string();
+		return v;
+	}
+	if (strncmp(kadane_input + kadane_position, "true", 4) == 0 || strncmp(kadane_input + kadane_position, "false", 5) == 0) {
+		v.kind = KADANE_BOOL;
+		v.boolean = c == 't';
+		kadane_position += v.boolean ? 4 : 5;
+		return v;
+	}
+	if (strncmp(kadane_input + kadane_position, "null", 4) == 0) {
+		kadane_position += 4;
+		return v;
+	}
+	size_t start = kadane_position;
+	while (kadane_input[kadane_position] && strchr("+-.eE0123456789", kadane_input[kadane_position])) kadane_position++;
+	if (start == kadane_position) kadane_fail("invalid input");
+	v.kind = KADANE_NUMBER;
+	v.text = strndup(kadane_input + start, kadane_position - start);
+	return v;
+}
+
+static void kadane_read_args(void) {
+	size_t capacity = 1024, length = 0, read;
+	kadane_input = malloc(capacity);
+	while ((read = fread(kadane_input + length, 1, capacity - length - 1, stdin)) > 0) {
+		length += read;
+		if (length + 1 == capacity) {
+			capacity *= 2;
+			kadane_input = realloc(kadane_input, capacity);
+		}
+	}
+	kadane_input[length] = '\0';
+	kadane_args = kadane_parse();
+}
+
+static KadaneJson* kadane_arg(int index) {
+	if (index >= kadane_args.size) kadane_fail("missing argument");
+	return &kadane_args.items[index];
+}
+
+static int kadane_int(KadaneJson* j) { return (int)strtoll(j->text, NULL, 10); }
+static long long kadane_long(KadaneJson* j) { return strtoll(j->text, NULL, 10); }
+static float kadane_float(KadaneJson* j) { return strtof(j->text, NULL); }
+static double kadane_double(KadaneJson* j) { return strtod(j->text, NULL); }
+static bool kadane_bool(KadaneJson* j) { return j->boolean; }
+static char* kadane_string(KadaneJson* j) { return j->text; }
+static char kadane_char(KadaneJson* j) { return j->text ? j->text[0] : '\0'; }
+static void* kadane_null(KadaneJson* j) { (void)j; return NULL; }
+
+// Arrays get an extra element so an empty array is not a NULL pointer
+#define KADANE_ARRAY(name, type, element) \
+	static type* name(KadaneJson* j, int* size) { \
+		type* values = malloc(sizeof(type) * (j->size + 1)); \
+		for (int i = 0; i < j->size; i++) values[i] = element(&j->items[i]); \
+		*size = j->size; \
+		return values; \
+	}
+
+KADANE_ARRAY(kadane_int_array, int, kadane_int)
+KADANE_ARRAY(kadane_long_array, long long, kadane_long)
+KADANE_ARRAY(kadane_float_array, float, kadane_float)
+KADANE_ARRAY(kadane_double_array, double, kadane_double)
+KADANE_ARRAY(kadane_bool_array, bool, kadane_bool)
+KADANE_ARRAY(kadane_string_array, char*, kadane_string)
+KADANE_ARRAY(kadane_char_array, char, kadane_char)
+
+// 2D arrays also return the size of every row
+#define KADANE_MATRIX(name, type, row) \
+	static type** name(KadaneJson* j, int* size, int** colSize) { \
+		type** values = malloc(sizeof(type*) * (j->size + 1)); \
+		*colSize = malloc(sizeof(int) * (j->size + 1)); \
+		for (int i = 0; i < j->size; i++) values[i] = row(&j->items[i], &(*colSize)[i]); \
+		*size = j->size; \
+		return values; \
+	}
+
+KADANE_MATRIX(kadane_int_matrix, int, kadane_int_array)
+KADANE_MATRIX(kadane_string_matrix, char*, kadane_string_array)
+
+// Maps are their values, the keys are returned alongside
+static int* kadane_map(KadaneJson* j, char*** keys, int* size) {
+	int* values = malloc(sizeof(int) * (j->size + 1));
+	for (int i = 0; i < j->size; i++) values[i] = kadane_int(&j->items[i]);
+	*keys = j->keys;
+	*size = j->size;
+	return values;
+}
+
+// Linked lists and trees are built once the solution has defined them, see the structures
+static struct ListNode* kadane_list(KadaneJson* j);
+static struct TreeNode* kadane_tree(KadaneJson* j);
+static void kadane_print_list(struct ListNode* v);
+static void kadane_print_tree(struct TreeNode* v);
+
+static void kadane_print_int(int v) { printf("%d", v); }
+static void kadane_print_long(long v) { printf("%ld", v); }
+static void kadane_print_long_long(long long v) { printf("%lld", v); }
+static void kadane_print_bool(bool v) { printf("%s", v ? "true" : "false"); }
+
+// Exponents of %g are written out, 1e+20 is printed as 100000000000000000000 like the other languages do
+static void kadane_print_plain(const char* text) {
+	const char* exponent = strchr(text, 'e');
+	if (exponent == NULL) {
+		printf("%s", text);
+		return;
+	}
+	if (*text == '-') putchar(*text++);
+	char digits[32];
+	int count = 0, point = -1;
+	for (const char* c = text; c < exponent; c++) {
+		if (*c == '.') point = count;
+		else digits[count++] = *c;
+	}
+	point = (point < 0 ? count : point) + atoi(exponent + 1);
+	if (point <= 0) {
+		printf("0.");
+		for (int i = point; i < 0; i++) putchar('0');
+		printf("%.*s", count, digits);
+	} else if (point >= count) {
+		printf("%.*s", count, digits);
+		for (int i = count; i < point; i++) putchar('0');
+	} else {
+		printf("%.*s.%.*s", point, digits, count - point, digits + point);
+	}
+}
+
+// Floats are printed with the fewest digits that read back the same value
+static void kadane_print_double(double v) {
+	char buffer[32];
+	for (int precision = 15; precision <= 17; precision++) {
+		snprintf(buffer, sizeof(buffer), "%.*g", precision, v);
+		if (strtod(buffer, NULL) == v) break;
+	}
+	kadane_print_plain(buffer);
+}
+
+static void kadane_print_float(float v) {
+	char buffer[32];
+	for (int precision = 6; precision <= 9; precision++) {
+		snprintf(buffer, sizeof(buffer), "%.*g", precision, v);
+		if (strtof(buffer, NULL) == v) break;
+	}
+	kadane_print_plain(buffer);
+}
+
+static void kadane_print_string(const char* v) {
+	if (!v) {
+		printf("null");
+		return;
+	}
+	putchar('"');
+	for (; *v; v++) {
+		switch (*v) {
+		case '"': printf("\\\""); break;
+		case '\\': printf("\\\\"); break;
+		case '\n': printf("\\n"); break;
+		case '\t': printf("\\t"); break;
+		case '\r': printf("\\r"); break;
+		default:
+			if ((unsigned char)*v < 0x20) printf("\\u%04x", *v);
+			else putchar(*v);
+		}
+	}
+	putchar('"');
+}
+
+static void kadane_print_char(char v) {
+	char text[2] = {v, '\0'};
+	kadane_print_string(text);
+}
+
+#define kadane_print_value(v) _Generic((v), \
+	int: kadane_print_int, \
+	long: kadane_print_long, \
+	long long: kadane_print_long_long, \
+	float: kadane_print_float, \
+	double: kadane_print_double, \
+	bool: kadane_print_bool, \
+	char: kadane_print_char, \
+	char*: kadane_print_string, \
+	const char*: kadane_print_string, \
+	struct ListNode*: kadane_print_list, \
+	struct TreeNode*: kadane_print_tree)(v)
+
+#define KADANE_PRINT_ARRAY(name, type, printer) \
+	static void name(type* values, int size) { \
+		printf("["); \
+		for (int i = 0; i < size; i++) { \
+			if (i > 0) printf(","); \
+			printer(values[i]); \
+		} \
+		printf("]"); \
+	}
+
+KADANE_PRINT_ARRAY(kadane_print_int_array, int, kadane_print_int)
+KADANE_PRINT_ARRAY(kadane_print_long_array, long, kadane_print_long)
+KADANE_PRINT_ARRAY(kadane_print_long_long_array, long long, kadane_print_long_long)
+KADANE_PRINT_ARRAY(kadane_print_float_array, float, kadane_print_float)
+KADANE_PRINT_ARRAY(kadane_print_double_array, double, kadane_print_double)
+KADANE_PRINT_ARRAY(kadane_print_bool_array, bool, kadane_print_bool)
+KADANE_PRINT_ARRAY(kadane_print_char_array, char, kadane_print_char)
+KADANE_PRINT_ARRAY(kadane_print_string_array, char*, kadane_print_string)
+KADANE_PRINT_ARRAY(kadane_print_list_array, struct ListNode*, kadane_print_list)
+KADANE_PRINT_ARRAY(kadane_print_tree_array, struct TreeNode*, kadane_print_tree)
+
+#define kadane_print_array(v, size) _Generic((v), \
+	int*: kadane_print_int_array, \
+	long*: kadane_print_long_array, \
+	long long*: kadane_print_long_long_array, \
+	float*: kadane_print_float_array, \
+	double*: kadane_print_double_array, \
+	bool*: kadane_print_bool_array, \
+	char*: kadane_print_char_array, \
+	char**: kadane_print_string_array, \
+	struct ListNode**: kadane_print_list_array, \
+	struct TreeNode**: kadane_print_tree_array)(v, size)
+
+#define KADANE_PRINT_MATRIX(name, type, printer) \
+	static void name(type** values, int size, int* colSize) { \
+		printf("["); \
+		for (int i = 0; i < size; i++) { \
+			if (i > 0) printf(","); \
+			printer(values[i], colSize[i]); \
+		} \
+		printf("]"); \
+	}
+
+KADANE_PRINT_MATRIX(kadane_print_int_matrix, int, kadane_print_int_array)
+KADANE_PRINT_MATRIX(kadane_print_long_long_matrix, long long, kadane_print_long_long_array)
+KADANE_PRINT_MATRIX(kadane_print_double_matrix, double, kadane_print_double_array)
+KADANE_PRINT_MATRIX(kadane_print_bool_matrix, bool, kadane_print_bool_array)
+KADANE_PRINT_MATRIX(kadane_print_char_matrix, char, kadane_print_char_array)
+KADANE_PRINT_MATRIX(kadane_print_string_matrix, char*, kadane_print_string_array)
+
+#define kadane_print_matrix(v, size, colSize) _Generic((v), \
+	int**: kadane_print_int_matrix, \
+	long long**: kadane_print_long_long_matrix, \
+	double**: kadane_print_double_matrix, \
+	bool**: kadane_print_bool_matrix, \
+	char**: kadane_print_char_matrix, \
+	char***: kadane_print_string_matrix)(v, size, colSize)
+`
+
+// templateCListNode and templateCTreeNode are leetcode's definitions
+const templateCListNode = `
+struct ListNode {
+	int val;
+	struct ListNode *next;
+};
+`
+
+const templateCTreeNode = `
+struct TreeNode {
+	int val;
+	struct TreeNode *left;
+	struct TreeNode *right;
+};
+`
+
+// templateCStructures builds linked lists and trees from their JSON arrays and back, it follows the
+// solution as it needs the complete ListNode and TreeNode
+const templateCStructures = `
+static struct ListNode* kadane_list(KadaneJson* j) {
+	struct ListNode* head = NULL;
+	for (int i = j->size - 1; i >= 0; i--) {
+		struct ListNode* node = malloc(sizeof(struct ListNode));
+		node->val = kadane_int(&j->items[i]);
+		node->next = head;
+		head = node;
+	}
+	return head;
+}
+
+static struct TreeNode* kadane_tree_node(KadaneJson* j) {
+	if (j->kind == KADANE_NULL) return NULL;
+	struct TreeNode* node = malloc(sizeof(struct TreeNode));
+	node->val = kadane_int(j);
+	node->left = NULL;
+	node->right = NULL;
+	return node;
+}
+
+// Trees are written in level order, null marks a missing child
+static struct TreeNode* kadane_tree(KadaneJson* j) {
+	if (j->size == 0) return NULL;
+	struct TreeNode** queue = malloc(sizeof(struct TreeNode*) * j->size);
+	int head = 0, tail = 0;
+	struct TreeNode* root = kadane_tree_node(&j->items[0]);
+	queue[tail++] = root;
+	for (int i = 1; head < tail && i < j->size; i += 2) {
+		struct TreeNode* node = queue[head++];
+		node->left = kadane_tree_node(&j->items[i]);
+		if (node->left) queue[tail++] = node->left;
+		if (i + 1 < j->size) node->right = kadane_tree_node(&j->items[i + 1]);
+		if (node->right) queue[tail++] = node->right;
+	}
+	free(queue);
+	return root;
+}
+
+static void kadane_print_list(struct ListNode* v) {
+	if (!v) {
+		printf("null");
+		return;
+	}
+	printf("[");
+	for (struct ListNode* node = v; node; node = node->next) {
+		if (node != v) printf(",");
+		printf("%d", node->val);
+	}
+	printf("]");
+}
+
+static void kadane_print_tree(struct TreeNode* v) {
+	if (!v) {
+		printf("null");
+		return;
+	}
+	int capacity = 16, size = 0;
+	struct TreeNode** nodes = malloc(sizeof(struct TreeNode*) * capacity);
+	nodes[size++] = v;
+	for (int i = 0; i < size; i++) {
+		if (!nodes[i]) continue;
+		if (size + 2 > capacity) {
+			capacity *= 2;
+			nodes = realloc(nodes, sizeof(struct TreeNode*) * capacity);
+		}
+		nodes[size++] = nodes[i]->left;
+		nodes[size++] = nodes[i]->right;
+	}
+	while (size > 0 && !nodes[size - 1]) size--;
+	printf("[");
+	for (int i = 0; i < size; i++) {
+		if (i > 0) printf(",");
+		if (nodes[i]) printf("%d", nodes[i]->val);
+		else printf("null");
+	}
+	printf("]");
+	free(nodes);
+}
+`
+
+// C template
+func TemplateCSourceCode(functionName string, declarations string, inputs string, returns string, sourceCode string) string {
+	return templateCProgram(sourceCode, declarations+"\n\t"+templateCCall(functionName, inputs, returns))
+}
+
+// templateCMain declares the arguments, calls the solution and prints what it returns
+func templateCMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// The declared return type says what the solution returns, older problems only have the solution
+	returns := templateCReturns(templateInput.FunctionName, templateInput.SourceCode)
+	if templateInput.Signature != nil {
+		returns = templateCReturnKind(templateInput.Signature.ReturnType)
+	}
+	return declarations + "\n\t" + templateCCall(templateInput.FunctionName, inputs, returns)
+}
+
+// templateCCall calls a function and prints what it returns, returnSize is only set once the call returns
+func templateCCall(functionName string, inputs string, returns string) string {
+	if inputs != "" {
+		inputs += ", "
+	}
+	switch returns {
+	case templateCArray:
+		return fmt.Sprintf(`int returnSize = 0;
+	__auto_type result = %s(%s&returnSize);
+	kadane_print_array(result, returnSize);`, functionName, inputs)
+	case templateCMatrix:
+		return fmt.Sprintf(`int returnSize = 0;
+	int* returnColumnSizes = NULL;
+	__auto_type result = %s(%s&returnSize, &returnColumnSizes);
+	kadane_print_matrix(result, returnSize, returnColumnSizes);`, functionName, inputs)
+	}
+	return fmt.Sprintf(`kadane_print_value(%s(%s));`, functionName, strings.TrimSuffix(inputs, ", "))
+}
+
+// templateCDesign constructs the class and prints the results of its methods as they return. Like on leetcode
+// the functions are prefixed with the class name, e.g. lRUCacheCreate and lRUCacheGet for LRUCache, and take
+// the object first.
+func templateCDesign(className string, calls []templateDesignCall) string {
+	prefix := strings.ToLower(className[:1]) + className[1:]
+	main := []string{
+		calls[0].Declarations,
+		fmt.Sprintf("\t%s* kadaneObject = %sCreate(%s);", className, prefix, calls[0].Arguments),
+		`	fputs("[null", stdout);`,
+	}
+	for _, call := range calls[1:] {
+		if call.Declarations != "" {
+			main = append(main, call.Declarations)
+		}
+		function := prefix + strings.ToUpper(call.Method.Name[:1]) + call.Method.Name[1:]
+		arguments := strings.TrimSuffix("kadaneObject, "+call.Arguments, ", ")
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("\t%s(%s);", function, arguments), `	fputs(",null", stdout);`)
+			continue
+		}
+		// every call has its own block for its returnSize
+		main = append(main, `	fputs(",", stdout);`, fmt.Sprintf("\t{\n\t%s\n\t}", templateCCall(function, arguments, templateCReturnKind(call.Method.ReturnType))))
+	}
+	return strings.Join(append(main, `	fputs("]", stdout);`), "\n")
+}
+
+// templateCTest times the statements of a test case in a block of their own and prints the delimiter after its result
+func templateCTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`	{
+	clock_t kadane_start = clock();
+%s
+	printf("\n%s %d %%.3f\n", (double)(clock() - kadane_start) / CLOCKS_PER_SEC);
+	fflush(stdout);
+	}`, main, delimiter, index)
+}
+
+// templateCProgram places the solution and the statements of main in the harness
+func templateCProgram(sourceCode string, main string) string {
+	return templateCPrelude + templateDefinitions(sourceCode, templateCListNode, templateCTreeNode) + fmt.Sprintf(`
+// Source Code
+%s
+`, sourceCode) + templateCStructures + fmt.Sprintf(`
+int main() {
+	kadane_read_args();
+%s
+	return 0;
+}
+`, main)
+}
+
+func TemplateC(templateInput TemplateInput) judge0.Submission {
+	declarations, inputs := TemplateCInputs(templateInput.TestCase)                                              // Get the inputs
+	sourceCode := templateCProgram(templateInput.SourceCode, templateCMain(templateInput, declarations, inputs)) // Get the source code
+
+	submission := judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID("c"),
+		SourceCode: sourceCode,
+		//ExpectedOutput: templateInput.ExpectedOutput,
+	}
+
+	return submission
+}
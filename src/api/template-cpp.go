@@ -7,42 +7,54 @@ import (
 	"kadane.xyz/go-backend/v2/src/judge0"
 )
 
-// Convert the test case inputs to a comma separated string
-func TemplateCppInputs(testCase TestCase) string {
-	var inputs []string
-
-	for _, input := range testCase.Input {
-		switch input.Type {
-		case "int[]":
-			inputValue := strings.Trim(input.Value, "[]")
-			inputs = append(inputs, fmt.Sprintf("vector<int>{%s}", inputValue))
-		case "int":
-			inputs = append(inputs, input.Value)
-		case "string":
-			inputs = append(inputs, fmt.Sprintf("%q", input.Value))
-		case "string[]":
-			inputValue := strings.Trim(input.Value, "[]")
-			// Split by comma and wrap each element in quotes
-			elements := strings.Split(inputValue, ",")
-			for i, e := range elements {
-				elements[i] = fmt.Sprintf("%q", strings.TrimSpace(e))
-			}
-			inputs = append(inputs, fmt.Sprintf("vector<string>{%s}", strings.Join(elements, ",")))
-		default:
-			inputs = append(inputs, input.Value)
-		}
-	}
+// templateCppTypes are the c++ types of the test case types
+var templateCppTypes = map[TestCaseType]string{
+	IntType:         "int",
+	FloatType:       "float",
+	DoubleType:      "double",
+	StringType:      "string",
+	BoolType:        "bool",
+	IntArrayType:    "vector<int>",
+	FloatArrayType:  "vector<float>",
+	DoubleArrayType: "vector<double>",
+	StringArrayType: "vector<string>",
+	BoolArrayType:   "vector<bool>",
+	"null":          "nullptr_t",
 
-	return strings.Join(inputs, ", ")
+	LongType:          "long long",
+	CharType:          "char",
+	Int2DArrayType:    "vector<vector<int>>",
+	String2DArrayType: "vector<vector<string>>",
+	ListNodeType:      "ListNode*",
+	TreeNodeType:      "TreeNode*",
+	GraphType:         "vector<vector<int>>",
+	MapType:           "unordered_map<string, int>",
 }
 
-// C++ template
-func TemplateCppSourceCode(functionName string, inputs string, sourceCode string) string {
-	return fmt.Sprintf(`
+// Convert the test case inputs to variables decoded from stdin, returns their declarations and the arguments.
+// Leetcode style solutions take containers by reference, so temporaries won't do.
+func TemplateCppInputs(testCase TestCase) (string, string) {
+	return templateInputs(testCase.Input, 0, templateCppArgument)
+}
+
+// templateCppArgument declares a variable decoded from the input at an index of the arguments
+func templateCppArgument(index int, input TestCaseInput) (string, []string) {
+	name := fmt.Sprintf("kadaneArg%d", index)
+	return fmt.Sprintf("\tauto %s = kadane::arg<%s>(%d);", name, templateCppTypes[templateInputType(input.Type)], index), []string{name}
+}
+
+// templateCppPrelude reads JSON arguments and writes JSON results, it is declared before the solution.
+// ListNode and TreeNode are only declared, solutions may define them, see templateCppStructures.
+const templateCppPrelude = `
 #include <iostream>
+#include <sstream>
 #include <string>
 #include <vector>
+#include <array>
+#include <list>
 #include <algorithm>
+#include <numeric>
+#include <functional>
 #include <map>
 #include <unordered_map>
 #include <set>
@@ -50,52 +62,522 @@ func TemplateCppSourceCode(functionName string, inputs string, sourceCode string
 #include <queue>
 #include <deque>
 #include <stack>
+#include <utility>
+#include <tuple>
+#include <type_traits>
+#include <climits>
 #include <cmath>
 #include <cstdio>
 #include <cstdlib>
 #include <cstring>
+#include <chrono>
 using namespace std;
 
-// Helper functions to print different types
-template<typename T>
-void printValue(const T& val) {
-	cout << val;
+struct ListNode;
+struct TreeNode;
+
+namespace kadane {
+
+struct Json {
+	enum Kind { Null, Bool, Number, String, Array, Object } kind = Null;
+	bool boolean = false;
+	string text; // number literal or string value
+	vector<Json> items;
+	vector<pair<string, Json>> fields;
+};
+
+[[noreturn]] inline void fail(const string& message) {
+	cerr << message << endl;
+	exit(1);
 }
 
-template<typename T>
-void printVector(const vector<T>& vec) {
-	cout << "[";
-	for (size_t i = 0; i < vec.size(); i++) {
-		if (i > 0) cout << ",";
-		printValue(vec[i]);
+struct Parser {
+	const string& s;
+	size_t i = 0;
+
+	explicit Parser(const string& source) : s(source) {}
+
+	char peek() {
+		while (i < s.size() && isspace((unsigned char)s[i])) i++;
+		return i < s.size() ? s[i] : '\0';
+	}
+
+	void expect(char c) {
+		if (peek() != c) fail(string("invalid input, expected ") + c);
+		i++;
+	}
+
+	void utf8(string& out, unsigned long c) {
+		if (c < 0x80) {
+			out += (char)c;
+		} else if (c < 0x800) {
+			out += (char)(0xC0 | (c >> 6));
+			out += (char)(0x80 | (c & 0x3F));
+		} else if (c < 0x10000) {
+			out += (char)(0xE0 | (c >> 12));
+			out += (char)(0x80 | ((c >> 6) & 0x3F));
+			out += (char)(0x80 | (c & 0x3F));
+		} else {
+			out += (char)(0xF0 | (c >> 18));
+			out += (char)(0x80 | ((c >> 12) & 0x3F));
+			out += (char)(0x80 | ((c >> 6) & 0x3F));
+			out += (char)(0x80 | (c & 0x3F));
+		}
+	}
+
+	unsigned long hex4() {
+		if (i + 4 > s.size()) fail("invalid input, bad escape");
+		unsigned long c = stoul(s.substr(i, 4), nullptr, 16);
+		i += 4;
+		return c;
+	}
+
+	string str() {
+		expect('"');
+		string out;
+		while (i < s.size() && s[i] != '"') {
+			char c = s[i++];
+			if (c != '\\') {
+				out += c;
+				continue;
+			}
+			if (i >= s.size()) break;
+			c = s[i++];
+			switch (c) {
+			case 'n': out += '\n'; break;
+			case 't': out += '\t'; break;
+			case 'r': out += '\r'; break;
+			case 'b': out += '\b'; break;
+			case 'f': out += '\f'; break;
+			case 'u': {
+				unsigned long code = hex4();
+				if (code >= 0xD800 && code < 0xDC00 && i + 1 < s.size() && s[i] == '\\' && s[i + 1] == 'u') {
+					i += 2;
+					code = 0x10000 + ((code - 0xD800) << 10) + (hex4() - 0xDC00);
+				}
+				utf8(out, code);
+				break;
+			}
+			default: out += c;
+			}
+		}
+		expect('"');
+		return out;
+	}
+
+	Json value() {
+		Json v;
+		char c = peek();
+		if (c == '[') {
+			i++;
+			v.kind = Json::Array;
+			if (peek() == ']') { i++; return v; }
+			while (true) {
+				v.items.push_back(value());
+				if (peek() == ',') { i++; continue; }
+				expect(']');
+				return v;
+			}
+		}
+		if (c == '{') {
+			i++;
+			v.kind = Json::Object;
+			if (peek() == '}') { i++; return v; }
+			while (true) {
+				string key = str();
+				expect(':');
+				v.fields.emplace_back(key, value());
+				if (peek() == ',') { i++; continue; }
+				expect('}');
+				return v;
+			}
+		}
+		if (c == '"') {
+			v.kind = Json::String;
+			v.text = str();
+			return v;
+		}
+		if (s.compare(i, 4, "true") == 0 || s.compare(i, 5, "false") == 0) {
+			v.kind = Json::Bool;
+			v.boolean = s[i] == 't';
+			i += v.boolean ? 4 : 5;
+			return v;
+		}
+		if (s.compare(i, 4, "null") == 0) {
+			i += 4;
+			return v;
+		}
+		size_t start = i;
+		while (i < s.size() && strchr("+-.eE0123456789", s[i])) i++;
+		if (start == i) fail("invalid input");
+		v.kind = Json::Number;
+		v.text = s.substr(start, i - start);
+		return v;
+	}
+};
+
+inline void from_json(const Json& j, int& v) { v = (int)stoll(j.text); }
+inline void from_json(const Json& j, long& v) { v = stol(j.text); }
+inline void from_json(const Json& j, long long& v) { v = stoll(j.text); }
+inline void from_json(const Json& j, float& v) { v = stof(j.text); }
+inline void from_json(const Json& j, double& v) { v = stod(j.text); }
+inline void from_json(const Json& j, bool& v) { v = j.boolean; }
+inline void from_json(const Json& j, string& v) { v = j.text; }
+inline void from_json(const Json& j, char& v) { v = j.text.empty() ? '\0' : j.text[0]; }
+inline void from_json(const Json& j, nullptr_t& v) { v = nullptr; }
+void from_json(const Json& j, ListNode*& v);
+void from_json(const Json& j, TreeNode*& v);
+template<typename T> void from_json(const Json& j, vector<T>& v);
+template<typename T> void from_json(const Json& j, unordered_map<string, T>& v);
+
+template<typename T> void from_json(const Json& j, vector<T>& v) {
+	v.clear();
+	for (const Json& item : j.items) {
+		T value;
+		from_json(item, value);
+		v.push_back(value);
 	}
-	cout << "]";
 }
 
-template<typename T>
-void printResult(const T& result) {
-	printValue(result);
+template<typename T> void from_json(const Json& j, unordered_map<string, T>& v) {
+	v.clear();
+	for (const auto& field : j.fields) from_json(field.second, v[field.first]);
 }
 
-template<typename T>
-void printResult(const vector<T>& result) {
-	printVector(result);
+inline vector<Json>& args() {
+	static vector<Json> values;
+	return values;
 }
 
-// Source Code
+inline void read_args() {
+	stringstream input;
+	input << cin.rdbuf();
+	string source = input.str();
+	args() = Parser(source).value().items;
+}
+
+template<typename T> T arg(size_t index) {
+	if (index >= args().size()) fail("missing argument");
+	T value;
+	from_json(args()[index], value);
+	return value;
+}
+
+inline void write(ostream& out, bool v);
+inline void write(ostream& out, char v);
+inline void write(ostream& out, const string& v);
+inline void write(ostream& out, const char* v);
+inline void write(ostream& out, float v);
+inline void write(ostream& out, double v);
+inline void write(ostream& out, nullptr_t);
+void write(ostream& out, const ListNode* v);
+void write(ostream& out, const TreeNode* v);
+template<typename T> typename enable_if<is_integral<T>::value>::type write(ostream& out, T v);
+template<typename A, typename B> void write(ostream& out, const pair<A, B>& v);
+template<typename T> void write(ostream& out, const vector<T>& v);
+template<typename T, size_t N> void write(ostream& out, const array<T, N>& v);
+template<typename T> void write(ostream& out, const list<T>& v);
+template<typename T> void write(ostream& out, const deque<T>& v);
+template<typename T> void write(ostream& out, const set<T>& v);
+template<typename T> void write(ostream& out, const multiset<T>& v);
+template<typename T> void write(ostream& out, const unordered_set<T>& v);
+template<typename K, typename V> void write(ostream& out, const map<K, V>& v);
+template<typename K, typename V> void write(ostream& out, const unordered_map<K, V>& v);
+
+inline void write(ostream& out, bool v) { out << (v ? "true" : "false"); }
+inline void write(ostream& out, char v) { write(out, string(1, v)); }
+inline void write(ostream& out, const char* v) { write(out, string(v)); }
+inline void write(ostream& out, nullptr_t) { out << "null"; }
+
+inline void write(ostream& out, const string& v) {
+	out << '"';
+	for (char c : v) {
+		switch (c) {
+		case '"': out << "\\\""; break;
+		case '\\': out << "\\\\"; break;
+		case '\n': out << "\\n"; break;
+		case '\t': out << "\\t"; break;
+		case '\r': out << "\\r"; break;
+		default:
+			if ((unsigned char)c < 0x20) {
+				char escaped[7];
+				snprintf(escaped, sizeof(escaped), "\\u%04x", c);
+				out << escaped;
+			} else {
+				out << c;
+			}
+		}
+	}
+	out << '"';
+}
+
+// Exponents of %g are written out, 1e+20 is written as 100000000000000000000 like the other languages do
+inline void write_plain(ostream& out, const string& text) {
+	size_t exponent = text.find('e');
+	if (exponent == string::npos) {
+		out << text;
+		return;
+	}
+	size_t start = text[0] == '-' ? 1 : 0;
+	string digits = text.substr(start, exponent - start);
+	size_t dot = digits.find('.');
+	int point = dot == string::npos ? (int)digits.size() : (int)dot;
+	if (dot != string::npos) digits.erase(dot, 1);
+	point += stoi(text.substr(exponent + 1));
+	out << text.substr(0, start);
+	if (point <= 0) {
+		out << "0." << string(-point, '0') << digits;
+	} else if (point >= (int)digits.size()) {
+		out << digits << string(point - digits.size(), '0');
+	} else {
+		out << digits.substr(0, point) << '.' << digits.substr(point);
+	}
+}
+
+// Floats are written with the fewest digits that read back the same value
+inline void write(ostream& out, double v) {
+	char buffer[32];
+	for (int precision = 15; precision <= 17; precision++) {
+		snprintf(buffer, sizeof(buffer), "%.*g", precision, v);
+		if (strtod(buffer, nullptr) == v) break;
+	}
+	write_plain(out, buffer);
+}
+
+inline void write(ostream& out, float v) {
+	char buffer[32];
+	for (int precision = 6; precision <= 9; precision++) {
+		snprintf(buffer, sizeof(buffer), "%.*g", precision, v);
+		if (strtof(buffer, nullptr) == v) break;
+	}
+	write_plain(out, buffer);
+}
+
+template<typename T> typename enable_if<is_integral<T>::value>::type write(ostream& out, T v) { out << +v; }
+
+template<typename Iterable> void write_items(ostream& out, const Iterable& v) {
+	out << '[';
+	bool first = true;
+	for (const auto& item : v) {
+		if (!first) out << ',';
+		first = false;
+		write(out, item);
+	}
+	out << ']';
+}
+
+// Fields are written in the order of their keys like Go's encoding/json does, whatever the order of the map
+template<typename Map> void write_fields(ostream& out, const Map& v) {
+	vector<pair<string, string>> fields;
+	for (const auto& field : v) {
+		// JSON keys are strings
+		ostringstream key, value;
+		write(key, field.first);
+		string text = key.str();
+		if (!text.empty() && text[0] == '"') text = text.substr(1, text.size() - 2);
+		write(value, field.second);
+		fields.emplace_back(text, value.str());
+	}
+	sort(fields.begin(), fields.end());
+	out << '{';
+	for (size_t i = 0; i < fields.size(); i++) {
+		if (i > 0) out << ',';
+		out << '"' << fields[i].first << "\":" << fields[i].second;
+	}
+	out << '}';
+}
+
+template<typename A, typename B> void write(ostream& out, const pair<A, B>& v) {
+	out << '[';
+	write(out, v.first);
+	out << ',';
+	write(out, v.second);
+	out << ']';
+}
+
+template<typename T> void write(ostream& out, const vector<T>& v) { write_items(out, v); }
+template<typename T, size_t N> void write(ostream& out, const array<T, N>& v) { write_items(out, v); }
+template<typename T> void write(ostream& out, const list<T>& v) { write_items(out, v); }
+template<typename T> void write(ostream& out, const deque<T>& v) { write_items(out, v); }
+template<typename T> void write(ostream& out, const set<T>& v) { write_items(out, v); }
+template<typename T> void write(ostream& out, const multiset<T>& v) { write_items(out, v); }
+template<typename T> void write(ostream& out, const unordered_set<T>& v) { write_items(out, v); }
+template<typename K, typename V> void write(ostream& out, const map<K, V>& v) { write_fields(out, v); }
+template<typename K, typename V> void write(ostream& out, const unordered_map<K, V>& v) { write_fields(out, v); }
+
+} // namespace kadane
+`
+
+// templateCppListNode and templateCppTreeNode are leetcode's definitions
+const templateCppListNode = `
+struct ListNode {
+	int val;
+	ListNode *next;
+	ListNode() : val(0), next(nullptr) {}
+	ListNode(int x) : val(x), next(nullptr) {}
+	ListNode(int x, ListNode *next) : val(x), next(next) {}
+};
+`
+
+const templateCppTreeNode = `
+struct TreeNode {
+	int val;
+	TreeNode *left;
+	TreeNode *right;
+	TreeNode() : val(0), left(nullptr), right(nullptr) {}
+	TreeNode(int x) : val(x), left(nullptr), right(nullptr) {}
+	TreeNode(int x, TreeNode *left, TreeNode *right) : val(x), left(left), right(right) {}
+};
+`
+
+// templateCppStructures builds linked lists and trees from their JSON arrays and back, it follows the
+// solution as it needs the complete ListNode and TreeNode
+const templateCppStructures = `
+namespace kadane {
+
+void from_json(const Json& j, ListNode*& v) {
+	v = nullptr;
+	for (size_t i = j.items.size(); i > 0; i--) {
+		ListNode* node = new ListNode(stoi(j.items[i - 1].text));
+		node->next = v;
+		v = node;
+	}
+}
+
+// Trees are written in level order, null marks a missing child
+void from_json(const Json& j, TreeNode*& v) {
+	v = nullptr;
+	if (j.items.empty()) return;
+	v = new TreeNode(stoi(j.items[0].text));
+	deque<TreeNode*> queue{v};
+	for (size_t i = 1; !queue.empty() && i < j.items.size(); i += 2) {
+		TreeNode* node = queue.front();
+		queue.pop_front();
+		if (j.items[i].kind != Json::Null) {
+			node->left = new TreeNode(stoi(j.items[i].text));
+			queue.push_back(node->left);
+		}
+		if (i + 1 < j.items.size() && j.items[i + 1].kind != Json::Null) {
+			node->right = new TreeNode(stoi(j.items[i + 1].text));
+			queue.push_back(node->right);
+		}
+	}
+}
+
+void write(ostream& out, const ListNode* v) {
+	if (!v) {
+		out << "null";
+		return;
+	}
+	vector<int> values;
+	for (; v; v = v->next) values.push_back(v->val);
+	write(out, values);
+}
+
+void write(ostream& out, const TreeNode* v) {
+	if (!v) {
+		out << "null";
+		return;
+	}
+	vector<const TreeNode*> nodes{v};
+	for (size_t i = 0; i < nodes.size(); i++) {
+		if (nodes[i]) {
+			nodes.push_back(nodes[i]->left);
+			nodes.push_back(nodes[i]->right);
+		}
+	}
+	while (!nodes.empty() && !nodes.back()) nodes.pop_back();
+	out << '[';
+	for (size_t i = 0; i < nodes.size(); i++) {
+		if (i) out << ',';
+		if (nodes[i]) out << nodes[i]->val;
+		else out << "null";
+	}
+	out << ']';
+}
+
+} // namespace kadane
+`
+
+// templateCppStub is leetcode's c++ starter code, containers are taken by reference
+func templateCppStub(functionName string, signature ProblemSignature) string {
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		cppType := templateCppTypes[parameter.Type]
+		if strings.HasPrefix(cppType, "vector") || strings.HasPrefix(cppType, "unordered_map") {
+			cppType += "&"
+		}
+		return cppType + " " + parameter.Name
+	})
+	return fmt.Sprintf("class Solution {\npublic:\n    %s %s(%s) {\n        \n    }\n};", templateCppTypes[signature.ReturnType], functionName, parameters)
+}
+
+// C++ template
+func TemplateCppSourceCode(functionName string, declarations string, inputs string, sourceCode string) string {
+	return templateCppProgram(sourceCode, templateCppMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, declarations, inputs))
+}
+
+// templateCppMain declares the arguments, calls the solution and writes its result
+func templateCppMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Leetcode style solutions are methods of a Solution class
+	call := fmt.Sprintf("%s(%s)", templateInput.FunctionName, inputs)
+	if className := templateClassName(templateInput.SourceCode); className != "" {
+		call = fmt.Sprintf("%s().%s", className, call)
+	}
+
+	return fmt.Sprintf(`%s
+	auto result = %s;
+	kadane::write(cout, result);`, declarations, call)
+}
+
+// templateCppDesign constructs the class and writes the results of its methods as they return
+func templateCppDesign(className string, calls []templateDesignCall) string {
+	main := []string{
+		calls[0].Declarations,
+		fmt.Sprintf("\t%[1]s* kadaneObject = new %[1]s(%[2]s);", className, calls[0].Arguments),
+		`	cout << "[null";`,
+	}
+	for _, call := range calls[1:] {
+		if call.Declarations != "" {
+			main = append(main, call.Declarations)
+		}
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("\tkadaneObject->%s(%s);", call.Method.Name, call.Arguments), `	cout << ",null";`)
+			continue
+		}
+		main = append(main, `	cout << ",";`, fmt.Sprintf("\tkadane::write(cout, kadaneObject->%s(%s));", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, `	cout << "]";`), "\n")
+}
+
+// templateCppTest times the statements of a test case in a block of their own and writes the delimiter after
+// its result, formatting the time with printf leaves the stream's float format alone
+func templateCppTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`	{
+	auto kadane_start = chrono::steady_clock::now();
 %s
+	cout << flush;
+	printf("\n%s %d %%.3f\n", chrono::duration<double>(chrono::steady_clock::now() - kadane_start).count());
+	fflush(stdout);
+	}`, main, delimiter, index)
+}
 
+// templateCppProgram places the solution and the statements of main in the harness
+func templateCppProgram(sourceCode string, main string) string {
+	return templateCppPrelude + templateDefinitions(sourceCode, templateCppListNode, templateCppTreeNode) + fmt.Sprintf(`
+// Source Code
+%s
+`, sourceCode) + templateCppStructures + fmt.Sprintf(`
 int main() {
-	auto result = %s(%s);
-	printResult(result);
+	kadane::read_args();
+%s
 	return 0;
 }
-`, sourceCode, functionName, inputs)
+`, main)
 }
 
 func TemplateCpp(templateInput TemplateInput) judge0.Submission {
-	inputs := TemplateCppInputs(templateInput.TestCase)                                               // Get the inputs
-	sourceCode := TemplateCppSourceCode(templateInput.FunctionName, inputs, templateInput.SourceCode) // Get the source code
+	declarations, inputs := TemplateCppInputs(templateInput.TestCase)                                               // Get the inputs
+	sourceCode := TemplateCppSourceCode(templateInput.FunctionName, declarations, inputs, templateInput.SourceCode) // Get the source code
 	languageID := judge0.LanguageToLanguageID("cpp")
 
 	submission := judge0.Submission{
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// templateCsharpTypes are the C# types of the test case types
+var templateCsharpTypes = map[TestCaseType]string{
+	IntType:         "int",
+	FloatType:       "float",
+	DoubleType:      "double",
+	StringType:      "string",
+	BoolType:        "bool",
+	IntArrayType:    "int[]",
+	FloatArrayType:  "float[]",
+	DoubleArrayType: "double[]",
+	StringArrayType: "string[]",
+	BoolArrayType:   "bool[]",
+	"null":          "object",
+
+	LongType:          "long",
+	CharType:          "char",
+	Int2DArrayType:    "int[][]",
+	String2DArrayType: "string[][]",
+	ListNodeType:      "ListNode",
+	TreeNodeType:      "TreeNode",
+	GraphType:         "int[][]",
+	MapType:           "Dictionary<string, int>",
+}
+
+// Convert the test case inputs to the arguments decoded from stdin
+func TemplateCsharpInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templateCsharpArgument)
+	return inputs
+}
+
+// templateCsharpArgument decodes the input at an index of the arguments
+func templateCsharpArgument(index int, input TestCaseInput) (string, []string) {
+	return "", []string{fmt.Sprintf("Kadane.Arg<%s>(%d)", templateCsharpTypes[templateInputType(input.Type)], index)}
+}
+
+// templateCsharpPrelude reads JSON arguments and writes JSON results
+const templateCsharpPrelude = `
+public static class Kadane {
+	static string text;
+	static int pos;
+	static List<object> args;
+
+	static char Peek() {
+		while (pos < text.Length && char.IsWhiteSpace(text[pos])) pos++;
+		return pos < text.Length ? text[pos] : '\0';
+	}
+
+	static void Expect(char c) {
+		if (Peek() != c) throw new FormatException("invalid input at " + pos);
+		pos++;
+	}
+
+	static string ParseString() {
+		Expect('"');
+		var sb = new StringBuilder();
+		while (pos < text.Length && text[pos] != '"') {
+			char c = text[pos++];
+			if (c != '\\') { sb.Append(c); continue; }
+			char e = text[pos++];
+			switch (e) {
+				case 'n': sb.Append('\n'); break;
+				case 't': sb.Append('\t'); break;
+				case 'r': sb.Append('\r'); break;
+				case 'b': sb.Append('\b'); break;
+				case 'f': sb.Append('\f'); break;
+				case 'u': sb.Append((char)Convert.ToInt32(text.Substring(pos, 4), 16)); pos += 4; break;
+				default: sb.Append(e); break;
+			}
+		}
+		Expect('"');
+		return sb.ToString();
+	}
+
+	// Arrays are lists, objects dictionaries and numbers their literal
+	static object ParseValue() {
+		char c = Peek();
+		if (c == '[') {
+			pos++;
+			var items = new List<object>();
+			if (Peek() == ']') { pos++; return items; }
+			while (true) {
+				items.Add(ParseValue());
+				if (Peek() == ',') { pos++; continue; }
+				Expect(']');
+				return items;
+			}
+		}
+		if (c == '{') {
+			pos++;
+			var fields = new Dictionary<string, object>();
+			if (Peek() == '}') { pos++; return fields; }
+			while (true) {
+				string key = ParseString();
+				Expect(':');
+				fields[key] = ParseValue();
+				if (Peek() == ',') { pos++; continue; }
+				Expect('}');
+				return fields;
+			}
+		}
+		if (c == '"') return ParseString();
+		if (string.CompareOrdinal(text, pos, "true", 0, 4) == 0) { pos += 4; return true; }
+		if (string.CompareOrdinal(text, pos, "false", 0, 5) == 0) { pos += 5; return false; }
+		if (string.CompareOrdinal(text, pos, "null", 0, 4) == 0) { pos += 4; return null; }
+		int start = pos;
+		while (pos < text.Length && "+-.eE0123456789".IndexOf(text[pos]) >= 0) pos++;
+		if (start == pos) throw new FormatException("invalid input at " + pos);
+		return new KadaneNumber(text.Substring(start, pos - start));
+	}
+
+	class KadaneNumber {
+		public readonly string Literal;
+		public KadaneNumber(string literal) { Literal = literal; }
+	}
+
+	// ConvertTo turns a parsed value into the type the solution takes
+	static object ConvertTo(object value, Type type) {
+		if (value == null) return null;
+		var number = value as KadaneNumber;
+		if (number != null) {
+			var target = Nullable.GetUnderlyingType(type) ?? type;
+			if (target == typeof(object)) target = typeof(double);
+			long integer;
+			if (target == typeof(long) && long.TryParse(number.Literal, NumberStyles.Integer, CultureInfo.InvariantCulture, out integer)) return integer;
+			return Convert.ChangeType(double.Parse(number.Literal, CultureInfo.InvariantCulture), target, CultureInfo.InvariantCulture);
+		}
+		var list = value as List<object>;
+		if (list != null && type == typeof(ListNode)) return BuildList(list);
+		if (list != null && type == typeof(TreeNode)) return BuildTree(list);
+		var fields = value as Dictionary<string, object>;
+		if (fields != null && type.IsGenericType) {
+			var valueType = type.GetGenericArguments()[1];
+			var dictionary = (IDictionary)Activator.CreateInstance(typeof(Dictionary<,>).MakeGenericType(typeof(string), valueType));
+			foreach (var field in fields) dictionary[field.Key] = ConvertTo(field.Value, valueType);
+			return dictionary;
+		}
+		if (list != null) {
+			if (type.IsArray) {
+				var elementType = type.GetElementType();
+				var array = Array.CreateInstance(elementType, list.Count);
+				for (int i = 0; i < list.Count; i++) array.SetValue(ConvertTo(list[i], elementType), i);
+				return array;
+			}
+			if (type.IsGenericType) {
+				var elementType = type.GetGenericArguments()[0];
+				var collection = (IList)Activator.CreateInstance(typeof(List<>).MakeGenericType(elementType));
+				foreach (var item in list) collection.Add(ConvertTo(item, elementType));
+				return collection;
+			}
+		}
+		if (type == typeof(char) && value is string) return ((string)value)[0];
+		return value;
+	}
+
+	static ListNode BuildList(List<object> values) {
+		ListNode head = null;
+		for (int i = values.Count - 1; i >= 0; i--) head = new ListNode((int)ConvertTo(values[i], typeof(int)), head);
+		return head;
+	}
+
+	// Trees are written in level order, null marks a missing child
+	static TreeNode BuildTree(List<object> values) {
+		if (values.Count == 0 || values[0] == null) return null;
+		var root = new TreeNode((int)ConvertTo(values[0], typeof(int)));
+		var queue = new Queue<TreeNode>();
+		queue.Enqueue(root);
+		for (int i = 1; queue.Count > 0 && i < values.Count; i += 2) {
+			var node = queue.Dequeue();
+			if (values[i] != null) {
+				node.left = new TreeNode((int)ConvertTo(values[i], typeof(int)));
+				queue.Enqueue(node.left);
+			}
+			if (i + 1 < values.Count && values[i + 1] != null) {
+				node.right = new TreeNode((int)ConvertTo(values[i + 1], typeof(int)));
+				queue.Enqueue(node.right);
+			}
+		}
+		return root;
+	}
+
+	static List<object> TreeValues(TreeNode root) {
+		var values = new List<object>();
+		var queue = new Queue<TreeNode>();
+		queue.Enqueue(root);
+		while (queue.Count > 0) {
+			var node = queue.Dequeue();
+			if (node == null) {
+				values.Add(null);
+				continue;
+			}
+			values.Add(node.val);
+			queue.Enqueue(node.left);
+			queue.Enqueue(node.right);
+		}
+		while (values.Count > 0 && values[values.Count - 1] == null) values.RemoveAt(values.Count - 1);
+		return values;
+	}
+
+	public static T Arg<T>(int index) {
+		if (args == null) {
+			text = Console.In.ReadToEnd();
+			pos = 0;
+			args = (List<object>)ParseValue();
+		}
+		return (T)ConvertTo(args[index], typeof(T));
+	}
+
+	static void Quote(StringBuilder sb, string value) {
+		sb.Append('"');
+		foreach (char c in value) {
+			switch (c) {
+				case '"': sb.Append("\\\""); break;
+				case '\\': sb.Append("\\\\"); break;
+				case '\n': sb.Append("\\n"); break;
+				case '\t': sb.Append("\\t"); break;
+				case '\r': sb.Append("\\r"); break;
+				default:
+					if (c < 0x20) sb.Append("\\u").Append(((int)c).ToString("x4"));
+					else sb.Append(c);
+					break;
+			}
+		}
+		sb.Append('"');
+	}
+
+	// Exponents of round trip formats are written out, 1E+20 is printed as 100000000000000000000 like the other
+	// languages do
+	static string Plain(string text) {
+		int exponent = text.IndexOf('E');
+		if (exponent < 0) return text;
+		string sign = text[0] == '-' ? "-" : "";
+		string mantissa = text.Substring(sign.Length, exponent - sign.Length);
+		int dot = mantissa.IndexOf('.');
+		string digits = mantissa.Replace(".", "");
+		int point = (dot < 0 ? mantissa.Length : dot) + int.Parse(text.Substring(exponent + 1), CultureInfo.InvariantCulture);
+		if (point <= 0) return sign + "0." + new string('0', -point) + digits;
+		if (point >= digits.Length) return sign + digits + new string('0', point - digits.Length);
+		return sign + digits.Substring(0, point) + "." + digits.Substring(point);
+	}
+
+	static void Write(StringBuilder sb, object value) {
+		if (value == null) { sb.Append("null"); return; }
+		if (value is bool) { sb.Append((bool)value ? "true" : "false"); return; }
+		if (value is string) { Quote(sb, (string)value); return; }
+		if (value is char) { Quote(sb, value.ToString()); return; }
+		if (value is ListNode) {
+			var values = new List<object>();
+			for (var node = (ListNode)value; node != null; node = node.next) values.Add(node.val);
+			Write(sb, values);
+			return;
+		}
+		if (value is TreeNode) { Write(sb, TreeValues((TreeNode)value)); return; }
+		if (value is float) { sb.Append(Plain(((float)value).ToString("R", CultureInfo.InvariantCulture))); return; }
+		if (value is double) { sb.Append(Plain(((double)value).ToString("R", CultureInfo.InvariantCulture))); return; }
+		if (value is IDictionary) {
+			// Fields are written in the order of their keys like Go's encoding/json does, whatever the order of the map
+			var fields = new List<KeyValuePair<string, object>>();
+			foreach (DictionaryEntry entry in (IDictionary)value) {
+				fields.Add(new KeyValuePair<string, object>(Convert.ToString(entry.Key, CultureInfo.InvariantCulture), entry.Value));
+			}
+			fields.Sort((a, b) => string.CompareOrdinal(a.Key, b.Key));
+			sb.Append('{');
+			for (int i = 0; i < fields.Count; i++) {
+				if (i > 0) sb.Append(',');
+				Quote(sb, fields[i].Key);
+				sb.Append(':');
+				Write(sb, fields[i].Value);
+			}
+			sb.Append('}');
+			return;
+		}
+		if (value is IEnumerable) {
+			sb.Append('[');
+			bool first = true;
+			foreach (var item in (IEnumerable)value) {
+				if (!first) sb.Append(',');
+				first = false;
+				Write(sb, item);
+			}
+			sb.Append(']');
+			return;
+		}
+		var type = value.GetType();
+		if (type.IsGenericType && type.GetGenericTypeDefinition().FullName.StartsWith("System.ValueTuple")) {
+			Write(sb, type.GetFields().Select(f => f.GetValue(value)).ToList());
+			return;
+		}
+		if (value is IFormattable) { sb.Append(((IFormattable)value).ToString(null, CultureInfo.InvariantCulture)); return; }
+		Quote(sb, value.ToString());
+	}
+
+	public static string ToJson(object value) {
+		var sb = new StringBuilder();
+		Write(sb, value);
+		return sb.ToString();
+	}
+}
+`
+
+// templateCsharpListNode and templateCsharpTreeNode are leetcode's definitions, they follow the solution's usings
+const templateCsharpListNode = `
+public class ListNode {
+	public int val;
+	public ListNode next;
+	public ListNode(int val = 0, ListNode next = null) {
+		this.val = val;
+		this.next = next;
+	}
+}
+`
+
+const templateCsharpTreeNode = `
+public class TreeNode {
+	public int val;
+	public TreeNode left;
+	public TreeNode right;
+	public TreeNode(int val = 0, TreeNode left = null, TreeNode right = null) {
+		this.val = val;
+		this.left = left;
+		this.right = right;
+	}
+}
+`
+
+// templateCsharpStub is leetcode's C# starter code
+func templateCsharpStub(functionName string, signature ProblemSignature) string {
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return templateCsharpTypes[parameter.Type] + " " + parameter.Name
+	})
+	return fmt.Sprintf("public class Solution {\n    public %s %s(%s) {\n        \n    }\n}", templateCsharpTypes[signature.ReturnType], functionName, parameters)
+}
+
+// C# template
+func TemplateCsharpSourceCode(functionName string, inputs string, sourceCode string) string {
+	return templateCsharpProgram(sourceCode, templateCsharpMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, "", inputs))
+}
+
+// templateCsharpMain calls the solution and prints its result
+func templateCsharpMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Solutions written as a class (e.g. leetcode's Solution) are called on it,
+	// bare methods are placed in the Program class like in the java template
+	className := templateClassName(templateInput.SourceCode)
+	if className == "" {
+		className = "Program"
+	}
+
+	receiver := "new " + className + "()"
+	if regexp.MustCompile(`\bstatic\b[^;{(]*\b` + regexp.QuoteMeta(templateInput.FunctionName) + `\s*\(`).MatchString(templateInput.SourceCode) {
+		receiver = className
+	}
+
+	return fmt.Sprintf("Console.Write(Kadane.ToJson(%s.%s(%s)));", receiver, templateInput.FunctionName, inputs)
+}
+
+// templateCsharpDesign constructs the class and prints the results of its methods, which are
+// capitalized like on leetcode
+func templateCsharpDesign(className string, calls []templateDesignCall) string {
+	main := []string{
+		fmt.Sprintf("var kadaneObject = new %s(%s);", className, calls[0].Arguments),
+		"var kadaneResult = new List<object> { null };",
+	}
+	for _, call := range calls[1:] {
+		method := strings.ToUpper(call.Method.Name[:1]) + call.Method.Name[1:]
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("kadaneObject.%s(%s);", method, call.Arguments), "kadaneResult.Add(null);")
+			continue
+		}
+		main = append(main, fmt.Sprintf("kadaneResult.Add(kadaneObject.%s(%s));", method, call.Arguments))
+	}
+	return strings.Join(append(main, "Console.Write(Kadane.ToJson(kadaneResult));"), "\n\t\t")
+}
+
+// templateCsharpTest times the statements of a test case in a block of their own and prints the delimiter after its result
+func templateCsharpTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`{
+		var kadaneStart = System.Diagnostics.Stopwatch.StartNew();
+		%s
+		Console.Write("\n%s %d " + kadaneStart.Elapsed.TotalSeconds.ToString("F3", CultureInfo.InvariantCulture) + "\n");
+		}`, main, delimiter, index)
+}
+
+// templateCsharpProgram places the solution and the statements of main in the harness
+func templateCsharpProgram(sourceCode string, main string) string {
+	solutionCode, programCode := sourceCode, ""
+	if templateClassName(sourceCode) == "" {
+		solutionCode, programCode = "", sourceCode
+	}
+
+	return fmt.Sprintf(`
+using System;
+using System.Collections;
+using System.Collections.Generic;
+using System.Globalization;
+using System.Linq;
+using System.Text;
+
+// Source Code
+%s
+%s`, solutionCode, templateDefinitions(sourceCode, templateCsharpListNode, templateCsharpTreeNode)) + templateCsharpPrelude + fmt.Sprintf(`
+public class Program {
+	%s
+
+	public static void Main(string[] args) {
+		Console.OutputEncoding = new UTF8Encoding(false);
+		%s
+	}
+}
+`, programCode, main)
+}
+
+// TemplateCsharp creates a judge0.Submission for C#
+func TemplateCsharp(templateInput TemplateInput) judge0.Submission {
+	inputs := TemplateCsharpInputs(templateInput.TestCase)                                               // Get the inputs
+	sourceCode := TemplateCsharpSourceCode(templateInput.FunctionName, inputs, templateInput.SourceCode) // Get the source code
+
+	submission := judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID("csharp"),
+		SourceCode: sourceCode,
+		//ExpectedOutput: templateInput.ExpectedOutput,
+	}
+
+	return submission
+}
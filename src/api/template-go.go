@@ -7,69 +7,283 @@ import (
 	"kadane.xyz/go-backend/v2/src/judge0"
 )
 
-// Convert the test case inputs to a comma separated string
-func TemplateGoInputs(testCases TestCase) string {
-	var inputs []string
-
-	for _, input := range testCases.Input {
-		inputValue := strings.Trim(input.Value, "[]")
-		switch input.Type {
-		case IntType:
-			inputs = append(inputs, inputValue)
-		case "float":
-			inputs = append(inputs, fmt.Sprintf("float32(%s)", inputValue))
-		case "double":
-			inputs = append(inputs, fmt.Sprintf("float64(%s)", inputValue))
-		case "int[]":
-			inputs = append(inputs, fmt.Sprintf("[]int{%s}", inputValue))
-		case "float[]":
-			inputs = append(inputs, fmt.Sprintf("[]float32{%s}", inputValue))
-		case "double[]":
-			inputs = append(inputs, fmt.Sprintf("[]float64{%s}", inputValue))
-		case "string[]":
-			inputs = append(inputs, fmt.Sprintf("[]string{%s}", inputValue))
-		case "bool[]":
-			inputs = append(inputs, fmt.Sprintf("[]bool{%s}", inputValue))
+// templateGoTypes are the go types of the test case types
+var templateGoTypes = map[TestCaseType]string{
+	IntType:         "int",
+	FloatType:       "float32",
+	DoubleType:      "float64",
+	StringType:      "string",
+	BoolType:        "bool",
+	IntArrayType:    "[]int",
+	FloatArrayType:  "[]float32",
+	DoubleArrayType: "[]float64",
+	StringArrayType: "[]string",
+	BoolArrayType:   "[]bool",
+	"null":          "interface{}",
+
+	LongType:          "int64",
+	CharType:          "byte",
+	Int2DArrayType:    "[][]int",
+	String2DArrayType: "[][]string",
+	ListNodeType:      "*ListNode",
+	TreeNodeType:      "*TreeNode",
+	GraphType:         "[][]int",
+	MapType:           "map[string]int",
+}
+
+// Convert the test case inputs to variables decoded from stdin, returns their declarations and the arguments
+func TemplateGoInputs(testCase TestCase) (string, string) {
+	return templateInputs(testCase.Input, 0, templateGoArgument)
+}
+
+// templateGoArgument declares a variable decoded from the input at an index of the arguments
+func templateGoArgument(index int, input TestCaseInput) (string, []string) {
+	name := fmt.Sprintf("kadaneArg%d", index)
+	switch templateInputType(input.Type) {
+	case ListNodeType:
+		return fmt.Sprintf("\t%s := kadaneList(%d)", name, index), []string{name}
+	case TreeNodeType:
+		return fmt.Sprintf("\t%s := kadaneTree(%d)", name, index), []string{name}
+	case CharType:
+		return fmt.Sprintf("\t%s := kadaneChar(%d)", name, index), []string{name}
+	}
+	return fmt.Sprintf("\tvar %s %s\n\tkadaneArg(%d, &%s)", name, templateGoTypes[templateInputType(input.Type)], index, name), []string{name}
+}
+
+// templateGoListNode and templateGoTreeNode are leetcode's definitions, they follow the solution's imports
+const templateGoListNode = `
+type ListNode struct {
+	Val  int
+	Next *ListNode
+}
+`
+
+const templateGoTreeNode = `
+type TreeNode struct {
+	Val   int
+	Left  *TreeNode
+	Right *TreeNode
+}
+`
+
+// templateGoStub is leetcode's go starter code
+func templateGoStub(functionName string, signature ProblemSignature) string {
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return parameter.Name + " " + templateGoTypes[parameter.Type]
+	})
+	return fmt.Sprintf("func %s(%s) %s {\n    \n}", functionName, parameters, templateGoTypes[signature.ReturnType])
+}
+
+// Golang template. Judge0 runs go 1.13, so the harness sticks to it and imports under names solutions won't use.
+func TemplateGoSourceCode(functionName string, declarations string, inputs string, sourceCode string) string {
+	return templateGoProgram(sourceCode, templateGoMain(TemplateInput{FunctionName: functionName}, declarations, inputs))
+}
+
+// templateGoMain declares the arguments, calls the solution and prints its result
+func templateGoMain(templateInput TemplateInput, declarations string, inputs string) string {
+	return fmt.Sprintf("%s\n\tkadanePrint(%s(%s))", declarations, templateInput.FunctionName, inputs)
+}
+
+// templateGoDesign constructs the class and prints the results of its methods. Like on leetcode the
+// constructor is named Constructor and the methods are exported.
+func templateGoDesign(className string, calls []templateDesignCall) string {
+	main := []string{
+		calls[0].Declarations,
+		fmt.Sprintf("\tkadaneObject := Constructor(%s)", calls[0].Arguments),
+		"\tkadaneResult := []interface{}{nil}",
+	}
+	if len(calls) == 1 {
+		main = append(main, "\t_ = kadaneObject")
+	}
+	for _, call := range calls[1:] {
+		if call.Declarations != "" {
+			main = append(main, call.Declarations)
 		}
+		method := strings.ToUpper(call.Method.Name[:1]) + call.Method.Name[1:]
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("\tkadaneObject.%s(%s)", method, call.Arguments), "\tkadaneResult = append(kadaneResult, nil)")
+			continue
+		}
+		main = append(main, fmt.Sprintf("\tkadaneResult = append(kadaneResult, kadaneObject.%s(%s))", method, call.Arguments))
 	}
-	return strings.Join(inputs, ",")
+	return strings.Join(append(main, "\tkadanePrint(kadaneResult)"), "\n")
+}
+
+// templateGoTest times the statements of a test case in a block of their own and prints the delimiter after its result
+func templateGoTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf("\t{\n\tkadaneStart := kadaneTime.Now()\n%s\n\tkadaneTest(\"%s %d\", kadaneStart)\n\t}", main, delimiter, index)
 }
 
-// Golang template
-func TemplateGoSourceCode(functionName string, inputs string, sourceCode string) string {
+// templateGoProgram places the solution and the statements of main in the harness
+func templateGoProgram(sourceCode string, main string) string {
 	return fmt.Sprintf(`
 package main
 
 import (
-	"fmt"
-	"strings"
-	"reflect"
+	kadaneJSON "encoding/json"
+	kadaneFmt "fmt"
+	kadaneIoutil "io/ioutil"
+	kadaneOs "os"
+	kadaneReflect "reflect"
+	kadaneTime "time"
 )
 
 // Source Code
 %s
+%s
+// kadaneArgs are the JSON arguments read from stdin
+var kadaneArgs []kadaneJSON.RawMessage
+
+// kadaneArg decodes an argument into the value pointed to
+func kadaneArg(index int, value interface{}) {
+	if err := kadaneJSON.Unmarshal(kadaneArgs[index], value); err != nil {
+		kadaneFmt.Fprintln(kadaneOs.Stderr, "invalid argument", index, err)
+		kadaneOs.Exit(1)
+	}
+}
+
+// kadaneList builds a linked list from an array argument
+func kadaneList(index int) *ListNode {
+	var values []int
+	kadaneArg(index, &values)
+	var head *ListNode
+	for i := len(values) - 1; i >= 0; i-- {
+		head = &ListNode{Val: values[i], Next: head}
+	}
+	return head
+}
+
+// kadaneTree builds a binary tree from its level order, null marks a missing child
+func kadaneTree(index int) *TreeNode {
+	var values []*int
+	kadaneArg(index, &values)
+	if len(values) == 0 {
+		return nil
+	}
+	root := &TreeNode{Val: *values[0]}
+	queue := []*TreeNode{root}
+	for i := 1; len(queue) > 0 && i < len(values); i += 2 {
+		node := queue[0]
+		queue = queue[1:]
+		if values[i] != nil {
+			node.Left = &TreeNode{Val: *values[i]}
+			queue = append(queue, node.Left)
+		}
+		if i+1 < len(values) && values[i+1] != nil {
+			node.Right = &TreeNode{Val: *values[i+1]}
+			queue = append(queue, node.Right)
+		}
+	}
+	return root
+}
+
+// kadaneChar reads a character argument, go solutions take characters as bytes
+func kadaneChar(index int) byte {
+	var value string
+	kadaneArg(index, &value)
+	return value[0]
+}
 
-func formatOutput(output interface{}) string {
-	val := reflect.ValueOf(output)
-	if val.Kind() == reflect.Slice {
-		var parts []string
-		for i := 0; i < val.Len(); i++ {
-			parts = append(parts, fmt.Sprint(val.Index(i).Interface()))
+// kadaneStructure converts linked lists and trees to their arrays, empty ones are null like in other languages
+func kadaneStructure(value interface{}) (interface{}, bool) {
+	switch node := value.(type) {
+	case *ListNode:
+		if node == nil {
+			return nil, true
+		}
+		values := []interface{}{}
+		for ; node != nil; node = node.Next {
+			values = append(values, node.Val)
+		}
+		return values, true
+	case *TreeNode:
+		if node == nil {
+			return nil, true
+		}
+		values := []interface{}{}
+		queue := []*TreeNode{node}
+		for len(queue) > 0 {
+			if queue[0] == nil {
+				values = append(values, nil)
+			} else {
+				values = append(values, queue[0].Val)
+				queue = append(queue, queue[0].Left, queue[0].Right)
+			}
+			queue = queue[1:]
 		}
-		return "[" + strings.Join(parts, ",") + "]"
+		for len(values) > 0 && values[len(values)-1] == nil {
+			values = values[:len(values)-1]
+		}
+		return values, true
+	case byte:
+		return string(node), true
 	}
-	return fmt.Sprint(output)
+	return nil, false
+}
+
+// kadaneValue converts a result to values encoding/json prints the way every other language does
+func kadaneValue(value kadaneReflect.Value) interface{} {
+	if value.IsValid() && value.CanInterface() {
+		if structure, ok := kadaneStructure(value.Interface()); ok {
+			return structure
+		}
+	}
+	switch value.Kind() {
+	case kadaneReflect.Invalid:
+		return nil
+	case kadaneReflect.Ptr, kadaneReflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return kadaneValue(value.Elem())
+	case kadaneReflect.Slice, kadaneReflect.Array:
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = kadaneValue(value.Index(i))
+		}
+		return items
+	case kadaneReflect.Map:
+		fields := make(map[string]interface{}, value.Len())
+		for _, key := range value.MapKeys() {
+			fields[kadaneFmt.Sprint(key.Interface())] = kadaneValue(value.MapIndex(key))
+		}
+		return fields
+	}
+	return value.Interface()
+}
+
+func kadanePrint(result interface{}) {
+	encoder := kadaneJSON.NewEncoder(kadaneOs.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(kadaneValue(kadaneReflect.ValueOf(result))); err != nil {
+		kadaneFmt.Fprintln(kadaneOs.Stderr, "cannot print result", err)
+		kadaneOs.Exit(1)
+	}
+}
+
+// kadaneTest prints the delimiter following the result of a test case with the time it took
+func kadaneTest(delimiter string, start kadaneTime.Time) {
+	kadaneFmt.Printf("\n%%s %%.3f\n", delimiter, kadaneTime.Since(start).Seconds())
 }
 
 func main() {
-	response := %s(%s)
-	fmt.Print(formatOutput(response))
-}`, sourceCode, functionName, inputs)
+	input, err := kadaneIoutil.ReadAll(kadaneOs.Stdin)
+	if err == nil {
+		err = kadaneJSON.Unmarshal(input, &kadaneArgs)
+	}
+	if err != nil {
+		kadaneFmt.Fprintln(kadaneOs.Stderr, "invalid input", err)
+		kadaneOs.Exit(1)
+	}
+
+%s
+}
+`, sourceCode, templateDefinitions(sourceCode, templateGoListNode, templateGoTreeNode), main)
 }
 
 func TemplateGo(templateInput TemplateInput) judge0.Submission {
-	inputs := TemplateGoInputs(templateInput.TestCase)                                               // Get the inputs
-	sourceCode := TemplateGoSourceCode(templateInput.FunctionName, inputs, templateInput.SourceCode) // Get the source code
+	declarations, inputs := TemplateGoInputs(templateInput.TestCase)                                               // Get the inputs
+	sourceCode := TemplateGoSourceCode(templateInput.FunctionName, declarations, inputs, templateInput.SourceCode) // Get the source code
 
 	submission := judge0.Submission{
 		LanguageID: judge0.LanguageToLanguageID("go"),
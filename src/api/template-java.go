@@ -2,118 +2,421 @@ package api
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"kadane.xyz/go-backend/v2/src/judge0"
 )
 
-// Convert the test case inputs to a comma separated string
+// templateJavaTypes are the java types of the test case types
+var templateJavaTypes = map[TestCaseType]string{
+	IntType:         "int",
+	FloatType:       "float",
+	DoubleType:      "double",
+	StringType:      "String",
+	BoolType:        "boolean",
+	IntArrayType:    "int[]",
+	FloatArrayType:  "float[]",
+	DoubleArrayType: "double[]",
+	StringArrayType: "String[]",
+	BoolArrayType:   "boolean[]",
+	"null":          "Object",
+
+	LongType:          "long",
+	CharType:          "char",
+	Int2DArrayType:    "int[][]",
+	String2DArrayType: "String[][]",
+	ListNodeType:      "ListNode",
+	TreeNodeType:      "TreeNode",
+	GraphType:         "int[][]",
+	MapType:           "Map<String, Integer>",
+}
+
+// Convert the test case inputs to the arguments decoded from stdin
 func TemplateJavaInputs(testCases TestCase) string {
-	var inputs []string
-
-	for _, input := range testCases.Input {
-		inputValue := strings.Trim(input.Value, "[]")
-		switch input.Type {
-		case IntType:
-			inputs = append(inputs, inputValue)
-		case "float":
-			inputs = append(inputs, fmt.Sprintf("%sf", inputValue)) // Java float literal
-		case "double":
-			inputs = append(inputs, fmt.Sprintf("%sd", inputValue)) // Java double literal
-		case "int[]":
-			inputs = append(inputs, fmt.Sprintf("new int[]{%s}", inputValue))
-		case "float[]":
-			inputs = append(inputs, fmt.Sprintf("new float[]{%s}", inputValue))
-		case "double[]":
-			inputs = append(inputs, fmt.Sprintf("new double[]{%s}", inputValue))
-		case "string[]":
-			inputs = append(inputs, fmt.Sprintf("new String[]{%s}", inputValue))
-		case "boolean[]":
-			inputs = append(inputs, fmt.Sprintf("new boolean[]{%s}", inputValue))
-		}
+	_, inputs := templateInputs(testCases.Input, 0, templateJavaArgument)
+	return inputs
+}
+
+// templateJavaArgument decodes the input at an index of the arguments
+func templateJavaArgument(index int, input TestCaseInput) (string, []string) {
+	// Maps are generic, a class literal would make an unchecked conversion
+	if templateInputType(input.Type) == MapType {
+		return "", []string{fmt.Sprintf("Kadane.map(%d, Integer.class)", index)}
 	}
-	return strings.Join(inputs, ",")
+	return "", []string{fmt.Sprintf("Kadane.arg(%d, %s.class)", index, templateJavaTypes[templateInputType(input.Type)])}
 }
 
-// Java template (modified)
-func TemplateJavaSourceCode(functionName string, inputs string, sourceCode string) string {
-	// If the source code already contains a print statement, just call the method.
-	containsPrint := strings.Contains(sourceCode, "System.out.print")
+// templateJavaPrelude reads JSON arguments and writes JSON results
+const templateJavaPrelude = `
+class Kadane {
+	private static String text;
+	private static int pos;
+	private static List<Object> args;
 
-	// If it's a void method, just call the function (its own prints if needed).
-	if strings.Contains(sourceCode, "void") {
-		return fmt.Sprintf(`
-import java.util.*;
-public class Main {
+	// Numbers keep their literal until the solution's parameter type is known
+	private static final class Number {
+		final String literal;
+		Number(String literal) { this.literal = literal; }
+	}
 
-	// Source Code
-	%s
+	private static char peek() {
+		while (pos < text.length() && Character.isWhitespace(text.charAt(pos))) pos++;
+		return pos < text.length() ? text.charAt(pos) : '\0';
+	}
 
-	public static void main(String[] args) {
-		Main main = new Main();
-		main.%s(%s);
+	private static void expect(char c) {
+		if (peek() != c) throw new IllegalArgumentException("invalid input at " + pos);
+		pos++;
 	}
-}`, sourceCode, functionName, inputs)
+
+	private static String parseString() {
+		expect('"');
+		StringBuilder sb = new StringBuilder();
+		while (pos < text.length() && text.charAt(pos) != '"') {
+			char c = text.charAt(pos++);
+			if (c != '\\') { sb.append(c); continue; }
+			char e = text.charAt(pos++);
+			switch (e) {
+				case 'n': sb.append('\n'); break;
+				case 't': sb.append('\t'); break;
+				case 'r': sb.append('\r'); break;
+				case 'b': sb.append('\b'); break;
+				case 'f': sb.append('\f'); break;
+				case 'u': sb.append((char) Integer.parseInt(text.substring(pos, pos + 4), 16)); pos += 4; break;
+				default: sb.append(e);
+			}
+		}
+		expect('"');
+		return sb.toString();
 	}
 
-	// If the code already prints output, no extra formatting is needed.
-	if containsPrint {
-		return fmt.Sprintf(`
-import java.util.*;
-public class Main {
+	private static Object parseValue() {
+		char c = peek();
+		if (c == '[') {
+			pos++;
+			List<Object> items = new ArrayList<>();
+			if (peek() == ']') { pos++; return items; }
+			while (true) {
+				items.add(parseValue());
+				if (peek() == ',') { pos++; continue; }
+				expect(']');
+				return items;
+			}
+		}
+		if (c == '{') {
+			pos++;
+			Map<String, Object> fields = new LinkedHashMap<>();
+			if (peek() == '}') { pos++; return fields; }
+			while (true) {
+				String key = parseString();
+				expect(':');
+				fields.put(key, parseValue());
+				if (peek() == ',') { pos++; continue; }
+				expect('}');
+				return fields;
+			}
+		}
+		if (c == '"') return parseString();
+		if (text.startsWith("true", pos)) { pos += 4; return true; }
+		if (text.startsWith("false", pos)) { pos += 5; return false; }
+		if (text.startsWith("null", pos)) { pos += 4; return null; }
+		int start = pos;
+		while (pos < text.length() && "+-.eE0123456789".indexOf(text.charAt(pos)) >= 0) pos++;
+		if (start == pos) throw new IllegalArgumentException("invalid input at " + pos);
+		return new Number(text.substring(start, pos));
+	}
 
-	// Source Code
-	%s
+	// convert turns a parsed value into the type the solution takes
+	private static Object convert(Object value, Class<?> type) {
+		if (value instanceof Number) {
+			String literal = ((Number) value).literal;
+			if (type == int.class || type == Integer.class) return (int) Double.parseDouble(literal);
+			if (type == long.class || type == Long.class) return literal.matches("-?\\d+") ? Long.parseLong(literal) : (long) Double.parseDouble(literal);
+			if (type == float.class || type == Float.class) return Float.parseFloat(literal);
+			return Double.parseDouble(literal);
+		}
+		if (value instanceof List && type.isArray()) {
+			List<?> list = (List<?>) value;
+			Object array = java.lang.reflect.Array.newInstance(type.getComponentType(), list.size());
+			for (int i = 0; i < list.size(); i++) {
+				java.lang.reflect.Array.set(array, i, convert(list.get(i), type.getComponentType()));
+			}
+			return array;
+		}
+		if (value instanceof String && (type == char.class || type == Character.class)) return ((String) value).charAt(0);
+		if (value instanceof List && type == ListNode.class) {
+			ListNode head = null;
+			List<?> list = (List<?>) value;
+			for (int i = list.size() - 1; i >= 0; i--) head = new ListNode((Integer) convert(list.get(i), int.class), head);
+			return head;
+		}
+		if (value instanceof List && type == TreeNode.class) return tree((List<?>) value);
+		return value;
+	}
 
-	public static void main(String[] args) {
-		Main main = new Main();
-		main.%s(%s);
+	// Trees are written in level order, null marks a missing child
+	private static TreeNode tree(List<?> values) {
+		if (values.isEmpty() || values.get(0) == null) return null;
+		TreeNode root = new TreeNode((Integer) convert(values.get(0), int.class));
+		Deque<TreeNode> queue = new ArrayDeque<>();
+		queue.add(root);
+		for (int i = 1; !queue.isEmpty() && i < values.size(); i += 2) {
+			TreeNode node = queue.poll();
+			if (values.get(i) != null) {
+				node.left = new TreeNode((Integer) convert(values.get(i), int.class));
+				queue.add(node.left);
+			}
+			if (i + 1 < values.size() && values.get(i + 1) != null) {
+				node.right = new TreeNode((Integer) convert(values.get(i + 1), int.class));
+				queue.add(node.right);
+			}
+		}
+		return root;
+	}
+
+	private static List<Object> treeValues(TreeNode root) {
+		List<Object> values = new ArrayList<>();
+		Deque<TreeNode> queue = new LinkedList<>();
+		queue.add(root);
+		while (!queue.isEmpty()) {
+			TreeNode node = queue.poll();
+			values.add(node == null ? null : node.val);
+			if (node != null) {
+				queue.add(node.left);
+				queue.add(node.right);
+			}
+		}
+		while (!values.isEmpty() && values.get(values.size() - 1) == null) values.remove(values.size() - 1);
+		return values;
+	}
+
+	@SuppressWarnings("unchecked")
+	static <T> T arg(int index, Class<T> type) {
+		if (args == null) {
+			Scanner scanner = new Scanner(System.in, "UTF-8").useDelimiter("\\A");
+			text = scanner.hasNext() ? scanner.next() : "";
+			pos = 0;
+			args = (List<Object>) parseValue();
+		}
+		return (T) convert(args.get(index), type);
+	}
+
+	@SuppressWarnings("unchecked")
+	static <V> Map<String, V> map(int index, Class<V> type) {
+		Map<String, V> values = new HashMap<>();
+		for (Map.Entry<String, Object> field : ((Map<String, Object>) arg(index, Map.class)).entrySet()) {
+			values.put(field.getKey(), (V) convert(field.getValue(), type));
+		}
+		return values;
+	}
+
+	private static void quote(StringBuilder sb, String value) {
+		sb.append('"');
+		for (int i = 0; i < value.length(); i++) {
+			char c = value.charAt(i);
+			switch (c) {
+				case '"': sb.append("\\\""); break;
+				case '\\': sb.append("\\\\"); break;
+				case '\n': sb.append("\\n"); break;
+				case '\t': sb.append("\\t"); break;
+				case '\r': sb.append("\\r"); break;
+				default:
+					if (c < 0x20) sb.append(String.format("\\u%04x", (int) c));
+					else sb.append(c);
+			}
+		}
+		sb.append('"');
+	}
+
+	// plain writes floats without an exponent and integral ones without a fraction like the other languages do
+	private static String plain(String text) {
+		try {
+			return new java.math.BigDecimal(text).stripTrailingZeros().toPlainString();
+		} catch (NumberFormatException e) {
+			return text;
+		}
+	}
+
+	private static void write(StringBuilder sb, Object value) {
+		if (value == null) {
+			sb.append("null");
+		} else if (value instanceof String || value instanceof Character) {
+			quote(sb, value.toString());
+		} else if (value instanceof ListNode) {
+			List<Object> values = new ArrayList<>();
+			for (ListNode node = (ListNode) value; node != null; node = node.next) values.add(node.val);
+			write(sb, values);
+		} else if (value instanceof TreeNode) {
+			write(sb, treeValues((TreeNode) value));
+		} else if (value.getClass().isArray()) {
+			sb.append('[');
+			for (int i = 0; i < java.lang.reflect.Array.getLength(value); i++) {
+				if (i > 0) sb.append(',');
+				write(sb, java.lang.reflect.Array.get(value, i));
+			}
+			sb.append(']');
+		} else if (value instanceof Iterable) {
+			sb.append('[');
+			boolean first = true;
+			for (Object item : (Iterable<?>) value) {
+				if (!first) sb.append(',');
+				first = false;
+				write(sb, item);
+			}
+			sb.append(']');
+		} else if (value instanceof Map) {
+			// Fields are written in the order of their keys like Go's encoding/json does, whatever the order of the map
+			Map<String, Object> fields = new TreeMap<>();
+			for (Map.Entry<?, ?> entry : ((Map<?, ?>) value).entrySet()) {
+				fields.put(String.valueOf(entry.getKey()), entry.getValue());
+			}
+			sb.append('{');
+			boolean first = true;
+			for (Map.Entry<String, Object> field : fields.entrySet()) {
+				if (!first) sb.append(',');
+				first = false;
+				quote(sb, field.getKey());
+				sb.append(':');
+				write(sb, field.getValue());
+			}
+			sb.append('}');
+		} else if (value instanceof Double || value instanceof Float) {
+			sb.append(plain(value.toString()));
+		} else if (value instanceof java.lang.Number || value instanceof Boolean) {
+			sb.append(value);
+		} else {
+			quote(sb, value.toString());
+		}
+	}
+
+	static void print(Object value) {
+		StringBuilder sb = new StringBuilder();
+		write(sb, value);
+		output(sb);
+	}
+
+	// test prints the delimiter following the result of a test case with the time it took
+	static void test(String delimiter, long start) {
+		output(String.format(Locale.ROOT, "\n%s %.3f\n", delimiter, (System.nanoTime() - start) / 1e9));
+	}
+
+	// output writes UTF-8 after what the solution printed
+	private static void output(CharSequence sb) {
+		System.out.flush();
+		try {
+			java.io.PrintStream out = new java.io.PrintStream(new java.io.FileOutputStream(java.io.FileDescriptor.out), true, "UTF-8");
+			out.print(sb);
+			out.flush();
+		} catch (java.io.UnsupportedEncodingException e) {
+			System.out.print(sb);
+		}
+	}
+}
+`
+
+// templateJavaListNode and templateJavaTreeNode are leetcode's definitions, they follow the solution's imports
+const templateJavaListNode = `
+class ListNode {
+	int val;
+	ListNode next;
+	ListNode() {}
+	ListNode(int val) { this.val = val; }
+	ListNode(int val, ListNode next) { this.val = val; this.next = next; }
+}
+`
+
+const templateJavaTreeNode = `
+class TreeNode {
+	int val;
+	TreeNode left;
+	TreeNode right;
+	TreeNode() {}
+	TreeNode(int val) { this.val = val; }
+	TreeNode(int val, TreeNode left, TreeNode right) {
+		this.val = val;
+		this.left = left;
+		this.right = right;
+	}
+}
+`
+
+// templateJavaStub is leetcode's java starter code
+func templateJavaStub(functionName string, signature ProblemSignature) string {
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return templateJavaTypes[parameter.Type] + " " + parameter.Name
+	})
+	return fmt.Sprintf("class Solution {\n    public %s %s(%s) {\n        \n    }\n}", templateJavaTypes[signature.ReturnType], functionName, parameters)
+}
+
+// Java template
+func TemplateJavaSourceCode(functionName string, inputs string, sourceCode string) string {
+	return templateJavaProgram(sourceCode, templateJavaMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, "", inputs))
+}
+
+// templateJavaMain calls the solution and prints its result
+func templateJavaMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Solutions written as a class (e.g. leetcode's Solution) are called on an instance of it,
+	// bare methods are placed in the Main class
+	className := templateClassName(templateInput.SourceCode)
+	if className == "" {
+		className = "Main"
+	}
+
+	// void methods print their own output, there is no result to print
+	call := fmt.Sprintf("new %s().%s(%s)", className, templateInput.FunctionName, inputs)
+	if !regexp.MustCompile(`\bvoid\s+` + regexp.QuoteMeta(templateInput.FunctionName) + `\s*\(`).MatchString(templateInput.SourceCode) {
+		call = fmt.Sprintf("Kadane.print(%s)", call)
+	}
+	return call + ";"
+}
+
+// templateJavaDesign constructs the class and prints the results of its methods
+func templateJavaDesign(className string, calls []templateDesignCall) string {
+	main := []string{
+		fmt.Sprintf("%[1]s kadaneObject = new %[1]s(%[2]s);", className, calls[0].Arguments),
+		"List<Object> kadaneResult = new ArrayList<>();",
+		"kadaneResult.add(null);",
 	}
-}`, sourceCode, functionName, inputs)
+	for _, call := range calls[1:] {
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("kadaneObject.%s(%s);", call.Method.Name, call.Arguments), "kadaneResult.add(null);")
+			continue
+		}
+		main = append(main, fmt.Sprintf("kadaneResult.add(kadaneObject.%s(%s));", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, "Kadane.print(kadaneResult);"), "\n\t\t")
+}
+
+// templateJavaTest times the statements of a test case in a block of their own and prints the delimiter after its result
+func templateJavaTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`{
+		long kadaneStart = System.nanoTime();
+		%s
+		Kadane.test("%s %d", kadaneStart);
+		}`, main, delimiter, index)
+}
+
+// templateJavaProgram places the solution and the statements of main in the harness
+func templateJavaProgram(sourceCode string, main string) string {
+	solutionCode, mainCode := sourceCode, ""
+	if templateClassName(sourceCode) == "" {
+		solutionCode, mainCode = "", sourceCode
 	}
 
-	// Otherwise, capture the returned output and convert it to a properly formatted string.
-	// For a two-element int array, create a sorted copy without breaking the generic behavior.
 	return fmt.Sprintf(`
 import java.util.*;
-public class Main {
 
-	// Source Code
+// Source Code
+%s
+%s`, solutionCode, templateDefinitions(sourceCode, templateJavaListNode, templateJavaTreeNode)) + templateJavaPrelude + fmt.Sprintf(`
+public class Main {
 	%s
 
-	public static String formatOutput(Object output) {
-	    if (output != null && output.getClass().isArray()) {
-	        // If it's a two-element int[] array, return a sorted copy.
-	        if (output instanceof int[] && ((int[]) output).length == 2) {
-	            int[] arr = (int[]) output;
-	            int[] sorted = new int[] { Math.min(arr[0], arr[1]), Math.max(arr[0], arr[1]) };
-	            return Arrays.toString(sorted);
-	        } else if (output instanceof Object[]) {
-	            return Arrays.deepToString((Object[]) output);
-	        } else if (output instanceof int[]) {
-	            return Arrays.toString((int[]) output);
-	        } else if (output instanceof long[]) {
-	            return Arrays.toString((long[]) output);
-	        } else if (output instanceof double[]) {
-	            return Arrays.toString((double[]) output);
-	        } else if (output instanceof boolean[]) {
-	            return Arrays.toString((boolean[]) output);
-	        } else if (output instanceof char[]) {
-	            return Arrays.toString((char[]) output);
-	        } else if (output instanceof byte[]) {
-	            return Arrays.toString((byte[]) output);
-	        } else if (output instanceof short[]) {
-	            return Arrays.toString((short[]) output);
-	        }
-	    }
-	    return String.valueOf(output);
-	}
-
 	public static void main(String[] args) {
-		Main main = new Main();
-		System.out.print(formatOutput(main.%s(%s)));
+		%s
 	}
-}`, sourceCode, functionName, inputs)
+}`, mainCode, main)
 }
 
 // TemplateJava creates a judge0.Submission for Java
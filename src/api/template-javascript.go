@@ -7,34 +7,204 @@ import (
 	"kadane.xyz/go-backend/v2/src/judge0"
 )
 
-// Convert the test case inputs to a comma separated string
-func TemplateJavascriptInputs(testCases TestCase) string {
-	var inputs []string
-
-	for _, input := range testCases.Input {
-		inputValue := strings.Trim(input.Value, "[]")
-		switch input.Type {
-		case "string":
-			// Strings need quotes
-			inputs = append(inputs, fmt.Sprintf("\"%s\"", inputValue))
-		case "int[]", "float[]", "double[]", "string[]", "boolean[]":
-			// Arrays just need square brackets
-			inputs = append(inputs, fmt.Sprintf("[%s]", inputValue))
-		default:
-			// Numbers and booleans can be passed as-is
-			inputs = append(inputs, inputValue)
+// Convert the test case inputs to the arguments decoded from stdin
+func TemplateJavascriptInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templateJavascriptArgument)
+	return inputs
+}
+
+// templateJavascriptArgument decodes the input at an index of the arguments, JSON values are javascript
+// values already apart from linked lists and trees. Typescript decodes them the same way.
+func templateJavascriptArgument(index int, input TestCaseInput) (string, []string) {
+	arg := fmt.Sprintf("__kadaneArgs[%d]", index)
+	switch templateInputType(input.Type) {
+	case ListNodeType:
+		arg = fmt.Sprintf("__kadaneList(%s)", arg)
+	case TreeNodeType:
+		arg = fmt.Sprintf("__kadaneTree(%s)", arg)
+	}
+	return "", []string{arg}
+}
+
+// templateJavascriptListNode and templateJavascriptTreeNode are leetcode's definitions
+const templateJavascriptListNode = `
+function ListNode(val, next) {
+	this.val = (val === undefined ? 0 : val);
+	this.next = (next === undefined ? null : next);
+}
+`
+
+const templateJavascriptTreeNode = `
+function TreeNode(val, left, right) {
+	this.val = (val === undefined ? 0 : val);
+	this.left = (left === undefined ? null : left);
+	this.right = (right === undefined ? null : right);
+}
+`
+
+// templateJavascriptStructures builds linked lists and trees from their JSON arrays and back and encodes results,
+// typescript uses them too
+const templateJavascriptStructures = `
+function __kadaneList(values) {
+	let head = null;
+	for (let i = values.length - 1; i >= 0; i--) head = new ListNode(values[i], head);
+	return head;
+}
+
+function __kadaneTree(values) {
+	// level order, null marks a missing child
+	if (!values.length) return null;
+	const root = new TreeNode(values[0]);
+	const queue = [root];
+	for (let i = 1, head = 0; head < queue.length && i < values.length; head++) {
+		const node = queue[head];
+		if (i < values.length && values[i] !== null) queue.push(node.left = new TreeNode(values[i]));
+		i++;
+		if (i < values.length && values[i] !== null) queue.push(node.right = new TreeNode(values[i]));
+		i++;
+	}
+	return root;
+}
+
+function __kadaneStructure(value) {
+	const name = value && value.constructor && value.constructor.name;
+	if (name === 'ListNode') {
+		const values = [];
+		for (let node = value; node; node = node.next) values.push(node.val);
+		return values;
+	}
+	if (name === 'TreeNode') {
+		const values = [];
+		const queue = [value];
+		for (let head = 0; head < queue.length; head++) {
+			const node = queue[head];
+			values.push(node ? node.val : null);
+			if (node) queue.push(node.left, node.right);
 		}
+		while (values.length && values[values.length - 1] === null) values.pop();
+		return values;
 	}
-	return strings.Join(inputs, ",")
+	return value;
+}
+
+// Results are written like JSON.stringify does, apart from the fields of objects and maps which are written in the
+// order of their keys like Go's encoding/json does
+function __kadaneEncode(value) {
+	value = __kadaneStructure(value);
+	if (value === undefined || value === null || typeof value === 'function') return 'null';
+	const name = value.constructor && value.constructor.name;
+	if (name === 'Set' || ArrayBuffer.isView(value)) {
+		const items = [];
+		value.forEach(item => items.push(item));
+		value = items;
+	}
+	if (Array.isArray(value)) return '[' + value.map(item => __kadaneEncode(item)).join(',') + ']';
+	if (typeof value !== 'object') return JSON.stringify(value);
+	if (typeof value.toJSON === 'function') return __kadaneEncode(value.toJSON());
+	const fields = Object.create(null);
+	if (name === 'Map') value.forEach((item, key) => { fields[String(key)] = item; });
+	else Object.keys(value).forEach(key => { fields[key] = value[key]; });
+	return '{' + Object.keys(fields)
+		.filter(key => fields[key] !== undefined && typeof fields[key] !== 'function')
+		.sort()
+		.map(key => JSON.stringify(key) + ':' + __kadaneEncode(fields[key]))
+		.join(',') + '}';
+}
+`
+
+// templateJavascriptTypes are the JSDoc types of the test case types
+var templateJavascriptTypes = map[TestCaseType]string{
+	IntType:           "number",
+	LongType:          "number",
+	FloatType:         "number",
+	DoubleType:        "number",
+	StringType:        "string",
+	CharType:          "character",
+	BoolType:          "boolean",
+	IntArrayType:      "number[]",
+	FloatArrayType:    "number[]",
+	DoubleArrayType:   "number[]",
+	StringArrayType:   "string[]",
+	BoolArrayType:     "boolean[]",
+	Int2DArrayType:    "number[][]",
+	String2DArrayType: "string[][]",
+	ListNodeType:      "ListNode",
+	TreeNodeType:      "TreeNode",
+	GraphType:         "number[][]",
+	MapType:           "Object<string, number>",
+}
+
+// templateJavascriptStub is leetcode's javascript starter code, typed with JSDoc
+func templateJavascriptStub(functionName string, signature ProblemSignature) string {
+	var doc strings.Builder
+	for _, parameter := range signature.Parameters {
+		fmt.Fprintf(&doc, " * @param {%s} %s\n", templateJavascriptTypes[parameter.Type], parameter.Name)
+	}
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return parameter.Name
+	})
+	return fmt.Sprintf("/**\n%s * @return {%s}\n */\nvar %s = function(%s) {\n    \n};", doc.String(), templateJavascriptTypes[signature.ReturnType], functionName, parameters)
 }
 
 // Javascript template
 func TemplateJavascriptSourceCode(functionName string, inputs string, sourceCode string) string {
-	return fmt.Sprintf(`
+	return templateJavascriptProgram(sourceCode, templateJavascriptMain(TemplateInput{FunctionName: functionName}, "", inputs))
+}
+
+// templateJavascriptMain calls the solution and prints its result
+func templateJavascriptMain(templateInput TemplateInput, declarations string, inputs string) string {
+	return fmt.Sprintf(`// Solutions may also be methods of a Solution class
+const __kadaneSolve = typeof %[1]s === 'function' ? %[1]s : (...args) => new Solution().%[1]s(...args);
+__kadanePrint(__kadaneSolve(%[2]s));`, templateInput.FunctionName, inputs)
+}
+
+// templateJavascriptDesign constructs the class and prints the results of its methods, typescript does the same
+func templateJavascriptDesign(className string, calls []templateDesignCall) string {
+	return templateJavascriptDesignMain(className, calls, "")
+}
+
+// templateJavascriptDesignMain calls the methods, the results are typed resultType in typescript
+func templateJavascriptDesignMain(className string, calls []templateDesignCall, resultType string) string {
+	main := []string{
+		fmt.Sprintf("const __kadaneObject = new %s(%s);", className, calls[0].Arguments),
+		fmt.Sprintf("const __kadaneResult%s = [null];", resultType),
+	}
+	for _, call := range calls[1:] {
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("__kadaneObject.%s(%s);", call.Method.Name, call.Arguments), "__kadaneResult.push(null);")
+			continue
+		}
+		main = append(main, fmt.Sprintf("__kadaneResult.push(__kadaneObject.%s(%s));", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, "__kadanePrint(__kadaneResult);"), "\n")
+}
+
+// templateJavascriptTest times the statements of a test case in a block of their own and prints the
+// delimiter after its result, typescript does the same
+func templateJavascriptTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`{
+const __kadaneStart = process.hrtime();
 %s
+const __kadaneTime = process.hrtime(__kadaneStart);
+process.stdout.write('\n%s %d ' + (__kadaneTime[0] + __kadaneTime[1] / 1e9).toFixed(3) + '\n');
+}`, main, delimiter, index)
+}
+
+// templateJavascriptProgram places the solution and the statements of main in the harness
+func templateJavascriptProgram(sourceCode string, main string) string {
+	return fmt.Sprintf(`%[2]s
+// Source Code
+%[1]s
+%[3]s
+
+const __kadaneArgs = JSON.parse(require('fs').readFileSync(0, 'utf8'));
+
+function __kadanePrint(result) {
+	process.stdout.write(__kadaneEncode(result));
+}
 
-console.log(%s(%s))
-`, sourceCode, functionName, inputs)
+%[4]s
+`, sourceCode, templateDefinitions(sourceCode, templateJavascriptListNode, templateJavascriptTreeNode), templateJavascriptStructures, main)
 }
 
 func TemplateJavascript(templateInput TemplateInput) judge0.Submission {
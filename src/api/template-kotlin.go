@@ -0,0 +1,367 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// templateKotlinDecoders are the prelude functions decoding each test case type
+var templateKotlinDecoders = map[TestCaseType]string{
+	IntType:         "kadaneInt",
+	FloatType:       "kadaneFloat",
+	DoubleType:      "kadaneDouble",
+	StringType:      "kadaneString",
+	BoolType:        "kadaneBoolean",
+	IntArrayType:    "kadaneIntArray",
+	FloatArrayType:  "kadaneFloatArray",
+	DoubleArrayType: "kadaneDoubleArray",
+	StringArrayType: "kadaneStringArray",
+	BoolArrayType:   "kadaneBooleanArray",
+	"null":          "kadaneNull",
+
+	LongType:          "kadaneLong",
+	CharType:          "kadaneChar",
+	Int2DArrayType:    "kadaneIntMatrix",
+	String2DArrayType: "kadaneStringMatrix",
+	ListNodeType:      "kadaneListNode",
+	TreeNodeType:      "kadaneTreeNode",
+	GraphType:         "kadaneIntMatrix",
+	MapType:           "kadaneMap",
+}
+
+// Convert the test case inputs to the arguments decoded from stdin
+func TemplateKotlinInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templateKotlinArgument)
+	return inputs
+}
+
+// templateKotlinArgument decodes the input at an index of the arguments
+func templateKotlinArgument(index int, input TestCaseInput) (string, []string) {
+	return "", []string{fmt.Sprintf("%s(kadaneArgs[%d])", templateKotlinDecoders[templateInputType(input.Type)], index)}
+}
+
+// templateKotlinPrelude reads JSON arguments and writes JSON results
+const templateKotlinPrelude = `
+// Numbers keep their literal until the solution's parameter type is known
+class KadaneNumber(val literal: String)
+
+class KadaneParser(private val text: String) {
+	private var pos = 0
+
+	private fun peek(): Char {
+		while (pos < text.length && text[pos].isWhitespace()) pos++
+		return if (pos < text.length) text[pos] else '\u0000'
+	}
+
+	private fun expect(c: Char) {
+		if (peek() != c) throw IllegalArgumentException("invalid input at " + pos)
+		pos++
+	}
+
+	private fun string(): String {
+		expect('"')
+		val sb = StringBuilder()
+		while (pos < text.length && text[pos] != '"') {
+			val c = text[pos++]
+			if (c != '\\') {
+				sb.append(c)
+				continue
+			}
+			when (val e = text[pos++]) {
+				'n' -> sb.append('\n')
+				't' -> sb.append('\t')
+				'r' -> sb.append('\r')
+				'b' -> sb.append('\b')
+				'f' -> sb.append('\u000c')
+				'u' -> {
+					sb.append(text.substring(pos, pos + 4).toInt(16).toChar())
+					pos += 4
+				}
+				else -> sb.append(e)
+			}
+		}
+		expect('"')
+		return sb.toString()
+	}
+
+	fun value(): Any? {
+		val c = peek()
+		if (c == '[') {
+			pos++
+			val items = ArrayList<Any?>()
+			if (peek() == ']') {
+				pos++
+				return items
+			}
+			while (true) {
+				items.add(value())
+				if (peek() == ',') {
+					pos++
+					continue
+				}
+				expect(']')
+				return items
+			}
+		}
+		if (c == '{') {
+			pos++
+			val fields = LinkedHashMap<String, Any?>()
+			if (peek() == '}') {
+				pos++
+				return fields
+			}
+			while (true) {
+				val key = string()
+				expect(':')
+				fields[key] = value()
+				if (peek() == ',') {
+					pos++
+					continue
+				}
+				expect('}')
+				return fields
+			}
+		}
+		if (c == '"') return string()
+		if (text.startsWith("true", pos)) { pos += 4; return true }
+		if (text.startsWith("false", pos)) { pos += 5; return false }
+		if (text.startsWith("null", pos)) { pos += 4; return null }
+		val start = pos
+		while (pos < text.length && "+-.eE0123456789".indexOf(text[pos]) >= 0) pos++
+		if (start == pos) throw IllegalArgumentException("invalid input at " + pos)
+		return KadaneNumber(text.substring(start, pos))
+	}
+}
+
+val kadaneArgs: List<Any?> by lazy {
+	KadaneParser(String(System.` + "`in`" + `.readBytes(), Charsets.UTF_8)).value() as List<Any?>
+}
+
+fun kadaneDouble(value: Any?): Double = (value as KadaneNumber).literal.toDouble()
+fun kadaneInt(value: Any?): Int = kadaneDouble(value).toInt()
+fun kadaneFloat(value: Any?): Float = (value as KadaneNumber).literal.toFloat()
+fun kadaneString(value: Any?): String = value as String
+fun kadaneBoolean(value: Any?): Boolean = value as Boolean
+fun kadaneNull(value: Any?): Any? = value
+fun kadaneList(value: Any?): List<Any?> = value as List<Any?>
+fun kadaneIntArray(value: Any?): IntArray = kadaneList(value).map { kadaneInt(it) }.toIntArray()
+fun kadaneFloatArray(value: Any?): FloatArray = kadaneList(value).map { kadaneFloat(it) }.toFloatArray()
+fun kadaneDoubleArray(value: Any?): DoubleArray = kadaneList(value).map { kadaneDouble(it) }.toDoubleArray()
+fun kadaneStringArray(value: Any?): Array<String> = kadaneList(value).map { kadaneString(it) }.toTypedArray()
+fun kadaneBooleanArray(value: Any?): BooleanArray = kadaneList(value).map { kadaneBoolean(it) }.toBooleanArray()
+fun kadaneLong(value: Any?): Long = (value as KadaneNumber).literal.toLongOrNull() ?: kadaneDouble(value).toLong()
+fun kadaneChar(value: Any?): Char = kadaneString(value)[0]
+fun kadaneIntMatrix(value: Any?): Array<IntArray> = kadaneList(value).map { kadaneIntArray(it) }.toTypedArray()
+fun kadaneStringMatrix(value: Any?): Array<Array<String>> = kadaneList(value).map { kadaneStringArray(it) }.toTypedArray()
+fun kadaneMap(value: Any?): Map<String, Int> = (value as Map<String, Any?>).mapValues { kadaneInt(it.value) }
+
+fun kadaneListNode(value: Any?): ListNode? {
+	var head: ListNode? = null
+	for (item in kadaneList(value).asReversed()) {
+		val node = ListNode(kadaneInt(item))
+		node.next = head
+		head = node
+	}
+	return head
+}
+
+// Trees are written in level order, null marks a missing child
+fun kadaneTreeNode(value: Any?): TreeNode? {
+	val values = kadaneList(value)
+	if (values.isEmpty() || values[0] == null) return null
+	val root = TreeNode(kadaneInt(values[0]))
+	val queue = ArrayDeque<TreeNode>()
+	queue.add(root)
+	var i = 1
+	while (queue.isNotEmpty() && i < values.size) {
+		val node = queue.poll()
+		if (values[i] != null) {
+			val child = TreeNode(kadaneInt(values[i]))
+			node.left = child
+			queue.add(child)
+		}
+		if (i + 1 < values.size && values[i + 1] != null) {
+			val child = TreeNode(kadaneInt(values[i + 1]))
+			node.right = child
+			queue.add(child)
+		}
+		i += 2
+	}
+	return root
+}
+
+fun kadaneTreeValues(root: TreeNode): List<Int?> {
+	val values = ArrayList<Int?>()
+	val queue = LinkedList<TreeNode?>()
+	queue.add(root)
+	while (queue.isNotEmpty()) {
+		val node = queue.poll()
+		values.add(node?.` + "`val`" + `)
+		if (node != null) {
+			queue.add(node.left)
+			queue.add(node.right)
+		}
+	}
+	while (values.isNotEmpty() && values.last() == null) values.removeAt(values.size - 1)
+	return values
+}
+
+fun kadaneQuote(value: String): String {
+	val sb = StringBuilder("\"")
+	for (c in value) {
+		when {
+			c == '"' -> sb.append("\\\"")
+			c == '\\' -> sb.append("\\\\")
+			c == '\n' -> sb.append("\\n")
+			c == '\t' -> sb.append("\\t")
+			c == '\r' -> sb.append("\\r")
+			c < ' ' -> sb.append(String.format("\\u%04x", c.toInt()))
+			else -> sb.append(c)
+		}
+	}
+	return sb.append('"').toString()
+}
+
+// Floats are printed without an exponent and integral ones without a fraction like the other languages do
+fun kadanePlain(text: String): String = text.toBigDecimalOrNull()?.stripTrailingZeros()?.toPlainString() ?: text
+
+fun kadaneJson(value: Any?): String = when (value) {
+	null, is Unit -> "null"
+	is String -> kadaneQuote(value)
+	is Char -> kadaneQuote(value.toString())
+	is Double, is Float -> kadanePlain(value.toString())
+	is Number, is Boolean -> value.toString()
+	is IntArray -> value.joinToString(",", "[", "]")
+	is LongArray -> value.joinToString(",", "[", "]")
+	is FloatArray -> value.joinToString(",", "[", "]") { kadanePlain(it.toString()) }
+	is DoubleArray -> value.joinToString(",", "[", "]") { kadanePlain(it.toString()) }
+	is BooleanArray -> value.joinToString(",", "[", "]")
+	is CharArray -> value.joinToString(",", "[", "]") { kadaneQuote(it.toString()) }
+	is Array<*> -> value.joinToString(",", "[", "]") { kadaneJson(it) }
+	is Iterable<*> -> value.joinToString(",", "[", "]") { kadaneJson(it) }
+	// Fields are written in the order of their keys like Go's encoding/json does, whatever the order of the map
+	is Map<*, *> -> value.entries.map { it.key.toString() to it.value }.sortedBy { it.first }
+		.joinToString(",", "{", "}") { kadaneQuote(it.first) + ":" + kadaneJson(it.second) }
+	is ListNode -> kadaneJson(generateSequence(value) { it.next }.map { it.` + "`val`" + ` }.toList())
+	is TreeNode -> kadaneJson(kadaneTreeValues(value))
+	is Pair<*, *> -> "[" + kadaneJson(value.first) + "," + kadaneJson(value.second) + "]"
+	else -> kadaneQuote(value.toString())
+}
+`
+
+// templateKotlinListNode and templateKotlinTreeNode are leetcode's definitions, they follow the solution's imports
+const templateKotlinListNode = `
+class ListNode(var ` + "`val`" + `: Int) {
+	var next: ListNode? = null
+}
+`
+
+const templateKotlinTreeNode = `
+class TreeNode(var ` + "`val`" + `: Int) {
+	var left: TreeNode? = null
+	var right: TreeNode? = null
+}
+`
+
+// templateKotlinTypes are the kotlin types the decoders return
+var templateKotlinTypes = map[TestCaseType]string{
+	IntType:           "Int",
+	LongType:          "Long",
+	FloatType:         "Float",
+	DoubleType:        "Double",
+	StringType:        "String",
+	CharType:          "Char",
+	BoolType:          "Boolean",
+	IntArrayType:      "IntArray",
+	FloatArrayType:    "FloatArray",
+	DoubleArrayType:   "DoubleArray",
+	StringArrayType:   "Array<String>",
+	BoolArrayType:     "BooleanArray",
+	Int2DArrayType:    "Array<IntArray>",
+	String2DArrayType: "Array<Array<String>>",
+	ListNodeType:      "ListNode?",
+	TreeNodeType:      "TreeNode?",
+	GraphType:         "Array<IntArray>",
+	MapType:           "Map<String, Int>",
+}
+
+// templateKotlinStub is leetcode's kotlin starter code
+func templateKotlinStub(functionName string, signature ProblemSignature) string {
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return parameter.Name + ": " + templateKotlinTypes[parameter.Type]
+	})
+	return fmt.Sprintf("class Solution {\n    fun %s(%s): %s {\n        \n    }\n}", functionName, parameters, templateKotlinTypes[signature.ReturnType])
+}
+
+// Kotlin template
+func TemplateKotlinSourceCode(functionName string, inputs string, sourceCode string) string {
+	return templateKotlinProgram(sourceCode, templateKotlinMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, "", inputs))
+}
+
+// templateKotlinMain calls the solution and prints its result
+func templateKotlinMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Solutions written as a class (e.g. leetcode's Solution) are called on an instance of it
+	call := fmt.Sprintf("%s(%s)", templateInput.FunctionName, inputs)
+	if className := templateClassName(templateInput.SourceCode); className != "" {
+		call = fmt.Sprintf("%s().%s", className, call)
+	}
+	return fmt.Sprintf("kadaneOut.print(kadaneJson(%s))", call)
+}
+
+// templateKotlinDesign constructs the class and prints the results of its methods
+func templateKotlinDesign(className string, calls []templateDesignCall) string {
+	main := []string{
+		fmt.Sprintf("val kadaneObject = %s(%s)", className, calls[0].Arguments),
+		"val kadaneResult = mutableListOf<Any?>(null)",
+	}
+	for _, call := range calls[1:] {
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("kadaneObject.%s(%s)", call.Method.Name, call.Arguments), "kadaneResult.add(null)")
+			continue
+		}
+		main = append(main, fmt.Sprintf("kadaneResult.add(kadaneObject.%s(%s))", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, "kadaneOut.print(kadaneJson(kadaneResult))"), "\n\t")
+}
+
+// templateKotlinTest times the statements of a test case in a block of their own and prints the delimiter after its result
+func templateKotlinTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`run {
+	val kadaneStart = System.nanoTime()
+	%s
+	System.out.flush()
+	kadaneOut.print(String.format(Locale.ROOT, "\n%s %d %%.3f\n", (System.nanoTime() - kadaneStart) / 1e9))
+	}`, main, delimiter, index)
+}
+
+// templateKotlinProgram places the solution and the statements of main in the harness
+func templateKotlinProgram(sourceCode string, main string) string {
+	// Warnings end up in the compile output, the harness's own are suppressed
+	return fmt.Sprintf(`@file:Suppress("UNCHECKED_CAST")
+import java.util.*
+
+// Source Code
+%s
+%s`, sourceCode, templateDefinitions(sourceCode, templateKotlinListNode, templateKotlinTreeNode)) + templateKotlinPrelude + fmt.Sprintf(`
+fun main() {
+	val kadaneOut = java.io.PrintStream(java.io.FileOutputStream(java.io.FileDescriptor.out), true, "UTF-8")
+	%s
+	kadaneOut.flush()
+}
+`, main)
+}
+
+func TemplateKotlin(templateInput TemplateInput) judge0.Submission {
+	inputs := TemplateKotlinInputs(templateInput.TestCase)                                               // Get the inputs
+	sourceCode := TemplateKotlinSourceCode(templateInput.FunctionName, inputs, templateInput.SourceCode) // Get the source code
+
+	submission := judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID("kotlin"),
+		SourceCode: sourceCode,
+		//ExpectedOutput: templateInput.ExpectedOutput,
+	}
+
+	return submission
+}
@@ -7,90 +7,216 @@ import (
 	"kadane.xyz/go-backend/v2/src/judge0"
 )
 
-// Convert the test case inputs to a comma separated string
-func TemplatePythonInputs(testCases TestCase) string {
-	var inputs []string
-
-	for _, input := range testCases.Input {
-		inputValue := strings.Trim(input.Value, "[]")
-		switch input.Type {
-		case "string":
-			// Python strings use single or double quotes
-			inputs = append(inputs, fmt.Sprintf("'%s'", inputValue))
-		case "boolean", "bool":
-			// Python uses True/False with capital first letter
-			if inputValue == "true" {
-				inputs = append(inputs, "True")
-			} else {
-				inputs = append(inputs, "False")
-			}
-		case "float", "double":
-			// Ensure float values have decimal point
-			if !strings.Contains(inputValue, ".") {
-				inputs = append(inputs, inputValue+".0")
-			} else {
-				inputs = append(inputs, inputValue)
-			}
-		case "int[]":
-			inputs = append(inputs, fmt.Sprintf("[%s]", inputValue))
-		case "float[]", "double[]":
-			// Convert each number to float
-			nums := strings.Split(inputValue, ",")
-			floats := make([]string, len(nums))
-			for i, num := range nums {
-				if !strings.Contains(num, ".") {
-					floats[i] = num + ".0"
-				} else {
-					floats[i] = num
-				}
-			}
-			inputs = append(inputs, fmt.Sprintf("[%s]", strings.Join(floats, ",")))
-		case "string[]":
-			// Add quotes around each string in array
-			strs := strings.Split(inputValue, ",")
-			for i, s := range strs {
-				strs[i] = fmt.Sprintf("'%s'", strings.Trim(s, "\"'"))
-			}
-			inputs = append(inputs, fmt.Sprintf("[%s]", strings.Join(strs, ",")))
-		case "boolean[]", "bool[]":
-			// Convert each boolean to Python syntax
-			bools := strings.Split(inputValue, ",")
-			for i, b := range bools {
-				if b == "true" {
-					bools[i] = "True"
-				} else {
-					bools[i] = "False"
-				}
-			}
-			inputs = append(inputs, fmt.Sprintf("[%s]", strings.Join(bools, ",")))
-		default:
-			// Integers can pass through as-is
-			inputs = append(inputs, inputValue)
-		}
+// Convert the test case inputs to the arguments decoded from stdin
+func TemplatePythonInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templatePythonArgument)
+	return inputs
+}
+
+// templatePythonArgument decodes the input at an index of the arguments
+func templatePythonArgument(index int, input TestCaseInput) (string, []string) {
+	arg := fmt.Sprintf("_kadane_args[%d]", index)
+	switch templateInputType(input.Type) {
+	case FloatType, DoubleType:
+		// JSON doesn't tell 1 and 1.0 apart
+		arg = fmt.Sprintf("float(%s)", arg)
+	case FloatArrayType, DoubleArrayType:
+		arg = fmt.Sprintf("[float(v) for v in %s]", arg)
+	case ListNodeType:
+		arg = fmt.Sprintf("_kadane_list(%s)", arg)
+	case TreeNodeType:
+		arg = fmt.Sprintf("_kadane_tree(%s)", arg)
+	}
+	return "", []string{arg}
+}
+
+// templatePythonListNode and templatePythonTreeNode are leetcode's definitions, solutions annotate with them
+const templatePythonListNode = `
+class ListNode:
+    def __init__(self, val=0, next=None):
+        self.val = val
+        self.next = next
+`
+
+const templatePythonTreeNode = `
+class TreeNode:
+    def __init__(self, val=0, left=None, right=None):
+        self.val = val
+        self.left = left
+        self.right = right
+`
+
+// templatePythonTypes are the type hints of the test case types
+var templatePythonTypes = map[TestCaseType]string{
+	IntType:           "int",
+	LongType:          "int",
+	FloatType:         "float",
+	DoubleType:        "float",
+	StringType:        "str",
+	CharType:          "str",
+	BoolType:          "bool",
+	IntArrayType:      "List[int]",
+	FloatArrayType:    "List[float]",
+	DoubleArrayType:   "List[float]",
+	StringArrayType:   "List[str]",
+	BoolArrayType:     "List[bool]",
+	Int2DArrayType:    "List[List[int]]",
+	String2DArrayType: "List[List[str]]",
+	ListNodeType:      "Optional[ListNode]",
+	TreeNodeType:      "Optional[TreeNode]",
+	GraphType:         "List[List[int]]",
+	MapType:           "Dict[str, int]",
+}
+
+// templatePythonStub is leetcode's python starter code
+func templatePythonStub(functionName string, signature ProblemSignature) string {
+	parameters := []string{"self"}
+	for _, parameter := range signature.Parameters {
+		parameters = append(parameters, parameter.Name+": "+templatePythonTypes[parameter.Type])
 	}
-	return strings.Join(inputs, ",")
+	return fmt.Sprintf("class Solution:\n    def %s(%s) -> %s:\n        ", functionName, strings.Join(parameters, ", "), templatePythonTypes[signature.ReturnType])
 }
 
 // Python template
 func TemplatePythonSourceCode(functionName string, inputs string, sourceCode string) string {
+	return templatePythonProgram(sourceCode, templatePythonMain(TemplateInput{FunctionName: functionName}, "", inputs))
+}
+
+// templatePythonMain calls the solution and prints its result
+func templatePythonMain(templateInput TemplateInput, declarations string, inputs string) string {
+	return fmt.Sprintf("_kadane_print(_kadane_function('%s')(%s))", templateInput.FunctionName, inputs)
+}
+
+// templatePythonDesign constructs the class and prints the results of its methods
+func templatePythonDesign(className string, calls []templateDesignCall) string {
+	main := []string{fmt.Sprintf("_kadane_object = %s(%s)", className, calls[0].Arguments), "_kadane_result = [None]"}
+	for _, call := range calls[1:] {
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("_kadane_object.%s(%s)", call.Method.Name, call.Arguments), "_kadane_result.append(None)")
+			continue
+		}
+		main = append(main, fmt.Sprintf("_kadane_result.append(_kadane_object.%s(%s))", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, "_kadane_print(_kadane_result)"), "\n")
+}
+
+// templatePythonTest times the statements of a test case and prints the delimiter after its result
+func templatePythonTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`_kadane_start = _kadane_time.perf_counter()
+%s
+print('\n%s %d %%.3f' %% (_kadane_time.perf_counter() - _kadane_start), flush=True)`, main, delimiter, index)
+}
+
+// templatePythonProgram places the solution and the statements of main in the harness
+func templatePythonProgram(sourceCode string, main string) string {
 	return fmt.Sprintf(`
+import decimal as _kadane_decimal
+import json as _kadane_json
+import math as _kadane_math
+import sys as _kadane_sys
+import time as _kadane_time
+from collections import deque as _kadane_deque
+from typing import *
+%s
+# Source Code
 %s
 
-# Create a Solution class if the code uses 'self'
-class Solution:
-    pass
-
-# Create instance if function uses self parameter
-if 'self' in %s.__code__.co_varnames:
-    result = %s(Solution(), %s)
-else:
-    result = %s(%s)
-
-if isinstance(result, (list, tuple)):
-    print('[' + ','.join(map(str, result)) + ']')
-else:
-    print(result)
-`, sourceCode, functionName, functionName, inputs, functionName, inputs)
+def _kadane_list(values):
+    head = None
+    for value in reversed(values):
+        head = ListNode(value, head)
+    return head
+
+def _kadane_tree(values):
+    # level order, None marks a missing child
+    if not values:
+        return None
+    root = TreeNode(values[0])
+    queue = _kadane_deque([root])
+    i = 1
+    while queue and i < len(values):
+        node = queue.popleft()
+        if i < len(values) and values[i] is not None:
+            node.left = TreeNode(values[i])
+            queue.append(node.left)
+        i += 1
+        if i < len(values) and values[i] is not None:
+            node.right = TreeNode(values[i])
+            queue.append(node.right)
+        i += 1
+    return root
+
+def _kadane_tree_values(root):
+    values = []
+    queue = _kadane_deque([root])
+    while queue:
+        node = queue.popleft()
+        if node is None:
+            values.append(None)
+            continue
+        values.append(node.val)
+        queue.append(node.left)
+        queue.append(node.right)
+    while values and values[-1] is None:
+        values.pop()
+    return values
+
+def _kadane_function(name):
+    # Leetcode style solutions are methods of a Solution class
+    solution = globals().get('Solution')
+    if isinstance(solution, type) and hasattr(solution, name):
+        return getattr(solution(), name)
+    return globals()[name]
+
+def _kadane_default(value):
+    if isinstance(value, (set, frozenset, tuple)):
+        return list(value)
+    if type(value).__name__ == 'ListNode':
+        values = []
+        while value is not None:
+            values.append(value.val)
+            value = value.next
+        return values
+    if type(value).__name__ == 'TreeNode':
+        return _kadane_tree_values(value)
+    raise TypeError('cannot print ' + type(value).__name__)
+
+def _kadane_float(value):
+    # Print floats like the other languages: integral values without a fraction, never in exponent form
+    if not _kadane_math.isfinite(value):
+        return _kadane_json.dumps(value)
+    if value.is_integer():
+        return str(int(value))
+    return format(_kadane_decimal.Decimal(repr(value)), 'f')
+
+def _kadane_key(key):
+    if isinstance(key, str):
+        return key
+    if isinstance(key, float):
+        return _kadane_float(key)
+    if key is None or isinstance(key, (bool, int)):
+        return _kadane_json.dumps(key)
+    raise TypeError('cannot print key ' + type(key).__name__)
+
+def _kadane_encode(value):
+    if value is None or isinstance(value, (bool, int, str)):
+        return _kadane_json.dumps(value, ensure_ascii=False)
+    if isinstance(value, float):
+        return _kadane_float(value)
+    if isinstance(value, list):
+        return '[' + ','.join(_kadane_encode(item) for item in value) + ']'
+    if isinstance(value, dict):
+        # Fields are printed in the order of their keys like Go's encoding/json does, whatever the order of the dict
+        fields = sorted(((_kadane_key(key), item) for key, item in value.items()), key=lambda field: field[0])
+        return '{' + ','.join(_kadane_json.dumps(key, ensure_ascii=False) + ':' + _kadane_encode(item) for key, item in fields) + '}'
+    return _kadane_encode(_kadane_default(value))
+
+def _kadane_print(result):
+    print(_kadane_encode(result))
+
+_kadane_args = _kadane_json.loads(_kadane_sys.stdin.read())
+%s
+`, templateDefinitions(sourceCode, templatePythonListNode, templatePythonTreeNode), sourceCode, main)
 }
 
 func TemplatePython(templateInput TemplateInput) judge0.Submission {
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// Convert the test case inputs to the arguments decoded from stdin
+func TemplateRubyInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templateRubyArgument)
+	return inputs
+}
+
+// templateRubyArgument decodes the input at an index of the arguments
+func templateRubyArgument(index int, input TestCaseInput) (string, []string) {
+	arg := fmt.Sprintf("kadane_args[%d]", index)
+	switch templateInputType(input.Type) {
+	case FloatType, DoubleType:
+		// JSON doesn't tell 1 and 1.0 apart
+		arg += ".to_f"
+	case FloatArrayType, DoubleArrayType:
+		arg += ".map(&:to_f)"
+	case ListNodeType:
+		arg = fmt.Sprintf("kadane_list(%s)", arg)
+	case TreeNodeType:
+		arg = fmt.Sprintf("kadane_tree(%s)", arg)
+	}
+	return "", []string{arg}
+}
+
+// templateRubyListNode and templateRubyTreeNode are leetcode's definitions
+const templateRubyListNode = `
+class ListNode
+  attr_accessor :val, :next
+  def initialize(val = 0, _next = nil)
+    @val = val
+    @next = _next
+  end
+end
+`
+
+const templateRubyTreeNode = `
+class TreeNode
+  attr_accessor :val, :left, :right
+  def initialize(val = 0, left = nil, right = nil)
+    @val = val
+    @left = left
+    @right = right
+  end
+end
+`
+
+// templateRubyTypes are the YARD types of the test case types
+var templateRubyTypes = map[TestCaseType]string{
+	IntType:           "Integer",
+	LongType:          "Integer",
+	FloatType:         "Float",
+	DoubleType:        "Float",
+	StringType:        "String",
+	CharType:          "Character",
+	BoolType:          "Boolean",
+	IntArrayType:      "Integer[]",
+	FloatArrayType:    "Float[]",
+	DoubleArrayType:   "Float[]",
+	StringArrayType:   "String[]",
+	BoolArrayType:     "Boolean[]",
+	Int2DArrayType:    "Integer[][]",
+	String2DArrayType: "String[][]",
+	ListNodeType:      "ListNode",
+	TreeNodeType:      "TreeNode",
+	GraphType:         "Integer[][]",
+	MapType:           "Hash",
+}
+
+// templateRubyStub is leetcode's ruby starter code, typed with YARD comments
+func templateRubyStub(functionName string, signature ProblemSignature) string {
+	var doc strings.Builder
+	for _, parameter := range signature.Parameters {
+		fmt.Fprintf(&doc, "# @param {%s} %s\n", templateRubyTypes[parameter.Type], parameter.Name)
+	}
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return parameter.Name
+	})
+	return fmt.Sprintf("%s# @return {%s}\ndef %s(%s)\n    \nend", doc.String(), templateRubyTypes[signature.ReturnType], functionName, parameters)
+}
+
+// Ruby template
+func TemplateRubySourceCode(functionName string, inputs string, sourceCode string) string {
+	return templateRubyProgram(sourceCode, templateRubyMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, "", inputs))
+}
+
+// templateRubyMain calls the solution and prints its result
+func templateRubyMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Solutions written as a class (e.g. leetcode's Solution) are called on an instance of it
+	call := fmt.Sprintf("%s(%s)", templateInput.FunctionName, inputs)
+	if className := templateClassName(templateInput.SourceCode); className != "" {
+		call = fmt.Sprintf("%s.new.%s", className, call)
+	}
+	return fmt.Sprintf("kadane_print(%s)", call)
+}
+
+// templateRubyDesign constructs the class and prints the results of its methods
+func templateRubyDesign(className string, calls []templateDesignCall) string {
+	main := []string{fmt.Sprintf("kadane_object = %s.new(%s)", className, calls[0].Arguments), "kadane_result = [nil]"}
+	for _, call := range calls[1:] {
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("kadane_object.%s(%s)", call.Method.Name, call.Arguments), "kadane_result << nil")
+			continue
+		}
+		main = append(main, fmt.Sprintf("kadane_result << kadane_object.%s(%s)", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, "kadane_print(kadane_result)"), "\n")
+}
+
+// templateRubyTest times the statements of a test case and prints the delimiter after its result
+func templateRubyTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`kadane_start = Process.clock_gettime(Process::CLOCK_MONOTONIC)
+%s
+print format("\n%s %d %%.3f\n", Process.clock_gettime(Process::CLOCK_MONOTONIC) - kadane_start)
+$stdout.flush`, main, delimiter, index)
+}
+
+// templateRubyProgram places the solution and the statements of main in the harness
+func templateRubyProgram(sourceCode string, main string) string {
+	return fmt.Sprintf(`
+require 'json'
+require 'set'
+%s
+# Source Code
+%s
+
+def kadane_list(values)
+  values.reverse.inject(nil) { |head, value| ListNode.new(value, head) }
+end
+
+# Trees are written in level order, nil marks a missing child
+def kadane_tree(values)
+  return nil if values.empty? || values[0].nil?
+  root = TreeNode.new(values[0])
+  queue = [root]
+  i = 1
+  while !queue.empty? && i < values.size
+    node = queue.shift
+    unless values[i].nil?
+      node.left = TreeNode.new(values[i])
+      queue << node.left
+    end
+    if i + 1 < values.size && !values[i + 1].nil?
+      node.right = TreeNode.new(values[i + 1])
+      queue << node.right
+    end
+    i += 2
+  end
+  root
+end
+
+def kadane_tree_values(root)
+  values = []
+  queue = [root]
+  until queue.empty?
+    node = queue.shift
+    values << (node && node.val)
+    queue.push(node.left, node.right) if node
+  end
+  values.pop while !values.empty? && values.last.nil?
+  values
+end
+
+# KadaneNumber is a number JSON.generate writes as it is
+class KadaneNumber
+  def initialize(text)
+    @text = text
+  end
+
+  def to_json(*)
+    @text
+  end
+end
+
+# Floats are printed without an exponent and integral ones without a fraction like the other languages do, only
+# fractions below 1e-4 are written with one by to_s
+def kadane_float(value)
+  return value unless value.finite?
+  return value.to_i if value == value.floor
+  mantissa, exponent = value.to_s.split('e')
+  return value unless exponent
+  sign = mantissa.start_with?('-') ? '-' : ''
+  whole, fraction = mantissa.delete('-').split('.')
+  digits = (whole + fraction.to_s).sub(/0+\z/, '')
+  point = whole.length + exponent.to_i
+  return value if point > 0
+  KadaneNumber.new(sign + '0.' + '0' * -point + digits)
+end
+
+def kadane_value(value)
+  case value
+  when Float then kadane_float(value)
+  when Set then value.map { |item| kadane_value(item) }
+  when Array then value.map { |item| kadane_value(item) }
+  # Fields are written in the order of their keys like Go's encoding/json does, whatever the order of the hash
+  when Hash then value.map { |key, item| [key.to_s, kadane_value(item)] }.sort_by(&:first).to_h
+  when Symbol then value.to_s
+  when ListNode
+    values = []
+    while value
+      values << value.val
+      value = value.next
+    end
+    values
+  when TreeNode then kadane_tree_values(value)
+  else value
+  end
+end
+
+def kadane_print(result)
+  print JSON.generate(kadane_value(result))
+end
+
+kadane_args = JSON.parse(STDIN.read)
+%s
+`, templateDefinitions(sourceCode, templateRubyListNode, templateRubyTreeNode), sourceCode, main)
+}
+
+func TemplateRuby(templateInput TemplateInput) judge0.Submission {
+	inputs := TemplateRubyInputs(templateInput.TestCase)                                               // Get the inputs
+	sourceCode := TemplateRubySourceCode(templateInput.FunctionName, inputs, templateInput.SourceCode) // Get the source code
+
+	submission := judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID("ruby"),
+		SourceCode: sourceCode,
+		//ExpectedOutput: templateInput.ExpectedOutput,
+	}
+
+	return submission
+}
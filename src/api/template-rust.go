@@ -0,0 +1,640 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// Convert the test case inputs to the arguments decoded from stdin, their types are inferred from the solution
+func TemplateRustInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templateRustArgument)
+	return inputs
+}
+
+// templateRustArgument decodes the input at an index of the arguments
+func templateRustArgument(index int, input TestCaseInput) (string, []string) {
+	return "", []string{fmt.Sprintf("kadane_arg(&kadane_args, %d)", index)}
+}
+
+// templateRustPrelude reads JSON arguments and writes JSON results.
+// Judge0 runs rust 1.40 with the 2015 edition, so the harness sticks to it.
+const templateRustPrelude = `
+// Reads JSON arguments and writes JSON results the way every other language does
+enum KadaneJson {
+	Null,
+	Bool(bool),
+	Number(String),
+	Str(String),
+	Array(Vec<KadaneJson>),
+	Object(Vec<(String, KadaneJson)>),
+}
+
+struct KadaneParser {
+	chars: Vec<char>,
+	i: usize,
+}
+
+impl KadaneParser {
+	fn fail(&self) -> ! {
+		eprintln!("invalid input at {}", self.i);
+		std::process::exit(1);
+	}
+
+	fn peek(&mut self) -> char {
+		while self.i < self.chars.len() && self.chars[self.i].is_whitespace() {
+			self.i += 1;
+		}
+		if self.i < self.chars.len() { self.chars[self.i] } else { '\0' }
+	}
+
+	fn expect(&mut self, c: char) {
+		if self.peek() != c {
+			self.fail();
+		}
+		self.i += 1;
+	}
+
+	fn hex4(&mut self) -> u32 {
+		if self.i + 4 > self.chars.len() {
+			self.fail();
+		}
+		let digits: String = self.chars[self.i..self.i + 4].iter().cloned().collect();
+		self.i += 4;
+		match u32::from_str_radix(&digits, 16) {
+			Ok(code) => code,
+			Err(_) => self.fail(),
+		}
+	}
+
+	fn string(&mut self) -> String {
+		self.expect('"');
+		let mut out = String::new();
+		while self.i < self.chars.len() && self.chars[self.i] != '"' {
+			let c = self.chars[self.i];
+			self.i += 1;
+			if c != '\\' {
+				out.push(c);
+				continue;
+			}
+			if self.i >= self.chars.len() {
+				self.fail();
+			}
+			let escaped = self.chars[self.i];
+			self.i += 1;
+			match escaped {
+				'n' => out.push('\n'),
+				't' => out.push('\t'),
+				'r' => out.push('\r'),
+				'b' => out.push('\u{8}'),
+				'f' => out.push('\u{c}'),
+				'u' => {
+					let mut code = self.hex4();
+					if code >= 0xD800 && code < 0xDC00 && self.i + 1 < self.chars.len() && self.chars[self.i] == '\\' && self.chars[self.i + 1] == 'u' {
+						self.i += 2;
+						code = 0x10000 + ((code - 0xD800) << 10) + (self.hex4() - 0xDC00);
+					}
+					out.push(std::char::from_u32(code).unwrap_or('\u{FFFD}'));
+				}
+				other => out.push(other),
+			}
+		}
+		self.expect('"');
+		out
+	}
+
+	fn literal(&mut self, word: &str) -> bool {
+		let end = self.i + word.len();
+		if end <= self.chars.len() && self.chars[self.i..end].iter().cloned().collect::<String>() == word {
+			self.i = end;
+			return true;
+		}
+		false
+	}
+
+	fn value(&mut self) -> KadaneJson {
+		match self.peek() {
+			'[' => {
+				self.i += 1;
+				let mut items = Vec::new();
+				if self.peek() == ']' {
+					self.i += 1;
+					return KadaneJson::Array(items);
+				}
+				loop {
+					items.push(self.value());
+					if self.peek() == ',' {
+						self.i += 1;
+						continue;
+					}
+					self.expect(']');
+					return KadaneJson::Array(items);
+				}
+			}
+			'{' => {
+				self.i += 1;
+				let mut fields = Vec::new();
+				if self.peek() == '}' {
+					self.i += 1;
+					return KadaneJson::Object(fields);
+				}
+				loop {
+					let key = self.string();
+					self.expect(':');
+					fields.push((key, self.value()));
+					if self.peek() == ',' {
+						self.i += 1;
+						continue;
+					}
+					self.expect('}');
+					return KadaneJson::Object(fields);
+				}
+			}
+			'"' => KadaneJson::Str(self.string()),
+			_ => {
+				if self.literal("true") {
+					return KadaneJson::Bool(true);
+				}
+				if self.literal("false") {
+					return KadaneJson::Bool(false);
+				}
+				if self.literal("null") {
+					return KadaneJson::Null;
+				}
+				let start = self.i;
+				while self.i < self.chars.len() && "+-.eE0123456789".contains(self.chars[self.i]) {
+					self.i += 1;
+				}
+				if start == self.i {
+					self.fail();
+				}
+				KadaneJson::Number(self.chars[start..self.i].iter().cloned().collect())
+			}
+		}
+	}
+}
+
+fn kadane_read_args() -> Vec<KadaneJson> {
+	use std::io::Read;
+	let mut input = String::new();
+	std::io::stdin().read_to_string(&mut input).unwrap();
+	let mut parser = KadaneParser { chars: input.chars().collect(), i: 0 };
+	match parser.value() {
+		KadaneJson::Array(items) => items,
+		_ => parser.fail(),
+	}
+}
+
+fn kadane_invalid<T>(expected: &str) -> T {
+	eprintln!("invalid argument, expected {}", expected);
+	std::process::exit(1);
+}
+
+trait KadaneFromJson: Sized {
+	fn from_json(json: &KadaneJson) -> Self;
+}
+
+macro_rules! kadane_number_from_json {
+	($($t:ty),*) => {
+		$(impl KadaneFromJson for $t {
+			fn from_json(json: &KadaneJson) -> Self {
+				match *json {
+					KadaneJson::Number(ref text) => match text.parse::<$t>() {
+						Ok(value) => value,
+						Err(_) => match text.parse::<f64>() {
+							Ok(value) => value as $t,
+							Err(_) => kadane_invalid(stringify!($t)),
+						},
+					},
+					_ => kadane_invalid(stringify!($t)),
+				}
+			}
+		})*
+	};
+}
+
+kadane_number_from_json!(i32, i64, u32, u64, usize, f32, f64);
+
+impl KadaneFromJson for bool {
+	fn from_json(json: &KadaneJson) -> Self {
+		match *json {
+			KadaneJson::Bool(value) => value,
+			_ => kadane_invalid("bool"),
+		}
+	}
+}
+
+impl KadaneFromJson for String {
+	fn from_json(json: &KadaneJson) -> Self {
+		match *json {
+			KadaneJson::Str(ref value) => value.clone(),
+			_ => kadane_invalid("String"),
+		}
+	}
+}
+
+impl KadaneFromJson for char {
+	fn from_json(json: &KadaneJson) -> Self {
+		match *json {
+			KadaneJson::Str(ref value) => value.chars().next().unwrap_or('\0'),
+			_ => kadane_invalid("char"),
+		}
+	}
+}
+
+impl<T: KadaneFromJson> KadaneFromJson for Vec<T> {
+	fn from_json(json: &KadaneJson) -> Self {
+		match *json {
+			KadaneJson::Array(ref items) => items.iter().map(T::from_json).collect(),
+			_ => kadane_invalid("Vec"),
+		}
+	}
+}
+
+impl<T: KadaneFromJson> KadaneFromJson for Option<T> {
+	fn from_json(json: &KadaneJson) -> Self {
+		match *json {
+			KadaneJson::Null => None,
+			_ => Some(T::from_json(json)),
+		}
+	}
+}
+
+impl<V: KadaneFromJson> KadaneFromJson for HashMap<String, V> {
+	fn from_json(json: &KadaneJson) -> Self {
+		match *json {
+			KadaneJson::Object(ref fields) => fields.iter().map(|&(ref k, ref v)| (k.clone(), V::from_json(v))).collect(),
+			_ => kadane_invalid("HashMap"),
+		}
+	}
+}
+
+fn kadane_arg<T: KadaneFromJson>(args: &[KadaneJson], index: usize) -> T {
+	if index >= args.len() {
+		return kadane_invalid("another argument");
+	}
+	T::from_json(&args[index])
+}
+
+trait KadaneToJson {
+	fn to_json(&self) -> String;
+}
+
+macro_rules! kadane_display_to_json {
+	($($t:ty),*) => {
+		$(impl KadaneToJson for $t {
+			fn to_json(&self) -> String {
+				self.to_string()
+			}
+		})*
+	};
+}
+
+kadane_display_to_json!(i8, i16, i32, i64, i128, isize, u8, u16, u32, u64, u128, usize, f32, f64, bool);
+
+fn kadane_quote(value: &str) -> String {
+	let mut out = String::from("\"");
+	for c in value.chars() {
+		match c {
+			'"' => out.push_str("\\\""),
+			'\\' => out.push_str("\\\\"),
+			'\n' => out.push_str("\\n"),
+			'\t' => out.push_str("\\t"),
+			'\r' => out.push_str("\\r"),
+			c if (c as u32) < 0x20 => out.push_str(&format!("\\u{:04x}", c as u32)),
+			c => out.push(c),
+		}
+	}
+	out.push('"');
+	out
+}
+
+impl KadaneToJson for String {
+	fn to_json(&self) -> String {
+		kadane_quote(self)
+	}
+}
+
+impl<'a> KadaneToJson for &'a str {
+	fn to_json(&self) -> String {
+		kadane_quote(self)
+	}
+}
+
+impl KadaneToJson for char {
+	fn to_json(&self) -> String {
+		kadane_quote(&self.to_string())
+	}
+}
+
+impl KadaneToJson for () {
+	fn to_json(&self) -> String {
+		String::from("null")
+	}
+}
+
+fn kadane_items<'a, T: 'a + KadaneToJson, I: Iterator<Item = &'a T>>(items: I) -> String {
+	let parts: Vec<String> = items.map(|v| v.to_json()).collect();
+	format!("[{}]", parts.join(","))
+}
+
+impl<T: KadaneToJson> KadaneToJson for Vec<T> {
+	fn to_json(&self) -> String {
+		kadane_items(self.iter())
+	}
+}
+
+impl<T: KadaneToJson> KadaneToJson for VecDeque<T> {
+	fn to_json(&self) -> String {
+		kadane_items(self.iter())
+	}
+}
+
+impl<T: KadaneToJson + Eq + std::hash::Hash> KadaneToJson for HashSet<T> {
+	fn to_json(&self) -> String {
+		kadane_items(self.iter())
+	}
+}
+
+impl<T: KadaneToJson + Ord> KadaneToJson for BTreeSet<T> {
+	fn to_json(&self) -> String {
+		kadane_items(self.iter())
+	}
+}
+
+impl<T: KadaneToJson> KadaneToJson for Option<T> {
+	fn to_json(&self) -> String {
+		match *self {
+			Some(ref v) => v.to_json(),
+			None => String::from("null"),
+		}
+	}
+}
+
+impl<A: KadaneToJson, B: KadaneToJson> KadaneToJson for (A, B) {
+	fn to_json(&self) -> String {
+		format!("[{},{}]", self.0.to_json(), self.1.to_json())
+	}
+}
+
+// JSON keys are strings, this is the text between the quotes of a key
+fn kadane_key<K: KadaneToJson>(key: &K) -> String {
+	let text = key.to_json();
+	if text.starts_with('"') { text[1..text.len() - 1].to_string() } else { text }
+}
+
+// Fields are written in the order of their keys like Go's encoding/json does, whatever the order of the map
+fn kadane_fields<'a, K: 'a + KadaneToJson, V: 'a + KadaneToJson, I: Iterator<Item = (&'a K, &'a V)>>(fields: I) -> String {
+	let mut parts: Vec<(String, String)> = fields.map(|(k, v)| (kadane_key(k), v.to_json())).collect();
+	parts.sort();
+	let parts: Vec<String> = parts.into_iter().map(|(k, v)| format!("\"{}\":{}", k, v)).collect();
+	format!("{{{}}}", parts.join(","))
+}
+
+impl<K: KadaneToJson + Eq + std::hash::Hash, V: KadaneToJson> KadaneToJson for HashMap<K, V> {
+	fn to_json(&self) -> String {
+		kadane_fields(self.iter())
+	}
+}
+
+impl<K: KadaneToJson + Ord, V: KadaneToJson> KadaneToJson for BTreeMap<K, V> {
+	fn to_json(&self) -> String {
+		kadane_fields(self.iter())
+	}
+}
+`
+
+// templateRustListNode and templateRustTreeNode are leetcode's definitions, with full paths
+// so they don't clash with the solution's imports
+const templateRustListNode = `
+#[derive(PartialEq, Eq, Clone, Debug)]
+pub struct ListNode {
+	pub val: i32,
+	pub next: Option<Box<ListNode>>,
+}
+
+impl ListNode {
+	#[inline]
+	fn new(val: i32) -> Self {
+		ListNode { next: None, val: val }
+	}
+}
+`
+
+const templateRustTreeNode = `
+#[derive(Debug, PartialEq, Eq)]
+pub struct TreeNode {
+	pub val: i32,
+	pub left: Option<std::rc::Rc<std::cell::RefCell<TreeNode>>>,
+	pub right: Option<std::rc::Rc<std::cell::RefCell<TreeNode>>>,
+}
+
+impl TreeNode {
+	#[inline]
+	pub fn new(val: i32) -> Self {
+		TreeNode { val: val, left: None, right: None }
+	}
+}
+`
+
+// templateRustStructures builds linked lists and trees from their JSON arrays and back
+const templateRustStructures = `
+impl KadaneFromJson for Option<Box<ListNode>> {
+	fn from_json(json: &KadaneJson) -> Self {
+		let values: Vec<i32> = KadaneFromJson::from_json(json);
+		let mut head = None;
+		for &value in values.iter().rev() {
+			let mut node = ListNode::new(value);
+			node.next = head;
+			head = Some(Box::new(node));
+		}
+		head
+	}
+}
+
+// Trees are written in level order, null marks a missing child
+impl KadaneFromJson for Option<std::rc::Rc<std::cell::RefCell<TreeNode>>> {
+	fn from_json(json: &KadaneJson) -> Self {
+		let values: Vec<Option<i32>> = KadaneFromJson::from_json(json);
+		let root = match values.first() {
+			Some(&Some(value)) => std::rc::Rc::new(std::cell::RefCell::new(TreeNode::new(value))),
+			_ => return None,
+		};
+		let mut queue = VecDeque::new();
+		queue.push_back(root.clone());
+		let mut i = 1;
+		while i < values.len() {
+			let node = match queue.pop_front() {
+				Some(node) => node,
+				None => break,
+			};
+			if let Some(value) = values[i] {
+				let child = std::rc::Rc::new(std::cell::RefCell::new(TreeNode::new(value)));
+				node.borrow_mut().left = Some(child.clone());
+				queue.push_back(child);
+			}
+			if i + 1 < values.len() {
+				if let Some(value) = values[i + 1] {
+					let child = std::rc::Rc::new(std::cell::RefCell::new(TreeNode::new(value)));
+					node.borrow_mut().right = Some(child.clone());
+					queue.push_back(child);
+				}
+			}
+			i += 2;
+		}
+		Some(root)
+	}
+}
+
+impl KadaneToJson for Box<ListNode> {
+	fn to_json(&self) -> String {
+		let mut values = Vec::new();
+		let mut node = Some(self);
+		while let Some(current) = node {
+			values.push(current.val);
+			node = current.next.as_ref();
+		}
+		values.to_json()
+	}
+}
+
+impl KadaneToJson for std::rc::Rc<std::cell::RefCell<TreeNode>> {
+	fn to_json(&self) -> String {
+		let mut values: Vec<Option<i32>> = Vec::new();
+		let mut queue = VecDeque::new();
+		queue.push_back(Some(self.clone()));
+		while let Some(item) = queue.pop_front() {
+			match item {
+				Some(node) => {
+					let node = node.borrow();
+					values.push(Some(node.val));
+					queue.push_back(node.left.clone());
+					queue.push_back(node.right.clone());
+				}
+				None => values.push(None),
+			}
+		}
+		while values.last() == Some(&None) {
+			values.pop();
+		}
+		values.to_json()
+	}
+}
+`
+
+// templateRustTypes are the rust types of the test case types
+var templateRustTypes = map[TestCaseType]string{
+	IntType:           "i32",
+	LongType:          "i64",
+	FloatType:         "f32",
+	DoubleType:        "f64",
+	StringType:        "String",
+	CharType:          "char",
+	BoolType:          "bool",
+	IntArrayType:      "Vec<i32>",
+	FloatArrayType:    "Vec<f32>",
+	DoubleArrayType:   "Vec<f64>",
+	StringArrayType:   "Vec<String>",
+	BoolArrayType:     "Vec<bool>",
+	Int2DArrayType:    "Vec<Vec<i32>>",
+	String2DArrayType: "Vec<Vec<String>>",
+	ListNodeType:      "Option<Box<ListNode>>",
+	TreeNodeType:      "Option<Rc<RefCell<TreeNode>>>",
+	GraphType:         "Vec<Vec<i32>>",
+	MapType:           "HashMap<String, i32>",
+}
+
+// templateRustStub is leetcode's rust starter code, trees need Rc and RefCell in scope
+func templateRustStub(functionName string, signature ProblemSignature) string {
+	uses := ""
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		if parameter.Type == TreeNodeType {
+			uses = "use std::rc::Rc;\nuse std::cell::RefCell;\n"
+		}
+		return parameter.Name + ": " + templateRustTypes[parameter.Type]
+	})
+	if signature.ReturnType == TreeNodeType {
+		uses = "use std::rc::Rc;\nuse std::cell::RefCell;\n"
+	}
+	return fmt.Sprintf("%simpl Solution {\n    pub fn %s(%s) -> %s {\n        \n    }\n}", uses, functionName, parameters, templateRustTypes[signature.ReturnType])
+}
+
+// Rust template
+func TemplateRustSourceCode(functionName string, inputs string, sourceCode string) string {
+	return templateRustProgram(sourceCode, templateRustMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, "", inputs))
+}
+
+// templateRustMain calls the solution and prints its result
+func templateRustMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Leetcode style solutions are associated functions of an empty Solution struct, see templateRustProgram
+	call := fmt.Sprintf("%s(%s)", templateInput.FunctionName, inputs)
+	if strings.Contains(templateInput.SourceCode, "impl Solution") {
+		call = "Solution::" + call
+	}
+
+	return fmt.Sprintf(`let result = %s;
+	print!("{}", result.to_json());`, call)
+}
+
+// templateRustDesign constructs the class with new and writes the JSON array of the results of its methods
+func templateRustDesign(className string, calls []templateDesignCall) string {
+	main := []string{
+		fmt.Sprintf("let mut kadane_object = %s::new(%s);", className, calls[0].Arguments),
+		`let mut kadane_result: Vec<String> = vec!["null".to_string()];`,
+	}
+	for _, call := range calls[1:] {
+		if call.Method.ReturnType == VoidType {
+			main = append(main, fmt.Sprintf("kadane_object.%s(%s);", call.Method.Name, call.Arguments), `kadane_result.push("null".to_string());`)
+			continue
+		}
+		main = append(main, fmt.Sprintf("kadane_result.push(kadane_object.%s(%s).to_json());", call.Method.Name, call.Arguments))
+	}
+	return strings.Join(append(main, `print!("[{}]", kadane_result.join(","));`), "\n\t")
+}
+
+// templateRustTest times the statements of a test case in a block of their own and prints the delimiter after its result
+func templateRustTest(delimiter string, index int, main string) string {
+	return fmt.Sprintf(`{
+	let kadane_start = std::time::Instant::now();
+	%s
+	print!("\n%s %d {:.3}\n", kadane_start.elapsed().as_secs_f64());
+	}`, main, delimiter, index)
+}
+
+// templateRustProgram places the solution and the statements of main in the harness, declaring the empty
+// Solution struct of leetcode style solutions that don't
+func templateRustProgram(sourceCode string, main string) string {
+	solutionStruct := ""
+	if strings.Contains(sourceCode, "impl Solution") && !strings.Contains(sourceCode, "struct Solution") {
+		solutionStruct = "struct Solution;"
+	}
+
+	return fmt.Sprintf(`
+#![allow(unused, non_snake_case)]
+use std::collections::*;
+
+%s
+%s
+// Source Code
+%s
+`, solutionStruct, templateDefinitions(sourceCode, templateRustListNode, templateRustTreeNode), sourceCode) + templateRustPrelude + templateRustStructures + fmt.Sprintf(`
+fn main() {
+	let kadane_args = kadane_read_args();
+	%s
+}
+`, main)
+}
+
+func TemplateRust(templateInput TemplateInput) judge0.Submission {
+	inputs := TemplateRustInputs(templateInput.TestCase)                                               // Get the inputs
+	sourceCode := TemplateRustSourceCode(templateInput.FunctionName, inputs, templateInput.SourceCode) // Get the source code
+
+	submission := judge0.Submission{
+		LanguageID: judge0.LanguageToLanguageID("rust"),
+		SourceCode: sourceCode,
+		//ExpectedOutput: templateInput.ExpectedOutput,
+	}
+
+	return submission
+}
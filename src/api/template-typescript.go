@@ -2,36 +2,114 @@ package api
 
 import (
 	"fmt"
-	"strings"
 
 	"kadane.xyz/go-backend/v2/src/judge0"
 )
 
-// Convert the test case inputs to a comma separated string
-func TemplateTypescriptInputs(testCases TestCase) string {
-	var inputs []string
-
-	for _, input := range testCases.Input {
-		inputValue := strings.Trim(input.Value, "[]")
-		switch input.Type {
-		case "float", "double", "int", "boolean":
-			inputs = append(inputs, inputValue) // TypeScript uses number for both
-		case "int[]", "float[]", "double[]", "string[]", "boolean[]":
-			inputs = append(inputs, fmt.Sprintf("[%s]", inputValue))
-		case "string":
-			inputs = append(inputs, fmt.Sprintf("\"%s\"", inputValue))
-		}
+// Convert the test case inputs to the arguments decoded from stdin
+func TemplateTypescriptInputs(testCase TestCase) string {
+	_, inputs := templateInputs(testCase.Input, 0, templateTypescriptArgument)
+	return inputs
+}
+
+// templateTypescriptArgument decodes the input at an index of the arguments like javascript, the arguments are typed any
+func templateTypescriptArgument(index int, input TestCaseInput) (string, []string) {
+	return templateJavascriptArgument(index, input)
+}
+
+// templateTypescriptListNode and templateTypescriptTreeNode are leetcode's definitions
+const templateTypescriptListNode = `
+class ListNode {
+	val: number
+	next: ListNode | null
+	constructor(val?: number, next?: ListNode | null) {
+		this.val = (val === undefined ? 0 : val)
+		this.next = (next === undefined ? null : next)
+	}
+}
+`
+
+const templateTypescriptTreeNode = `
+class TreeNode {
+	val: number
+	left: TreeNode | null
+	right: TreeNode | null
+	constructor(val?: number, left?: TreeNode | null, right?: TreeNode | null) {
+		this.val = (val === undefined ? 0 : val)
+		this.left = (left === undefined ? null : left)
+		this.right = (right === undefined ? null : right)
 	}
-	return strings.Join(inputs, ",")
+}
+`
+
+// templateTypescriptTypes are the typescript types of the test case types
+var templateTypescriptTypes = map[TestCaseType]string{
+	IntType:           "number",
+	LongType:          "number",
+	FloatType:         "number",
+	DoubleType:        "number",
+	StringType:        "string",
+	CharType:          "string",
+	BoolType:          "boolean",
+	IntArrayType:      "number[]",
+	FloatArrayType:    "number[]",
+	DoubleArrayType:   "number[]",
+	StringArrayType:   "string[]",
+	BoolArrayType:     "boolean[]",
+	Int2DArrayType:    "number[][]",
+	String2DArrayType: "string[][]",
+	ListNodeType:      "ListNode | null",
+	TreeNodeType:      "TreeNode | null",
+	GraphType:         "number[][]",
+	MapType:           "{ [key: string]: number }",
+}
+
+// templateTypescriptStub is leetcode's typescript starter code
+func templateTypescriptStub(functionName string, signature ProblemSignature) string {
+	parameters := signatureStubParameters(signature, func(parameter SignatureParameter) string {
+		return parameter.Name + ": " + templateTypescriptTypes[parameter.Type]
+	})
+	return fmt.Sprintf("function %s(%s): %s {\n    \n};", functionName, parameters, templateTypescriptTypes[signature.ReturnType])
 }
 
 // Typescript template
 func TemplateTypescriptSourceCode(functionName string, inputs string, sourceCode string) string {
+	return templateTypescriptProgram(sourceCode, templateTypescriptMain(TemplateInput{FunctionName: functionName, SourceCode: sourceCode}, "", inputs))
+}
+
+// templateTypescriptMain calls the solution and prints its result
+func templateTypescriptMain(templateInput TemplateInput, declarations string, inputs string) string {
+	// Solutions written as a class (e.g. leetcode's Solution) are called on an instance of it,
+	// typescript doesn't compile references to a class that doesn't exist
+	call := fmt.Sprintf("%s(%s)", templateInput.FunctionName, inputs)
+	if className := templateClassName(templateInput.SourceCode); className != "" {
+		call = fmt.Sprintf("new %s().%s", className, call)
+	}
+	return fmt.Sprintf("__kadanePrint(%s);", call)
+}
+
+// templateTypescriptDesign constructs the class and prints the results of its methods
+func templateTypescriptDesign(className string, calls []templateDesignCall) string {
+	return templateJavascriptDesignMain(className, calls, ": any[]")
+}
+
+// templateTypescriptProgram places the solution and the statements of main in the harness
+func templateTypescriptProgram(sourceCode string, main string) string {
 	return fmt.Sprintf(`
+declare var require: any;
+declare var process: any;
 %s
+// Source Code
+%s
+%s
+const __kadaneArgs: any[] = JSON.parse(require('fs').readFileSync(0, 'utf8'));
 
-console.log(%s(%s))
-`, sourceCode, functionName, inputs)
+function __kadanePrint(result: any) {
+	process.stdout.write(__kadaneEncode(result));
+}
+
+%s
+`, templateDefinitions(sourceCode, templateTypescriptListNode, templateTypescriptTreeNode), sourceCode, templateJavascriptStructures, main)
 }
 
 func TemplateTypescript(templateInput TemplateInput) judge0.Submission {
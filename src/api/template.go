@@ -1,6 +1,15 @@
 package api
 
-import "kadane.xyz/go-backend/v2/src/judge0"
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
 
 type TemplateInput struct {
 	Language       string   `json:"language"`
@@ -9,22 +18,296 @@ type TemplateInput struct {
 	ExpectedOutput string   `json:"expectedOutput"`
 	Problem        Problem  `json:"problem"`
 	TestCase       TestCase `json:"testCase"`
+	// Signature types the inputs when the problem declares one, the test case types are used otherwise
+	Signature *ProblemSignature `json:"signature,omitempty"`
+	// Design is the class of design problems, named after the function name, the test case calls its methods
+	Design *ProblemDesign `json:"design,omitempty"`
 }
 
-func TemplateCreate(templateInput TemplateInput) judge0.Submission {
+// TemplateCreate creates the judge0 submission running a solution against a test case.
+// Every language uses the same harness: the test case inputs are passed on stdin as a JSON array
+// with one element per argument, a per-language prelude decodes them and calls the solution, and
+// the result is printed as JSON so outputs look the same in every language.
+func TemplateCreate(templateInput TemplateInput) (judge0.Submission, error) {
+	if templateInput.Design != nil {
+		return TemplateDesign(templateInput)
+	}
+	if templateInput.Signature != nil {
+		templateInput.TestCase = templateInput.Signature.TestCase(templateInput.TestCase)
+	}
+
+	stdin, err := TemplateStdin(templateInput.TestCase)
+	if err != nil {
+		return judge0.Submission{}, err
+	}
+
+	var submission judge0.Submission
 	switch templateInput.Language {
 	case "cpp":
-		return TemplateCpp(templateInput)
+		submission = TemplateCpp(templateInput)
 	case "go":
-		return TemplateGo(templateInput)
+		submission = TemplateGo(templateInput)
 	case "java":
-		return TemplateJava(templateInput)
+		submission = TemplateJava(templateInput)
 	case "javascript":
-		return TemplateJavascript(templateInput)
+		submission = TemplateJavascript(templateInput)
 	case "python":
-		return TemplatePython(templateInput)
+		submission = TemplatePython(templateInput)
 	case "typescript":
-		return TemplateTypescript(templateInput)
+		submission = TemplateTypescript(templateInput)
+	case "rust":
+		submission = TemplateRust(templateInput)
+	case "c":
+		submission = TemplateC(templateInput)
+	case "csharp":
+		submission = TemplateCsharp(templateInput)
+	case "kotlin":
+		submission = TemplateKotlin(templateInput)
+	case "ruby":
+		submission = TemplateRuby(templateInput)
+	default:
+		return judge0.Submission{}, fmt.Errorf("unsupported language %s", templateInput.Language)
+	}
+
+	submission.Stdin = stdin
+	return submission, nil
+}
+
+// templateLanguage generates the harness of a language. argument decodes the input at an index of stdin, see
+// templateInputs, main calls the solution with the decoded arguments and prints its result, design constructs the
+// class of a design test case and prints the results of its calls, test times the statements of a test case run
+// with others and prints the delimiter line after them, see TemplateBatch, and program places the solution and the
+// statements of main in the harness.
+type templateLanguage struct {
+	argument func(index int, input TestCaseInput) (string, []string)
+	main     func(templateInput TemplateInput, declarations string, arguments string) string
+	design   func(className string, calls []templateDesignCall) string
+	test     func(delimiter string, index int, main string) string
+	program  func(sourceCode string, main string) string
+}
+
+var templateLanguages = map[string]templateLanguage{
+	"c":          {templateCArgument, templateCMain, templateCDesign, templateCTest, templateCProgram},
+	"cpp":        {templateCppArgument, templateCppMain, templateCppDesign, templateCppTest, templateCppProgram},
+	"csharp":     {templateCsharpArgument, templateCsharpMain, templateCsharpDesign, templateCsharpTest, templateCsharpProgram},
+	"go":         {templateGoArgument, templateGoMain, templateGoDesign, templateGoTest, templateGoProgram},
+	"java":       {templateJavaArgument, templateJavaMain, templateJavaDesign, templateJavaTest, templateJavaProgram},
+	"javascript": {templateJavascriptArgument, templateJavascriptMain, templateJavascriptDesign, templateJavascriptTest, templateJavascriptProgram},
+	"kotlin":     {templateKotlinArgument, templateKotlinMain, templateKotlinDesign, templateKotlinTest, templateKotlinProgram},
+	"python":     {templatePythonArgument, templatePythonMain, templatePythonDesign, templatePythonTest, templatePythonProgram},
+	"ruby":       {templateRubyArgument, templateRubyMain, templateRubyDesign, templateRubyTest, templateRubyProgram},
+	"rust":       {templateRustArgument, templateRustMain, templateRustDesign, templateRustTest, templateRustProgram},
+	"typescript": {templateTypescriptArgument, templateTypescriptMain, templateTypescriptDesign, templateJavascriptTest, templateTypescriptProgram},
+}
+
+// TemplateStdin encodes the test case inputs as the JSON array of arguments the harness reads from stdin
+func TemplateStdin(testCase TestCase) (string, error) {
+	args := make([]json.RawMessage, len(testCase.Input))
+	for i, input := range testCase.Input {
+		value, err := templateInputJSON(input)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s input %s: %w", input.Type, input.Name, err)
+		}
+		args[i] = value
+	}
+
+	stdin, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	return string(stdin), nil
+}
+
+// templateInputType maps the boolean spellings of the database enum to the api types
+func templateInputType(inputType TestCaseType) TestCaseType {
+	switch inputType {
+	case "boolean":
+		return BoolType
+	case "boolean[]":
+		return BoolArrayType
+	}
+	return inputType
+}
+
+// templateInputJSON converts a test case input value to JSON. Values are JSON already, except for
+// strings which may also be written bare or in single quotes, like "[a, 'b']".
+func templateInputJSON(input TestCaseInput) (json.RawMessage, error) {
+	value := strings.TrimSpace(input.Value)
+
+	var decoded any
+	var err error
+	switch templateInputType(input.Type) {
+	case IntType, LongType:
+		decoded, err = strconv.ParseInt(value, 10, 64)
+	case FloatType, DoubleType:
+		decoded, err = strconv.ParseFloat(value, 64)
+	case BoolType:
+		decoded, err = strconv.ParseBool(value)
+	case StringType:
+		decoded = templateString(value)
+	case CharType:
+		decoded = templateString(value)
+		if utf8.RuneCountInString(decoded.(string)) != 1 {
+			err = fmt.Errorf("expected a single character")
+		}
+	case IntArrayType, ListNodeType:
+		decoded, err = templateArray(value, func(element string) (any, error) {
+			return strconv.ParseInt(element, 10, 64)
+		})
+	case FloatArrayType, DoubleArrayType:
+		decoded, err = templateArray(value, func(element string) (any, error) {
+			return strconv.ParseFloat(element, 64)
+		})
+	case BoolArrayType:
+		decoded, err = templateArray(value, func(element string) (any, error) {
+			return strconv.ParseBool(element)
+		})
+	case StringArrayType:
+		decoded, err = templateArray(value, func(element string) (any, error) {
+			return templateString(element), nil
+		})
+	case Int2DArrayType:
+		decoded, err = templateJSON[[][]int64](value)
+	case String2DArrayType:
+		decoded, err = templateJSON[[][]string](value)
+	case TreeNodeType:
+		decoded, err = templateJSON[[]*int64](value)
+		if err == nil && len(decoded.([]*int64)) > 0 && decoded.([]*int64)[0] == nil {
+			err = fmt.Errorf("the root of a tree can't be null")
+		}
+	case GraphType:
+		decoded, err = templateGraph(value)
+	case MapType:
+		decoded, err = templateJSON[map[string]int64](value)
+	case "null":
+		decoded = nil
+	default:
+		return nil, fmt.Errorf("unsupported type")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decoded)
+}
+
+// templateString reads a string value, JSON strings are decoded and bare or single quoted ones taken as is
+func templateString(value string) string {
+	var decoded string
+	if json.Unmarshal([]byte(value), &decoded) == nil {
+		return decoded
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// templateJSON reads a value that must be written as JSON
+func templateJSON[T any](value string) (T, error) {
+	var decoded T
+	err := json.Unmarshal([]byte(value), &decoded)
+	return decoded, err
+}
+
+// templateGraph reads an adjacency list, every neighbour must be a node of the graph
+func templateGraph(value string) ([][]int64, error) {
+	graph, err := templateJSON[[][]int64](value)
+	if err != nil {
+		return nil, err
+	}
+	for node, neighbours := range graph {
+		for _, neighbour := range neighbours {
+			if neighbour < 0 || neighbour >= int64(len(graph)) {
+				return nil, fmt.Errorf("node %d has neighbour %d, which isn't a node", node, neighbour)
+			}
+		}
+	}
+	return graph, nil
+}
+
+// templateArray reads an array value element by element
+func templateArray(value string, element func(string) (any, error)) ([]any, error) {
+	var elements []json.RawMessage
+	var raw []string
+	if json.Unmarshal([]byte(value), &elements) == nil {
+		for _, e := range elements {
+			raw = append(raw, string(e))
+		}
+	} else {
+		raw = templateArrayElements(value)
+	}
+
+	result := make([]any, 0, len(raw))
+	for _, e := range raw {
+		decoded, err := element(strings.TrimSpace(e))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, decoded)
+	}
+	return result, nil
+}
+
+// templateInputs decodes the inputs with argument, the first one being at index offset of stdin. argument returns
+// the statements declaring an input, for languages passing arguments by reference, and the arguments it's passed as.
+// Returns the declarations and the argument list.
+func templateInputs(inputs []TestCaseInput, offset int, argument func(index int, input TestCaseInput) (string, []string)) (string, string) {
+	var declarations []string
+	var arguments []string
+	for i, input := range inputs {
+		declaration, args := argument(offset+i, input)
+		if declaration != "" {
+			declarations = append(declarations, declaration)
+		}
+		arguments = append(arguments, args...)
+	}
+	return strings.Join(declarations, "\n"), strings.Join(arguments, ", ")
+}
+
+// templateArrayElements splits an array test case value such as "[1, 2]" into its trimmed elements
+func templateArrayElements(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "[")
+	value = strings.TrimSuffix(value, "]")
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	elements := strings.Split(value, ",")
+	for i, element := range elements {
+		elements[i] = strings.TrimSpace(element)
+	}
+	return elements
+}
+
+// templateDefines reports whether a solution declares the type itself, so the prelude leaves out
+// its own ListNode or TreeNode. Commented out declarations, like leetcode's, don't count.
+func templateDefines(sourceCode string, typeName string) bool {
+	pattern := `(?m)^[ \t]*(?:(?:pub|public|export|data|open|typedef)\s+)*(?:class|struct|type|function)\s+` + regexp.QuoteMeta(typeName) +
+		`\s*(?:[{(:<]|$|(?:struct|extends|implements)\b)`
+	return regexp.MustCompile(pattern).MatchString(sourceCode)
+}
+
+// templateDefinitions returns the prelude's declarations of the types the solution doesn't declare
+func templateDefinitions(sourceCode string, listNode string, treeNode string) string {
+	var definitions []string
+	if !templateDefines(sourceCode, "ListNode") {
+		definitions = append(definitions, listNode)
+	}
+	if !templateDefines(sourceCode, "TreeNode") {
+		definitions = append(definitions, treeNode)
+	}
+	return strings.Join(definitions, "\n")
+}
+
+// templateClassName finds the class a solution is written in, if any. The ListNode and TreeNode
+// classes solutions may declare, or mention in comments, hold inputs and aren't it.
+func templateClassName(sourceCode string) string {
+	for _, match := range regexp.MustCompile(`\bclass\s+(\w+)`).FindAllStringSubmatch(sourceCode, -1) {
+		if match[1] != "ListNode" && match[1] != "TreeNode" {
+			return match[1]
+		}
 	}
-	return judge0.Submission{}
+	return ""
 }
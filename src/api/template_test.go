@@ -0,0 +1,450 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// templateRunners run a generated program locally with the toolchain of its language, like judge0 does. Compiled
+// languages build the program in the directory of the file first. {file} and {dir} are replaced in both commands.
+var templateRunners = map[string]struct {
+	file    string
+	build   []string
+	command []string
+}{
+	"c":          {file: "main.c", build: []string{"gcc", "-o", "{dir}/main", "{file}", "-lm"}, command: []string{"{dir}/main"}},
+	"cpp":        {file: "main.cpp", build: []string{"g++", "-std=c++17", "-o", "{dir}/main", "{file}"}, command: []string{"{dir}/main"}},
+	"csharp":     {file: "Main.cs", build: []string{"mcs", "-out:{dir}/main.exe", "{file}"}, command: []string{"mono", "{dir}/main.exe"}},
+	"go":         {file: "main.go", command: []string{"go", "run", "{file}"}},
+	"java":       {file: "Main.java", build: []string{"javac", "{file}"}, command: []string{"java", "-cp", "{dir}", "Main"}},
+	"javascript": {file: "main.js", command: []string{"node", "{file}"}},
+	"kotlin":     {file: "Main.kt", build: []string{"kotlinc", "{file}", "-include-runtime", "-d", "{dir}/main.jar"}, command: []string{"java", "-jar", "{dir}/main.jar"}},
+	"python":     {file: "main.py", command: []string{"python3", "{file}"}},
+	"ruby":       {file: "main.rb", command: []string{"ruby", "{file}"}},
+	"rust":       {file: "main.rs", build: []string{"rustc", "-o", "{dir}/main", "{file}"}, command: []string{"{dir}/main"}},
+	"typescript": {file: "main.ts", build: []string{"tsc", "{file}"}, command: []string{"node", "{dir}/main.js"}},
+}
+
+// runTemplate runs a generated program with its stdin and returns what it printed. The test is skipped when the
+// toolchain of the language isn't installed.
+func runTemplate(t *testing.T, language string, sourceCode string, stdin string) string {
+	t.Helper()
+
+	runner := templateRunners[language]
+	for _, command := range [][]string{runner.build, runner.command} {
+		if len(command) == 0 || strings.Contains(command[0], "{") {
+			continue
+		}
+		if _, err := exec.LookPath(command[0]); err != nil {
+			t.Skipf("%s is not installed", command[0])
+		}
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, runner.file)
+	if err := os.WriteFile(file, []byte(sourceCode), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	command := func(args []string) *exec.Cmd {
+		replaced := make([]string, len(args))
+		for i, arg := range args {
+			replaced[i] = strings.NewReplacer("{file}", file, "{dir}", dir).Replace(arg)
+		}
+		return exec.Command(replaced[0], replaced[1:]...)
+	}
+
+	if len(runner.build) > 0 {
+		if output, err := command(runner.build).CombinedOutput(); err != nil {
+			t.Fatalf("Failed to build template: %v\n%s\n%s", err, output, sourceCode)
+		}
+	}
+
+	run := command(runner.command)
+	run.Stdin = strings.NewReader(stdin)
+	var stderr strings.Builder
+	run.Stderr = &stderr
+	output, err := run.Output()
+	if err != nil {
+		t.Fatalf("Failed to run template: %v\n%s\n%s", err, stderr.String(), sourceCode)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestTemplateDoubleOutput(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          TestCaseInput
+		sourceCodes    map[string]string
+		expectedOutput string
+	}{
+		{
+			name:  "Print a double",
+			input: TestCaseInput{Name: "value", Type: DoubleType, Value: "1e20"},
+			sourceCodes: map[string]string{
+				"go":         "func identity(value float64) float64 {\n\treturn value\n}",
+				"javascript": "function identity(value) {\n\treturn value;\n}",
+				"python":     "def identity(value):\n    return value",
+			},
+			expectedOutput: "100000000000000000000",
+		},
+		{
+			name:  "Print a double array",
+			input: TestCaseInput{Name: "values", Type: DoubleArrayType, Value: "[1.0, 2.5, 0.00001, -3.0, 1e20]"},
+			sourceCodes: map[string]string{
+				"go":         "func identity(values []float64) []float64 {\n\treturn values\n}",
+				"javascript": "function identity(values) {\n\treturn values;\n}",
+				"python":     "def identity(values):\n    return values",
+			},
+			expectedOutput: "[1,2.5,0.00001,-3,100000000000000000000]",
+		},
+	}
+
+	for _, testCase := range testCases {
+		for language, sourceCode := range testCase.sourceCodes {
+			t.Run(testCase.name+" in "+language, func(t *testing.T) {
+				t.Parallel()
+
+				submission, err := TemplateCreate(TemplateInput{
+					Language:     language,
+					FunctionName: "identity",
+					SourceCode:   sourceCode,
+					TestCase:     TestCase{Input: []TestCaseInput{testCase.input}},
+				})
+				if err != nil {
+					t.Fatalf("Failed to create template: %v", err)
+				}
+
+				if got := runTemplate(t, language, submission.SourceCode, submission.Stdin); got != testCase.expectedOutput {
+					t.Errorf("Expected output %s, got %s", testCase.expectedOutput, got)
+				}
+			})
+		}
+	}
+}
+
+// templateIdentityReturns return the parameter named value from the body of a starter code stub, C solutions
+// returning arrays set their sizes too
+var templateIdentityReturns = map[string]func(returnType TestCaseType) string{
+	"c": func(returnType TestCaseType) string {
+		switch templateCReturnKind(returnType) {
+		case templateCArray:
+			return "*returnSize = valueSize;\n    return value;"
+		case templateCMatrix:
+			return "*returnSize = valueSize;\n    *returnColumnSizes = valueColSize;\n    return value;"
+		}
+		return "return value;"
+	},
+	"cpp":        func(TestCaseType) string { return "return value;" },
+	"csharp":     func(TestCaseType) string { return "return value;" },
+	"go":         func(TestCaseType) string { return "return value" },
+	"java":       func(TestCaseType) string { return "return value;" },
+	"javascript": func(TestCaseType) string { return "return value;" },
+	"kotlin":     func(TestCaseType) string { return "return value" },
+	"python":     func(TestCaseType) string { return "return value" },
+	"ruby":       func(TestCaseType) string { return "value" },
+	"rust":       func(TestCaseType) string { return "value" },
+	"typescript": func(TestCaseType) string { return "return value;" },
+}
+
+// templateStubBody is the blank line of a starter code stub the body goes on
+var templateStubBody = regexp.MustCompile(`(?m)^[ \t]+$`)
+
+// templateIdentity is a solution of a language returning its only parameter, written in the starter code of a
+// signature taking and returning the type. It is empty when the language can't express the signature.
+func templateIdentity(language string, valueType TestCaseType) string {
+	signature := ProblemSignature{Parameters: []SignatureParameter{{Name: "value", Type: valueType}}, ReturnType: valueType}
+	stub := signature.Stub(language, "identity")
+	body := templateStubBody.FindStringIndex(stub)
+	if body == nil {
+		return ""
+	}
+	return stub[:body[1]] + templateIdentityReturns[language](valueType) + stub[body[1]:]
+}
+
+// Every language decodes the JSON arguments into its own types and prints its results as the same JSON, which is
+// what expected outputs and checkers compare with
+func TestTemplateIdentity(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          TestCaseInput
+		expectedOutput string
+	}{
+		{name: "int", input: TestCaseInput{Type: IntType, Value: "-42"}, expectedOutput: "-42"},
+		{name: "long", input: TestCaseInput{Type: LongType, Value: "3000000000"}, expectedOutput: "3000000000"},
+		{name: "float", input: TestCaseInput{Type: FloatType, Value: "1.5"}, expectedOutput: "1.5"},
+		{name: "double", input: TestCaseInput{Type: DoubleType, Value: "0.1"}, expectedOutput: "0.1"},
+		{name: "integral double", input: TestCaseInput{Type: DoubleType, Value: "-3.0"}, expectedOutput: "-3"},
+		{name: "large double", input: TestCaseInput{Type: DoubleType, Value: "1e20"}, expectedOutput: "100000000000000000000"},
+		{name: "small double", input: TestCaseInput{Type: DoubleType, Value: "0.00001"}, expectedOutput: "0.00001"},
+		{name: "string", input: TestCaseInput{Type: StringType, Value: `"say \"hi\"\n\tto \\ you"`}, expectedOutput: `"say \"hi\"\n\tto \\ you"`},
+		{name: "unicode string", input: TestCaseInput{Type: StringType, Value: `"héllo wörld"`}, expectedOutput: `"héllo wörld"`},
+		{name: "bare string", input: TestCaseInput{Type: StringType, Value: "abc"}, expectedOutput: `"abc"`},
+		{name: "char", input: TestCaseInput{Type: CharType, Value: "'a'"}, expectedOutput: `"a"`},
+		{name: "bool", input: TestCaseInput{Type: BoolType, Value: "true"}, expectedOutput: "true"},
+		{name: "int array", input: TestCaseInput{Type: IntArrayType, Value: "[1, -2, 3]"}, expectedOutput: "[1,-2,3]"},
+		{name: "empty int array", input: TestCaseInput{Type: IntArrayType, Value: "[]"}, expectedOutput: "[]"},
+		{name: "float array", input: TestCaseInput{Type: FloatArrayType, Value: "[0.5, -2.25]"}, expectedOutput: "[0.5,-2.25]"},
+		{name: "double array", input: TestCaseInput{Type: DoubleArrayType, Value: "[1.0, 2.5, 0.001]"}, expectedOutput: "[1,2.5,0.001]"},
+		{name: "string array", input: TestCaseInput{Type: StringArrayType, Value: `["a", "b c", ""]`}, expectedOutput: `["a","b c",""]`},
+		{name: "bool array", input: TestCaseInput{Type: BoolArrayType, Value: "[true, false]"}, expectedOutput: "[true,false]"},
+		{name: "int 2D array", input: TestCaseInput{Type: Int2DArrayType, Value: "[[1, 2], [3], []]"}, expectedOutput: "[[1,2],[3],[]]"},
+		{name: "string 2D array", input: TestCaseInput{Type: String2DArrayType, Value: `[["a", "b"], ["c"]]`}, expectedOutput: `[["a","b"],["c"]]`},
+		{name: "linked list", input: TestCaseInput{Type: ListNodeType, Value: "[1, 2, 3]"}, expectedOutput: "[1,2,3]"},
+		{name: "empty linked list", input: TestCaseInput{Type: ListNodeType, Value: "[]"}, expectedOutput: "null"},
+		{name: "binary tree", input: TestCaseInput{Type: TreeNodeType, Value: "[1, null, 2, 3]"}, expectedOutput: "[1,null,2,3]"},
+		{name: "empty binary tree", input: TestCaseInput{Type: TreeNodeType, Value: "[]"}, expectedOutput: "null"},
+		{name: "graph", input: TestCaseInput{Type: GraphType, Value: "[[1, 2], [0], [0]]"}, expectedOutput: "[[1,2],[0],[0]]"},
+		{name: "map", input: TestCaseInput{Type: MapType, Value: `{"a": 1, "b": -2}`}, expectedOutput: `{"a":1,"b":-2}`},
+		{name: "map printed in key order", input: TestCaseInput{Type: MapType, Value: `{"b": 1, "a": 2, "9": 3, "10": 4}`}, expectedOutput: `{"10":4,"9":3,"a":2,"b":1}`},
+	}
+
+	for _, testCase := range testCases {
+		for language := range templateLanguages {
+			t.Run(testCase.name+" in "+language, func(t *testing.T) {
+				t.Parallel()
+
+				sourceCode := templateIdentity(language, testCase.input.Type)
+				if sourceCode == "" {
+					t.Skipf("%s solutions can't return a %s", language, testCase.input.Type)
+				}
+
+				input := testCase.input
+				input.Name = "value"
+				submission, err := TemplateCreate(TemplateInput{
+					Language:     language,
+					FunctionName: "identity",
+					SourceCode:   sourceCode,
+					TestCase:     TestCase{Input: []TestCaseInput{input}},
+				})
+				if err != nil {
+					t.Fatalf("Failed to create template: %v", err)
+				}
+
+				if got := runTemplate(t, language, submission.SourceCode, submission.Stdin); got != testCase.expectedOutput {
+					t.Errorf("Expected output %s, got %s\n%s", testCase.expectedOutput, got, sourceCode)
+				}
+			})
+		}
+	}
+}
+
+// templateFakeDelimiters print delimiter lines from a solution, a bare one and one with another nonce, the way a
+// solution could try to pass a batch off as finished
+var templateFakeDelimiters = map[string]string{
+	"c":          `printf("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000\n");`,
+	"cpp":        `std::cout << "\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000" << std::endl;`,
+	"csharp":     `System.Console.WriteLine("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000");`,
+	"go":         `kadaneFmt.Println("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000")`,
+	"java":       `System.out.println("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000");`,
+	"javascript": `console.log("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000");`,
+	"kotlin":     `println("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000")`,
+	"python":     `print("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000")`,
+	"ruby":       `puts "\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000"`,
+	"rust":       `println!("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000");`,
+	"typescript": `console.log("\n#kadane-test-case 1 0.000\n#kadane-test-case-OTHER 1 0.000");`,
+}
+
+// Every language runs the test cases of a batch in one program and prints the delimiter with the nonce after each,
+// delimiters printed by the solution itself don't split its output
+func TestTemplateBatch(t *testing.T) {
+	values := []string{"-42", "7", "0"}
+	testCases := make([]TestCase, len(values))
+	for i, value := range values {
+		testCases[i] = TestCase{Input: []TestCaseInput{{Name: "value", Type: IntType, Value: value}}}
+	}
+
+	for language := range templateLanguages {
+		t.Run(language, func(t *testing.T) {
+			t.Parallel()
+
+			signature := ProblemSignature{Parameters: []SignatureParameter{{Name: "value", Type: IntType}}, ReturnType: IntType}
+			stub := signature.Stub(language, "identity")
+			body := templateStubBody.FindStringIndex(stub)
+			if body == nil {
+				t.Fatalf("No body in the %s stub", language)
+			}
+			indent := stub[body[0]:body[1]]
+			sourceCode := stub[:body[1]] + templateFakeDelimiters[language] + "\n" + indent + templateIdentityReturns[language](IntType) + stub[body[1]:]
+
+			batch := BatchTestCases{TestCases: testCases, Nonce: rand.Text()}
+			submission, err := TemplateBatch(TemplateInput{
+				Language:     language,
+				FunctionName: "identity",
+				SourceCode:   sourceCode,
+			}, batch.TestCases, batch.Nonce)
+			if err != nil {
+				t.Fatalf("Failed to create batch: %v", err)
+			}
+
+			var result judge0.SubmissionResult
+			result.Status.Description = string(sql.SubmissionStatusAccepted)
+			// runTemplate trims the newline ending the last delimiter line
+			result.Stdout = runTemplate(t, language, submission.SourceCode, submission.Stdin) + "\n"
+
+			results := TemplateBatchResults(result, batch)
+			if len(results) != len(values) {
+				t.Fatalf("Expected %d results, got %d\n%s", len(values), len(results), result.Stdout)
+			}
+			for i, testResult := range results {
+				if testResult.Status.Description != string(sql.SubmissionStatusAccepted) {
+					t.Errorf("Expected test case %d to be accepted, got %s\n%s", i, testResult.Status.Description, result.Stdout)
+				}
+				lines := strings.Split(strings.TrimSpace(testResult.Stdout), "\n")
+				if got := lines[len(lines)-1]; got != values[i] {
+					t.Errorf("Expected output %s of test case %d, got %q\n%s", values[i], i, testResult.Stdout, result.Stdout)
+				}
+			}
+		})
+	}
+}
+
+// templateCounters implement adminCounterDesign in every language
+var templateCounters = map[string]string{
+	"c": `typedef struct {
+    int value;
+} Counter;
+
+Counter* counterCreate(int start) {
+    Counter* counter = malloc(sizeof(Counter));
+    counter->value = start;
+    return counter;
+}
+
+void counterAdd(Counter* obj, int value) {
+    obj->value += value;
+}
+
+int counterGet(Counter* obj) {
+    return obj->value;
+}`,
+	"cpp": `class Counter {
+    int value;
+public:
+    Counter(int start) : value(start) {}
+    void add(int value) { this->value += value; }
+    int get() { return value; }
+};`,
+	"csharp": `public class Counter {
+    private int value;
+    public Counter(int start) { value = start; }
+    public void Add(int value) { this.value += value; }
+    public int Get() { return value; }
+}`,
+	"go": `type Counter struct {
+	value int
+}
+
+func Constructor(start int) Counter {
+	return Counter{value: start}
+}
+
+func (this *Counter) Add(value int) {
+	this.value += value
+}
+
+func (this *Counter) Get() int {
+	return this.value
+}`,
+	"java": `class Counter {
+    private int value;
+    public Counter(int start) { value = start; }
+    public void add(int value) { this.value += value; }
+    public int get() { return value; }
+}`,
+	"javascript": `class Counter {
+    constructor(start) { this.value = start; }
+    add(value) { this.value += value; }
+    get() { return this.value; }
+}`,
+	"kotlin": `class Counter(start: Int) {
+    private var value = start
+    fun add(value: Int) { this.value += value }
+    fun get(): Int = value
+}`,
+	"python": `class Counter:
+    def __init__(self, start):
+        self.value = start
+
+    def add(self, value):
+        self.value += value
+
+    def get(self):
+        return self.value`,
+	"ruby": `class Counter
+  def initialize(start)
+    @value = start
+  end
+
+  def add(value)
+    @value += value
+  end
+
+  def get
+    @value
+  end
+end`,
+	"rust": `struct Counter {
+    value: i32,
+}
+
+impl Counter {
+    fn new(start: i32) -> Self {
+        Counter { value: start }
+    }
+
+    fn add(&mut self, value: i32) {
+        self.value += value;
+    }
+
+    fn get(&self) -> i32 {
+        self.value
+    }
+}`,
+	"typescript": `class Counter {
+    value: number;
+    constructor(start: number) { this.value = start; }
+    add(value: number): void { this.value += value; }
+    get(): number { return this.value; }
+}`,
+}
+
+// Design test cases construct the class and print the result of every call, null for the constructor and
+// methods returning nothing
+func TestTemplateDesign(t *testing.T) {
+	testCase := TestCase{Calls: []DesignCall{
+		{Method: "Counter", Arguments: []json.RawMessage{json.RawMessage("1")}},
+		{Method: "get"},
+		{Method: "add", Arguments: []json.RawMessage{json.RawMessage("2")}},
+		{Method: "get"},
+	}}
+
+	for language := range templateLanguages {
+		t.Run(language, func(t *testing.T) {
+			t.Parallel()
+
+			submission, err := TemplateCreate(TemplateInput{
+				Language:     language,
+				FunctionName: "Counter",
+				SourceCode:   templateCounters[language],
+				TestCase:     testCase,
+				Design:       &adminCounterDesign,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create template: %v", err)
+			}
+
+			if got := runTemplate(t, language, submission.SourceCode, submission.Stdin); got != "[null,1,null,3]" {
+				t.Errorf("Expected output [null,1,null,3], got %s", got)
+			}
+		})
+	}
+}
@@ -22,6 +22,17 @@ const (
 	DoubleArrayType TestCaseType = "double[]"
 	StringArrayType TestCaseType = "string[]"
 	BoolArrayType   TestCaseType = "bool[]"
+
+	LongType          TestCaseType = "long"
+	CharType          TestCaseType = "char"
+	Int2DArrayType    TestCaseType = "int[][]"
+	String2DArrayType TestCaseType = "string[][]"
+	ListNodeType      TestCaseType = "ListNode" // linked list, written as the array of its values
+	TreeNodeType      TestCaseType = "TreeNode" // binary tree, written in level order with null for missing children
+	GraphType         TestCaseType = "graph"    // adjacency list, element i lists the neighbours of node i
+	MapType           TestCaseType = "map"      // object of string keys and int values
+
+	VoidType TestCaseType = "void" // return type of design methods returning nothing
 )
 
 type TestCaseInput struct {
@@ -35,6 +46,7 @@ type TestCase struct {
 	Input       []TestCaseInput `json:"input"`
 	Output      string          `json:"output"`
 	Visibility  sql.Visibility  `json:"visibility"`
+	Calls       []DesignCall    `json:"calls,omitempty"` // method calls of design problems, which have no inputs
 }
 
 type FriendshipStatus string
@@ -5,19 +5,27 @@ import (
 	"net/http"
 )
 
-// { data: { error: { statusCode: 400, message: "Bad Request" } } }
+// { data: { error: { statusCode: 400, message: "Bad Request", fields: [{ field: "title", message: "Title is required" }] } } }
 
 // ErrorDetails contains the detailed error information
 type APIErrorData struct {
 	Error struct {
-		StatusCode int    `json:"statusCode"`
-		Message    string `json:"message"`
+		StatusCode int          `json:"statusCode"`
+		Message    string       `json:"message"`
+		Fields     []FieldError `json:"fields,omitempty"`
 	} `json:"error"`
 }
 
 type APIErrorError struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
+	StatusCode int          `json:"statusCode"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is an invalid field of a request, Field is its path in the JSON body ie. testCases[1].visibility
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 type APIError struct {
@@ -36,6 +44,13 @@ func NewError(statusCode int, message string) *APIError {
 	}
 }
 
+// NewFieldsError creates an APIError listing the invalid fields, its message is the first field's
+func NewFieldsError(statusCode int, fields []FieldError) *APIError {
+	apiErr := NewError(statusCode, fields[0].Message)
+	apiErr.Data.Error.Fields = fields
+	return apiErr
+}
+
 // SendError sends an error response using the APIError structure
 func SendError(w http.ResponseWriter, statusCode int, message string) {
 	response := APIError{
@@ -59,3 +74,14 @@ func (e *APIError) StatusCode() int {
 func (e *APIError) Message() string {
 	return e.Data.Error.Message
 }
+
+func (e *APIError) Fields() []FieldError {
+	return e.Data.Error.Fields
+}
+
+// Send sends the error as a response, including its invalid fields
+func (e *APIError) Send(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.StatusCode())
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,298 @@
+// Package checker decides whether a program's output answers a test case.
+//
+// The built-in checkers compare the output with the expected output of the test case. The custom
+// checker runs a checker program written by the problem author through a judge0 executor.
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Mode selects how outputs are compared, it is stored per problem
+type Mode string
+
+// Every mode but exact compares JSON outputs in their canonical form, see Canonical.
+const (
+	ModeDefault   Mode = "default"   // spaces in arrays and newlines are ignored
+	ModeExact     Mode = "exact"     // outputs must match exactly, apart from trailing whitespace
+	ModeToken     Mode = "token"     // outputs must have the same tokens, whitespace is ignored
+	ModeFloat     Mode = "float"     // like token, numbers may differ by the epsilon
+	ModeUnordered Mode = "unordered" // the elements of the top level array may be in any order
+	ModeAny       Mode = "any"       // the expected output lists one valid answer per line
+	ModeCustom    Mode = "custom"    // a checker program decides
+)
+
+// DefaultEpsilon is the tolerance of the float checker when the problem sets none
+const DefaultEpsilon = 1e-6
+
+// Modes lists every mode
+var Modes = []Mode{ModeDefault, ModeExact, ModeToken, ModeFloat, ModeUnordered, ModeAny, ModeCustom}
+
+// Case is a single output to check
+type Case struct {
+	Input    []byte // test case inputs as JSON, passed on to custom checkers
+	Expected string // expected output of the test case
+	Output   string // output of the program
+}
+
+// Checker checks a batch of outputs and reports for each whether it is accepted
+type Checker interface {
+	Check(ctx context.Context, cases []Case) ([]bool, error)
+}
+
+// Func adapts a comparison of expected and actual output to a Checker
+type Func func(expected, output string) bool
+
+// Check compares every case
+func (f Func) Check(ctx context.Context, cases []Case) ([]bool, error) {
+	accepted := make([]bool, len(cases))
+	for i, c := range cases {
+		accepted[i] = f(c.Expected, c.Output)
+	}
+	return accepted, nil
+}
+
+// New returns the built-in checker of a mode. Custom checkers need an executor, see NewCustom.
+func New(mode Mode, epsilon float64) (Checker, error) {
+	if epsilon <= 0 {
+		epsilon = DefaultEpsilon
+	}
+
+	switch mode {
+	case ModeDefault, "":
+		return Func(Default), nil
+	case ModeExact:
+		return Func(Exact), nil
+	case ModeToken:
+		return Func(Token), nil
+	case ModeFloat:
+		return Func(func(expected, output string) bool {
+			return Float(expected, output, epsilon)
+		}), nil
+	case ModeUnordered:
+		return Func(Unordered), nil
+	case ModeAny:
+		return Func(Any), nil
+	case ModeCustom:
+		return nil, fmt.Errorf("custom checker needs a checker program")
+	}
+	return nil, fmt.Errorf("unknown checker mode %q", mode)
+}
+
+// Normalize removes spaces from array outputs and every newline
+func Normalize(output string) string {
+	result := output
+	// Remove spaces from array elements
+	if strings.Contains(result, "[") {
+		result = strings.ReplaceAll(result, " ", "")
+	}
+	// Remove newlines
+	return strings.ReplaceAll(result, "\n", "")
+}
+
+// Canonical re-encodes a JSON output compactly, with sorted object keys and numbers in their shortest
+// form, so 3.0 equals 3 and {"b":1,"a":2} equals {"a":2,"b":1}. It reports whether the output is JSON,
+// other outputs are returned trimmed.
+func Canonical(output string) (string, bool) {
+	trimmed := strings.TrimSpace(output)
+
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return trimmed, false
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return trimmed, false // more than one value
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(canonicalValue(value)); err != nil {
+		return trimmed, false
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), true
+}
+
+// canonicalValue rewrites the numbers of a decoded JSON value, integral numbers lose their fraction
+func canonicalValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		// Integer literals are kept, they may not fit a float
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if v == "-0" {
+				return json.Number("0")
+			}
+			return v
+		}
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return v
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+			return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	case []any:
+		for i := range v {
+			v[i] = canonicalValue(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = canonicalValue(v[key])
+		}
+	}
+	return value
+}
+
+// canonicalPair canonicalizes both outputs. Expected outputs written as bare strings, like hello
+// instead of "hello", are compared with the value of a JSON string output.
+func canonicalPair(expected, output string) (string, string) {
+	expected, expectedJSON := Canonical(expected)
+	output, outputJSON := Canonical(output)
+	if !expectedJSON && outputJSON {
+		var value string
+		if json.Unmarshal([]byte(output), &value) == nil {
+			output = value
+		}
+	}
+	return expected, output
+}
+
+// Default compares normalized outputs
+func Default(expected, output string) bool {
+	expected, output = canonicalPair(expected, output)
+	return Normalize(expected) == Normalize(output)
+}
+
+// Exact compares outputs with trailing whitespace removed
+func Exact(expected, output string) bool {
+	return strings.TrimRight(expected, " \t\r\n") == strings.TrimRight(output, " \t\r\n")
+}
+
+// Token compares the tokens of the outputs
+func Token(expected, output string) bool {
+	expected, output = canonicalPair(expected, output)
+	return slices.Equal(Tokens(expected), Tokens(output))
+}
+
+// Float compares the tokens of the outputs, numbers are equal if they differ by at most
+// epsilon, absolute for small numbers and relative for large ones
+func Float(expected, output string, epsilon float64) bool {
+	expected, output = canonicalPair(expected, output)
+	expectedTokens, outputTokens := Tokens(expected), Tokens(output)
+	return slices.EqualFunc(expectedTokens, outputTokens, func(e, o string) bool {
+		if e == o {
+			return true
+		}
+		a, errA := strconv.ParseFloat(e, 64)
+		b, errB := strconv.ParseFloat(o, 64)
+		if errA != nil || errB != nil || math.IsNaN(a) || math.IsNaN(b) {
+			return false
+		}
+		return math.Abs(a-b) <= epsilon*max(1, math.Abs(a))
+	})
+}
+
+// Unordered compares array outputs ignoring the order of their top level elements.
+// Nested arrays keep their order, so [[1,2],[3]] equals [[3],[1,2]] but not [[2,1],[3]].
+// Outputs that aren't arrays are compared by token.
+func Unordered(expected, output string) bool {
+	expected, output = canonicalPair(expected, output)
+	expectedElements, ok := elements(Tokens(expected))
+	if !ok {
+		return Token(expected, output)
+	}
+	outputElements, ok := elements(Tokens(output))
+	if !ok {
+		return false
+	}
+	slices.Sort(expectedElements)
+	slices.Sort(outputElements)
+	return slices.Equal(expectedElements, outputElements)
+}
+
+// Any accepts the output if it matches any line of the expected output by token
+func Any(expected, output string) bool {
+	for answer := range strings.Lines(expected) {
+		if strings.TrimSpace(answer) == "" {
+			continue
+		}
+		if Token(answer, output) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tokens splits an output at whitespace, brackets and commas are tokens of their own
+func Tokens(output string) []string {
+	var tokens []string
+	start := -1
+	for i, r := range output {
+		separator := r == ' ' || r == '\t' || r == '\r' || r == '\n'
+		punctuation := r == '[' || r == ']' || r == ','
+		if separator || punctuation {
+			if start >= 0 {
+				tokens = append(tokens, output[start:i])
+				start = -1
+			}
+			if punctuation {
+				tokens = append(tokens, string(r))
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, output[start:])
+	}
+	return tokens
+}
+
+// elements splits the tokens of an array into its top level elements, each joined back into a string
+func elements(tokens []string) ([]string, bool) {
+	if len(tokens) < 2 || tokens[0] != "[" || tokens[len(tokens)-1] != "]" {
+		return nil, false
+	}
+
+	var result []string
+	var element []string
+	depth := 0
+	for _, token := range tokens[1 : len(tokens)-1] {
+		switch token {
+		case "[":
+			depth++
+		case "]":
+			depth--
+			if depth < 0 {
+				return nil, false
+			}
+		case ",":
+			if depth == 0 {
+				result = append(result, strings.Join(element, " "))
+				element = nil
+				continue
+			}
+		}
+		element = append(element, token)
+	}
+	if depth != 0 {
+		return nil, false
+	}
+	if len(element) > 0 || len(result) > 0 {
+		result = append(result, strings.Join(element, " "))
+	}
+	return result, true
+}
@@ -0,0 +1,144 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/judge0/judge0test"
+)
+
+func TestCheckers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     Mode
+		epsilon  float64
+		expected string
+		output   string
+		accepted bool
+	}{
+		{name: "Default ignores spaces in arrays", mode: ModeDefault, expected: "[0,1]", output: "[0, 1]\n", accepted: true},
+		{name: "Default keeps spaces in strings", mode: ModeDefault, expected: "hello world", output: "helloworld", accepted: false},
+		{name: "Exact ignores trailing newline", mode: ModeExact, expected: "[0, 1]", output: "[0, 1]\n", accepted: true},
+		{name: "Exact keeps spaces", mode: ModeExact, expected: "[0,1]", output: "[0, 1]", accepted: false},
+		{name: "Token ignores whitespace", mode: ModeToken, expected: "1 2\n3", output: "1  2 3\n", accepted: true},
+		{name: "Token compares tokens", mode: ModeToken, expected: "[1,2]", output: "[12]", accepted: false},
+		{name: "Float within epsilon", mode: ModeFloat, expected: "[0.333333,1]", output: "[0.3333333333, 1.0]", accepted: true},
+		{name: "Float outside epsilon", mode: ModeFloat, expected: "0.5", output: "0.51", accepted: false},
+		{name: "Float with custom epsilon", mode: ModeFloat, epsilon: 0.1, expected: "0.5", output: "0.51", accepted: true},
+		{name: "Float relative for large numbers", mode: ModeFloat, expected: "100000000", output: "100000000.5", accepted: true},
+		{name: "Float compares other tokens exactly", mode: ModeFloat, expected: "[true, 1]", output: "[false, 1]", accepted: false},
+		{name: "Unordered top level", mode: ModeUnordered, expected: "[[1,2],[3]]", output: "[[3], [1, 2]]", accepted: true},
+		{name: "Unordered keeps nested order", mode: ModeUnordered, expected: "[[1,2],[3]]", output: "[[3],[2,1]]", accepted: false},
+		{name: "Unordered counts duplicates", mode: ModeUnordered, expected: "[1,1,2]", output: "[1,2,2]", accepted: false},
+		{name: "Unordered empty array", mode: ModeUnordered, expected: "[]", output: "[ ]", accepted: true},
+		{name: "Unordered rejects non array", mode: ModeUnordered, expected: "[1,2]", output: "1 2", accepted: false},
+		{name: "Unordered falls back to tokens", mode: ModeUnordered, expected: "42", output: "42\n", accepted: true},
+		{name: "Any matches a listed answer", mode: ModeAny, expected: "[0,1]\n[1,0]", output: "[1, 0]", accepted: true},
+		{name: "Any rejects other answers", mode: ModeAny, expected: "[0,1]\n[1,0]", output: "[1,1]", accepted: false},
+		{name: "Default compares integral floats as integers", mode: ModeDefault, expected: "[3,1]", output: "[3.0, 1.0]", accepted: true},
+		{name: "Default sorts object keys", mode: ModeDefault, expected: `{"a":1,"b":[2]}`, output: `{"b": [2], "a": 1}`, accepted: true},
+		{name: "Default matches bare strings with JSON strings", mode: ModeDefault, expected: "hello world", output: `"hello world"`, accepted: true},
+		{name: "Default keeps JSON string quotes", mode: ModeDefault, expected: `["a"]`, output: "[a]", accepted: false},
+		{name: "Token compares canonical numbers", mode: ModeToken, expected: "[2.50]", output: "[2.5]", accepted: true},
+		{name: "Unordered compares canonical elements", mode: ModeUnordered, expected: `[{"a":1,"b":2},[3]]`, output: `[[3.0], {"b":2,"a":1}]`, accepted: true},
+		{name: "Any compares canonical answers", mode: ModeAny, expected: "[0,1]\n[1,0]", output: "[1.0,0.0]", accepted: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			checker, err := New(testCase.mode, testCase.epsilon)
+			if err != nil {
+				t.Fatalf("Failed to create checker: %v", err)
+			}
+
+			accepted, err := checker.Check(context.Background(), []Case{{Expected: testCase.expected, Output: testCase.output}})
+			if err != nil {
+				t.Fatalf("Failed to check output: %v", err)
+			}
+			if accepted[0] != testCase.accepted {
+				t.Errorf("Expected accepted %v for %q against %q, got %v", testCase.accepted, testCase.output, testCase.expected, accepted[0])
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	testCases := []struct {
+		output    string
+		canonical string
+		json      bool
+	}{
+		{output: " [1, 2.0, -0.50, 1e3]\n", canonical: "[1,2,-0.5,1000]", json: true},
+		{output: `{"b": "<x>", "a": null}`, canonical: `{"a":null,"b":"<x>"}`, json: true},
+		{output: "123456789012345678901234567890", canonical: "123456789012345678901234567890", json: true},
+		{output: "1 2\n", canonical: "1 2", json: false},
+		{output: "[1, 2", canonical: "[1, 2", json: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.output, func(t *testing.T) {
+			t.Parallel()
+
+			canonical, isJSON := Canonical(testCase.output)
+			if canonical != testCase.canonical || isJSON != testCase.json {
+				t.Errorf("Expected %q (json %v), got %q (json %v)", testCase.canonical, testCase.json, canonical, isJSON)
+			}
+		})
+	}
+}
+
+func TestNewUnknownMode(t *testing.T) {
+	if _, err := New("fuzzy", 0); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+	if _, err := New(ModeCustom, 0); err == nil {
+		t.Error("Expected an error for a custom checker without a program")
+	}
+}
+
+func TestCustomChecker(t *testing.T) {
+	// The fake judge0 plays the checker program, accepting outputs that equal the expected output
+	server := judge0test.NewServer(func(submission judge0.Submission) judge0test.Result {
+		var input CustomInput
+		if err := json.Unmarshal([]byte(submission.Stdin), &input); err != nil {
+			return judge0test.Result{StatusID: judge0test.StatusRuntimeErrorNZEC}
+		}
+		if string(input.Input) != `[{"name":"n","value":"1"}]` {
+			return judge0test.Result{Stdout: "missing input"}
+		}
+		if input.Output == input.ExpectedOutput {
+			return judge0test.Result{Stdout: "true\n"}
+		}
+		return judge0test.Result{Stdout: "false\n"}
+	})
+	defer server.Close()
+
+	checker := NewCustom(server.Judge0Client(), 71, "print('true')")
+	input := []byte(`[{"name":"n","value":"1"}]`)
+
+	accepted, err := checker.Check(context.Background(), []Case{
+		{Input: input, Expected: "1", Output: "1"},
+		{Input: input, Expected: "1", Output: "2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to run checker: %v", err)
+	}
+	if !accepted[0] || accepted[1] {
+		t.Errorf("Expected [true false], got %v", accepted)
+	}
+
+	_, err = checker.Check(context.Background(), []Case{{Expected: "1", Output: "1"}})
+	if err == nil {
+		t.Error("Expected an error when the checker prints neither true nor false")
+	}
+
+	server.SetResponder(judge0test.Sequence(judge0test.Result{StatusID: judge0test.StatusCompilationError}))
+	_, err = checker.Check(context.Background(), []Case{{Input: input, Expected: "1", Output: "1"}})
+	if err == nil {
+		t.Error("Expected an error when the checker does not compile")
+	}
+}
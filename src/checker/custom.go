@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// CustomInput is what a custom checker program reads from stdin, as JSON
+type CustomInput struct {
+	Input          json.RawMessage `json:"input"`          // test case inputs
+	ExpectedOutput string          `json:"expectedOutput"` // expected output of the test case
+	Output         string          `json:"output"`         // output of the program being checked
+}
+
+// Custom runs a checker program for every case. The program reads a CustomInput from stdin
+// and prints true to accept the output or false to reject it.
+type Custom struct {
+	Executor   judge0.Executor
+	LanguageID int
+	SourceCode string
+}
+
+// NewCustom returns a checker that runs the given checker program through the executor
+func NewCustom(executor judge0.Executor, languageID int, sourceCode string) *Custom {
+	return &Custom{Executor: executor, LanguageID: languageID, SourceCode: sourceCode}
+}
+
+// Check runs the checker program once per case in a single batch
+func (c *Custom) Check(ctx context.Context, cases []Case) ([]bool, error) {
+	if len(cases) == 0 {
+		return nil, nil
+	}
+
+	submissions := make([]judge0.Submission, len(cases))
+	for i, testCase := range cases {
+		input := testCase.Input
+		if len(input) == 0 {
+			input = json.RawMessage("null")
+		}
+		stdin, err := json.Marshal(CustomInput{
+			Input:          input,
+			ExpectedOutput: testCase.Expected,
+			Output:         testCase.Output,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error encoding checker input: %w", err)
+		}
+
+		submissions[i] = judge0.Submission{
+			SourceCode: c.SourceCode,
+			LanguageID: c.LanguageID,
+			Stdin:      string(stdin),
+		}
+	}
+
+	results, err := c.Executor.CreateSubmissionBatchAndWaitContext(ctx, submissions)
+	if err != nil {
+		return nil, fmt.Errorf("error running checker: %w", err)
+	}
+
+	accepted := make([]bool, len(cases))
+	for i, result := range results {
+		if result.Status.Description != "Accepted" {
+			return nil, fmt.Errorf("checker failed on case %d: %s", i, result.Status.Description)
+		}
+		switch verdict := strings.TrimSpace(result.Stdout); verdict {
+		case "true":
+			accepted[i] = true
+		case "false":
+		default:
+			return nil, fmt.Errorf("checker printed %q on case %d, expected true or false", verdict, i)
+		}
+	}
+	return accepted, nil
+}
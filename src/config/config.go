@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -27,8 +29,22 @@ type Config struct {
 	AWSRegion       string
 	CloudFrontUrl   string
 	// Judge0
-	Judge0Url   string
-	Judge0Token string
+	Judge0Url            string
+	Judge0Token          string
+	Judge0AuthzToken     string // optional, allows deleting submissions of cancelled requests
+	Judge0CallbackUrl    string // optional, base url judge0 reaches this api on
+	Judge0CallbackSecret string
+	Judge0Timeout        time.Duration // per request
+	Judge0WaitTimeout    time.Duration // until a submission finishes
+	Judge0MaxRetries     int           // retries of idempotent requests
+	Judge0BatchTestCases bool          // run all test cases in one submission
+	Judge0MaxConcurrent  int           // executions sent to judge0 at once, ordered by plan beyond it
+	// Result cache
+	ResultCache     string        // memory, postgres or empty to run every submission
+	ResultCacheSize int           // results kept by the memory cache
+	ResultCacheTTL  time.Duration // how long the postgres cache keeps a result
+	// Quotas
+	Quotas bool // limit the executions of each account by its plan
 }
 
 // Fetch environment variables
@@ -111,6 +127,67 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("JUDGE0_TOKEN is not set")
 	}
 
+	judge0AuthzToken := os.Getenv("JUDGE0_AUTHZ_TOKEN")
+
+	// Judge0 callbacks are optional, submissions are polled without them
+	judge0CallbackUrl := os.Getenv("JUDGE0_CALLBACK_URL")
+	judge0CallbackSecret := os.Getenv("JUDGE0_CALLBACK_SECRET")
+	if judge0CallbackUrl != "" && judge0CallbackSecret == "" {
+		return nil, fmt.Errorf("JUDGE0_CALLBACK_SECRET is not set")
+	}
+
+	judge0Timeout, err := durationEnv("JUDGE0_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	judge0WaitTimeout, err := durationEnv("JUDGE0_WAIT_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	judge0MaxRetries := 2
+	if retries := os.Getenv("JUDGE0_MAX_RETRIES"); retries != "" {
+		judge0MaxRetries, err = strconv.Atoi(retries)
+		if err != nil || judge0MaxRetries < 0 {
+			return nil, fmt.Errorf("JUDGE0_MAX_RETRIES is not a valid number: %s", retries)
+		}
+	}
+
+	// Executions beyond the limit wait for judge0, higher plans first
+	judge0MaxConcurrent := 10
+	if concurrent := os.Getenv("JUDGE0_MAX_CONCURRENT"); concurrent != "" {
+		judge0MaxConcurrent, err = strconv.Atoi(concurrent)
+		if err != nil || judge0MaxConcurrent < 0 {
+			return nil, fmt.Errorf("JUDGE0_MAX_CONCURRENT is not a valid number: %s", concurrent)
+		}
+	}
+
+	// Batching trades per test case judge0 limits for compiling and starting solutions once
+	judge0BatchTestCases := os.Getenv("JUDGE0_BATCH_TEST_CASES") == "true"
+
+	// Results of identical submissions are served from the cache instead of judge0
+	resultCache := os.Getenv("RESULT_CACHE")
+	if resultCache != "" && resultCache != "memory" && resultCache != "postgres" {
+		return nil, fmt.Errorf("RESULT_CACHE must be memory or postgres: %s", resultCache)
+	}
+
+	resultCacheSize := 0
+	if size := os.Getenv("RESULT_CACHE_SIZE"); size != "" {
+		resultCacheSize, err = strconv.Atoi(size)
+		if err != nil || resultCacheSize <= 0 {
+			return nil, fmt.Errorf("RESULT_CACHE_SIZE is not a valid number: %s", size)
+		}
+	}
+
+	resultCacheTTL, err := durationEnv("RESULT_CACHE_TTL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plan quotas are on unless turned off, for example for self hosted instances
+	quotas := os.Getenv("QUOTAS") != "false"
+
 	// Return the configuration by fetching environment variables
 	config := &Config{
 		Debug: debug,
@@ -130,11 +207,38 @@ func LoadConfig() (*Config, error) {
 		AWSRegion:       awsRegion,
 		CloudFrontUrl:   cloudFrontUrl,
 		//Judge0
-		Judge0Url:   judge0Url,
-		Judge0Token: judge0Token,
+		Judge0Url:            judge0Url,
+		Judge0Token:          judge0Token,
+		Judge0AuthzToken:     judge0AuthzToken,
+		Judge0CallbackUrl:    judge0CallbackUrl,
+		Judge0CallbackSecret: judge0CallbackSecret,
+		Judge0Timeout:        judge0Timeout,
+		Judge0WaitTimeout:    judge0WaitTimeout,
+		Judge0MaxRetries:     judge0MaxRetries,
+		Judge0BatchTestCases: judge0BatchTestCases,
+		Judge0MaxConcurrent:  judge0MaxConcurrent,
+		//Result cache
+		ResultCache:     resultCache,
+		ResultCacheSize: resultCacheSize,
+		ResultCacheTTL:  resultCacheTTL,
+		//Quotas
+		Quotas: quotas,
 	}
 
 	log.Println("Configuration loaded")
 
 	return config, nil
 }
+
+// durationEnv parses an optional duration such as "10s", falling back to the default when unset
+func durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s is not a valid duration: %s", key, value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,229 @@
+package judge0
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchError reports the submissions of a batch that failed, keyed by their index in the batch
+type BatchError struct {
+	Total  int
+	Errors map[int]error
+}
+
+func newBatchError(total int) *BatchError {
+	return &BatchError{Total: total, Errors: make(map[int]error)}
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]string, 0, len(e.Errors))
+	for i := 0; i < e.Total; i++ {
+		if err, ok := e.Errors[i]; ok {
+			indexes = append(indexes, fmt.Sprintf("submission %d: %s", i, err.Error()))
+		}
+	}
+	return fmt.Sprintf("%d of %d submissions failed: %s", len(e.Errors), e.Total, strings.Join(indexes, "; "))
+}
+
+// Unwrap exposes the submission errors to errors.Is and errors.As, e.g. to detect context cancellation
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// set records the error of a single submission
+func (e *BatchError) set(index int, err error) {
+	e.Errors[index] = err
+}
+
+// errOrNil returns the batch error only if any submission failed
+func (e *BatchError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// failedResult is the result reported for a submission that never produced one
+func failedResult(err error) SubmissionResult {
+	var result SubmissionResult
+	result.Status.ID = 13
+	result.Status.Description = "Internal Error"
+	result.Message = err.Error()
+	return result
+}
+
+// CreateSubmissionBatchAndWaitContext runs every submission through judge0's batch endpoints and waits for all results.
+// Submissions are sent in chunks of MaxBatchSize, with at most maxBatchConcurrency chunks in flight.
+// Results always have one entry per submission. When some submissions fail the error is a *BatchError
+// and the failed entries carry an "Internal Error" status with the failure as message.
+// When ctx is done polling stops right away and pending submissions are deleted where possible.
+func (c *Judge0Client) CreateSubmissionBatchAndWaitContext(ctx context.Context, submissions []Submission) ([]SubmissionResult, error) {
+	results := make([]SubmissionResult, len(submissions))
+	batchErr := newBatchError(len(submissions))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	semaphore := make(chan struct{}, maxBatchConcurrency)
+
+	for start := 0; start < len(submissions); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(submissions))
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			chunkResults, chunkErrors := c.runBatch(ctx, submissions[start:end])
+
+			mu.Lock()
+			defer mu.Unlock()
+			for i, result := range chunkResults {
+				results[start+i] = result
+			}
+			for i, err := range chunkErrors {
+				batchErr.set(start+i, err)
+				results[start+i] = failedResult(err)
+			}
+		}(start, end)
+	}
+
+	wg.Wait()
+
+	return results, batchErr.errOrNil()
+}
+
+// runBatch creates a single chunk of submissions and polls their results together
+func (c *Judge0Client) runBatch(ctx context.Context, submissions []Submission) ([]SubmissionResult, map[int]error) {
+	results := make([]SubmissionResult, len(submissions))
+	failures := make(map[int]error)
+
+	if err := ctx.Err(); err != nil {
+		for i := range submissions {
+			failures[i] = err
+		}
+		return results, failures
+	}
+
+	encoded := make([]Submission, len(submissions))
+	for i, submission := range submissions {
+		encoded[i] = EncodeSubmissionInputs(submission)
+	}
+
+	batchResp, err := c.CreateSubmissionBatchContext(ctx, encoded)
+	var rejected *BatchError
+	if err != nil && !errors.As(err, &rejected) {
+		// Nothing was queued
+		for i := range submissions {
+			failures[i] = err
+		}
+		return results, failures
+	}
+
+	// Tokens still waiting on a result, by index in the chunk
+	pending := make(map[string]int)
+	for i, resp := range batchResp.Submissions {
+		if resp.Token == "" {
+			failures[i] = rejected.Errors[i]
+			continue
+		}
+		pending[resp.Token] = i
+	}
+
+	startTime := time.Now()
+	currentDelay := initialRetryDelay
+
+	for len(pending) > 0 {
+		if time.Since(startTime) > c.WaitTimeout {
+			for _, i := range pending {
+				failures[i] = fmt.Errorf("submission not finished after %v: %w", c.WaitTimeout, ErrTimeout)
+			}
+			break
+		}
+
+		tokens := make([]string, 0, len(pending))
+		for token := range pending {
+			tokens = append(tokens, token)
+		}
+
+		err := sleepContext(ctx, currentDelay)
+		if err == nil {
+			var batchResults []SubmissionResult
+			batchResults, err = c.GetSubmissionBatchContext(ctx, tokens)
+			for _, result := range batchResults {
+				i, ok := pending[result.Token]
+				if !ok || result.Status.ID < 3 {
+					continue
+				}
+				results[i] = result
+				delete(pending, result.Token)
+			}
+		}
+		if err != nil {
+			for _, i := range pending {
+				failures[i] = err
+			}
+			// The caller is gone, stop judge0 from keeping its submissions around
+			if ctx.Err() != nil {
+				c.deleteSubmissions(ctx, tokens)
+			}
+			break
+		}
+
+		// Smaller multiplication factor for gentler backoff
+		currentDelay = time.Duration(float64(currentDelay) * 1.5)
+		if currentDelay > maxRetryDelay {
+			currentDelay = maxRetryDelay
+		}
+	}
+
+	return results, failures
+}
+
+// GetSubmissionBatchContext fetches the results of several submissions with a single request.
+// Unknown tokens are left out of the results.
+func (c *Judge0Client) GetSubmissionBatchContext(ctx context.Context, tokens []string) ([]SubmissionResult, error) {
+	url := fmt.Sprintf("%s/submissions/batch?tokens=%s&base64_encoded=true&fields=*", c.BaseURL, strings.Join(tokens, ","))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Auth-Token", c.Token)
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Submissions []*SubmissionResult `json:"submissions"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	results := make([]SubmissionResult, 0, len(response.Submissions))
+	for _, result := range response.Submissions {
+		if result == nil {
+			continue
+		}
+		decoded, err := DecodeSubmissionResult(*result)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, decoded)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,54 @@
+package judge0
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrQueueFull is returned when judge0 refuses new work because its queue is full or requests are throttled
+	ErrQueueFull = errors.New("judge0 queue is full")
+	// ErrUnauthorized is returned when judge0 rejects the authentication or authorization token
+	ErrUnauthorized = errors.New("judge0 rejected the auth token")
+	// ErrTimeout is returned when judge0 does not answer or finish a submission in time
+	ErrTimeout = errors.New("judge0 timed out")
+	// ErrUnavailable is returned when judge0 cannot be reached or fails with a server error
+	ErrUnavailable = errors.New("judge0 is unavailable")
+	// ErrCircuitOpen is returned without contacting judge0 after repeated failures
+	ErrCircuitOpen = fmt.Errorf("circuit breaker open: %w", ErrUnavailable)
+)
+
+// StatusError is an unexpected judge0 response. It unwraps to the typed error matching its status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrQueueFull
+	case e.StatusCode == http.StatusServiceUnavailable && strings.Contains(e.Body, "queue is full"):
+		// Judge0 answers 503 once MAX_QUEUE_SIZE submissions are waiting
+		return ErrQueueFull
+	case e.StatusCode == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// transient reports whether retrying the same request may succeed
+func (e *StatusError) transient() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		(e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented)
+}
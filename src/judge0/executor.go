@@ -0,0 +1,26 @@
+package judge0
+
+import "context"
+
+// Executor is a code-execution backend that runs submissions and reports their results.
+// Judge0Client is the production implementation, alternative runners and test doubles
+// can be wired into the api handler by implementing this interface.
+// Every method stops waiting on the backend once its context is done.
+type Executor interface {
+	// CreateSubmissionContext queues a single submission without waiting for it to finish
+	CreateSubmissionContext(ctx context.Context, submission Submission) (*SubmissionResponse, error)
+	// CreateSubmissionBatchContext queues up to MaxBatchSize submissions without waiting for them to finish
+	CreateSubmissionBatchContext(ctx context.Context, submissions []Submission) (*SubmissionBatchResponse, error)
+	// CreateSubmissionBatchAndWaitContext runs every submission and blocks until all results are available,
+	// failures of single submissions are reported through a *BatchError
+	CreateSubmissionBatchAndWaitContext(ctx context.Context, submissions []Submission) ([]SubmissionResult, error)
+	// GetSubmissionContext fetches the result of a previously created submission
+	GetSubmissionContext(ctx context.Context, token string) (*SubmissionResult, error)
+	// GetSubmissionBatchContext fetches the results of up to MaxBatchSize previously created submissions
+	GetSubmissionBatchContext(ctx context.Context, tokens []string) ([]SubmissionResult, error)
+	// GetLanguagesContext lists the languages supported by the backend
+	GetLanguagesContext(ctx context.Context) ([]Language, error)
+}
+
+// Ensure Judge0Client satisfies the Executor interface
+var _ Executor = (*Judge0Client)(nil)
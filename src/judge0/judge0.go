@@ -2,28 +2,38 @@ package judge0
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
-	"sync"
 	"time"
 
 	"kadane.xyz/go-backend/v2/src/config"
 )
 
 type Judge0Client struct {
-	BaseURL string
-	Token   string
-	Client  *http.Client
+	BaseURL     string
+	Token       string
+	AuthzToken  string // optional, required by judge0 to delete submissions
+	Client      *http.Client
+	MaxRetries  int           // retries of failed GET requests
+	WaitTimeout time.Duration // how long to wait for submissions to finish
+
+	breaker *circuitBreaker
 }
 
 type Submission struct {
-	SourceCode           string `json:"source_code"` // plain string that will be base64 encoded
-	LanguageID           int    `json:"language_id"`
-	CompilerOptions      string `json:"compiler_options"`
-	CommandLineArguments string `json:"command_line_arguments"`
-	Stdin                string `json:"stdin"` // plain string that will be base64 encoded
+	SourceCode           string  `json:"source_code"` // plain string that will be base64 encoded
+	LanguageID           int     `json:"language_id"`
+	CompilerOptions      string  `json:"compiler_options"`
+	CommandLineArguments string  `json:"command_line_arguments"`
+	Stdin                string  `json:"stdin"`                     // plain string that will be base64 encoded
+	CallbackURL          string  `json:"callback_url,omitempty"`    // judge0 sends a PUT with the result to this url once finished
+	CPUTimeLimit         float64 `json:"cpu_time_limit,omitempty"`  // seconds, judge0's default when zero
+	WallTimeLimit        float64 `json:"wall_time_limit,omitempty"` // seconds, judge0's default when zero
+	MemoryLimit          int     `json:"memory_limit,omitempty"`    // kilobytes, judge0's default when zero
+	StackLimit           int     `json:"stack_limit,omitempty"`     // kilobytes, judge0's default when zero
 	//ExpectedOutput       string `json:"expected_output"` // plain string that will be base64 encoded
 }
 
@@ -79,57 +89,64 @@ type SubmissionBatchResponse struct {
 const (
 	initialRetryDelay = 50 * time.Millisecond
 	maxRetryDelay     = 500 * time.Millisecond
-	maxWaitTime       = 30 * time.Second
+	defaultWaitTime   = 30 * time.Second
+	deleteTimeout     = 5 * time.Second
 )
 
-func NewJudge0Client(cfg *config.Config) *Judge0Client {
-	return &Judge0Client{
-		BaseURL: cfg.Judge0Url,
-		Token:   cfg.Judge0Token,
-		Client:  &http.Client{},
-	}
-}
+// Upper bounds judge0 accepts for submission limits (MAX_CPU_TIME_LIMIT etc. of a stock install)
+const (
+	MaxCPUTimeLimit  = 15.0   // seconds
+	MaxWallTimeLimit = 20.0   // seconds
+	MaxMemoryLimit   = 512000 // kilobytes
+	MaxStackLimit    = 128000 // kilobytes
+)
 
-func (c *Judge0Client) CreateSubmissionBatchAndWait(submissions []Submission) ([]SubmissionResult, error) {
-	var wg sync.WaitGroup
-	results := make([]SubmissionResult, len(submissions))
-	errors := make(chan error, len(submissions))
-
-	for i, submission := range submissions {
-		wg.Add(1)
-		go func(i int, submission Submission) {
-			defer wg.Done()
-			resp, err := c.CreateSubmissionAndWait(submission)
-			if err != nil {
-				errors <- fmt.Errorf("submission %d error: %s", i, err.Error())
-				return
-			}
-			results[i] = *resp
-		}(i, submission)
-	}
+// Longest compiler options and command line arguments judge0 accepts
+const (
+	MaxCompilerOptionsLength      = 512
+	MaxCommandLineArgumentsLength = 512
+)
 
-	wg.Wait()
-	close(errors)
+// MaxBatchSize is the most submissions judge0 accepts per batch request (MAX_SUBMISSION_BATCH_SIZE)
+const MaxBatchSize = 20
 
-	if len(errors) > 0 {
-		return nil, fmt.Errorf("one or more submissions failed")
-	}
+// maxBatchConcurrency bounds the batches of a single CreateSubmissionBatchAndWait call in flight
+const maxBatchConcurrency = 4
 
-	return results, nil
+func NewJudge0Client(cfg *config.Config) *Judge0Client {
+	timeout := cfg.Judge0Timeout
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+	waitTimeout := cfg.Judge0WaitTimeout
+	if waitTimeout == 0 {
+		waitTimeout = defaultWaitTime
+	}
+	return &Judge0Client{
+		BaseURL:     cfg.Judge0Url,
+		Token:       cfg.Judge0Token,
+		AuthzToken:  cfg.Judge0AuthzToken,
+		Client:      &http.Client{Timeout: timeout},
+		MaxRetries:  cfg.Judge0MaxRetries,
+		WaitTimeout: waitTimeout,
+		breaker:     newCircuitBreaker(breakerThreshold, breakerCooldown),
+	}
 }
 
-func (c *Judge0Client) CreateSubmissionAndWait(submission Submission) (*SubmissionResult, error) {
+// CreateSubmissionAndWaitContext runs a single submission and waits for its result.
+// When ctx is done polling stops right away and the submission is deleted where possible.
+func (c *Judge0Client) CreateSubmissionAndWaitContext(ctx context.Context, submission Submission) (*SubmissionResult, error) {
 	// First base64 encode submission
 	submission = EncodeSubmissionInputs(submission)
 	// First create the submission without waiting
 
-	resp, err := c.CreateSubmission(submission)
+	resp, err := c.CreateSubmissionContext(ctx, submission)
 	if err != nil {
 		return nil, err
 	}
 
 	// Quick first check after submission
-	result, err := c.GetSubmission(resp.Token)
+	result, err := c.GetSubmissionContext(ctx, resp.Token)
 	if err == nil && result.Status.ID >= 3 {
 		return result, nil
 	}
@@ -139,13 +156,20 @@ func (c *Judge0Client) CreateSubmissionAndWait(submission Submission) (*Submissi
 	currentDelay := initialRetryDelay
 
 	for {
-		if time.Since(startTime) > maxWaitTime {
-			return nil, fmt.Errorf("submission timed out after %v", maxWaitTime)
+		if time.Since(startTime) > c.WaitTimeout {
+			return nil, fmt.Errorf("submission not finished after %v: %w", c.WaitTimeout, ErrTimeout)
 		}
 
-		time.Sleep(currentDelay)
-		result, err := c.GetSubmission(resp.Token)
+		if err := sleepContext(ctx, currentDelay); err != nil {
+			c.deleteSubmissions(ctx, []string{resp.Token})
+			return nil, err
+		}
+
+		result, err := c.GetSubmissionContext(ctx, resp.Token)
 		if err != nil {
+			if ctx.Err() != nil {
+				c.deleteSubmissions(ctx, []string{resp.Token})
+			}
 			return nil, err
 		}
 
@@ -161,7 +185,7 @@ func (c *Judge0Client) CreateSubmissionAndWait(submission Submission) (*Submissi
 	}
 }
 
-func (c *Judge0Client) CreateSubmission(submission Submission) (*SubmissionResponse, error) {
+func (c *Judge0Client) CreateSubmissionContext(ctx context.Context, submission Submission) (*SubmissionResponse, error) {
 	url := fmt.Sprintf("%s/submissions?base64_encoded=true", c.BaseURL)
 
 	jsonData, err := json.Marshal(submission)
@@ -169,26 +193,16 @@ func (c *Judge0Client) CreateSubmission(submission Submission) (*SubmissionRespo
 		return nil, fmt.Errorf("error marshaling submission: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Auth-Token", c.Token)
 
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	var submissionResp SubmissionResponse
@@ -200,7 +214,9 @@ func (c *Judge0Client) CreateSubmission(submission Submission) (*SubmissionRespo
 	return &submissionResp, nil
 }
 
-func (c *Judge0Client) CreateSubmissionBatch(submissions []Submission) (*SubmissionBatchResponse, error) {
+// CreateSubmissionBatchContext queues already base64 encoded submissions with a single request.
+// Submissions rejected by judge0 have no token and are reported through a *BatchError.
+func (c *Judge0Client) CreateSubmissionBatchContext(ctx context.Context, submissions []Submission) (*SubmissionBatchResponse, error) {
 	url := fmt.Sprintf("%s/submissions/batch?base64_encoded=true", c.BaseURL)
 
 	submissionBatch := SubmissionBatch{
@@ -212,61 +228,56 @@ func (c *Judge0Client) CreateSubmissionBatch(submissions []Submission) (*Submiss
 		return nil, fmt.Errorf("error marshaling submission batch: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Auth-Token", c.Token)
 
-	resp, err := c.Client.Do(req)
+	body, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+	// Judge0 answers each submission with either a token or its validation errors
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
 	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	if len(items) != len(submissions) {
+		return nil, fmt.Errorf("expected %d submission tokens, got %d", len(submissions), len(items))
 	}
 
-	var submissionResponses []SubmissionResponse
-	if err := json.Unmarshal(body, &submissionResponses); err != nil {
-		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	batchResponse := &SubmissionBatchResponse{
+		Submissions: make([]SubmissionResponse, len(items)),
+	}
+	batchErr := newBatchError(len(items))
+	for i, item := range items {
+		var submissionResp SubmissionResponse
+		if err := json.Unmarshal(item, &submissionResp); err != nil || submissionResp.Token == "" {
+			batchErr.set(i, fmt.Errorf("submission rejected: %s", string(item)))
+			continue
+		}
+		batchResponse.Submissions[i] = submissionResp
 	}
 
-	return &SubmissionBatchResponse{
-		Submissions: submissionResponses,
-	}, nil
+	return batchResponse, batchErr.errOrNil()
 }
 
-func (c *Judge0Client) GetSubmission(token string) (*SubmissionResult, error) {
+func (c *Judge0Client) GetSubmissionContext(ctx context.Context, token string) (*SubmissionResult, error) {
 	// return as base64 encoded string with fields *
 	url := fmt.Sprintf("%s/submissions/%s?base64_encoded=true&fields=*", c.BaseURL, token)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("X-Auth-Token", c.Token)
 
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	var result SubmissionResult
@@ -275,43 +286,24 @@ func (c *Judge0Client) GetSubmission(token string) (*SubmissionResult, error) {
 		return nil, fmt.Errorf("error unmarshaling response: %w", err)
 	}
 
-	resultDecoded := result
-	resultDecoded.Stdout, err = DecodeBase64(result.Stdout)
-	if err != nil {
-		return nil, fmt.Errorf("error decoding stdout: %w", err)
-	}
-	resultDecoded.Stderr, err = DecodeBase64(result.Stderr)
+	resultDecoded, err := DecodeSubmissionResult(result)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding stderr: %w", err)
-	}
-	resultDecoded.CompileOutput, err = DecodeBase64(result.CompileOutput)
-	if err != nil {
-		return nil, fmt.Errorf("error decoding compile output: %w", err)
-	}
-	resultDecoded.Message, err = DecodeBase64(result.Message)
-	if err != nil {
-		return nil, fmt.Errorf("error decoding message: %w", err)
+		return nil, err
 	}
 
 	return &resultDecoded, nil
 }
 
-func (c *Judge0Client) GetSubmissions() (*SubmissionsResponse, error) {
+func (c *Judge0Client) GetSubmissionsContext(ctx context.Context) (*SubmissionsResponse, error) {
 	url := fmt.Sprintf("%s/submissions?base64_encoded=false&fields=*", c.BaseURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("X-Auth-Token", c.Token)
 
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -325,30 +317,112 @@ func (c *Judge0Client) GetSubmissions() (*SubmissionsResponse, error) {
 	return &response, nil
 }
 
-func (c *Judge0Client) GetLanguages() ([]map[string]interface{}, error) {
+func (c *Judge0Client) GetLanguagesContext(ctx context.Context) ([]Language, error) {
 	url := fmt.Sprintf("%s/languages", c.BaseURL)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("X-Auth-Token", c.Token)
 
-	resp, err := c.Client.Do(req)
+	body, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	var languages []Language
+	err = json.Unmarshal(body, &languages)
 	if err != nil {
 		return nil, err
 	}
 
-	var languages []map[string]interface{}
-	err = json.Unmarshal(body, &languages)
+	return languages, nil
+}
+
+// DeleteSubmissionContext deletes a submission from judge0. Judge0 only allows this with an
+// authorization token and only for submissions that are no longer queued or processing.
+func (c *Judge0Client) DeleteSubmissionContext(ctx context.Context, token string) error {
+	if c.AuthzToken == "" {
+		return fmt.Errorf("deleting submissions requires a judge0 authorization token")
+	}
+
+	url := fmt.Sprintf("%s/submissions/%s?fields=token", c.BaseURL, token)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req.Header.Set("X-Auth-Token", c.Token)
+	req.Header.Set("X-Auth-User", c.AuthzToken)
 
-	return languages, nil
+	_, err = c.do(req)
+	return err
+}
+
+// deleteSubmissions cleans up submissions abandoned by a cancelled caller. It runs on a
+// fresh context because ctx is usually done already, and is best effort.
+func (c *Judge0Client) deleteSubmissions(ctx context.Context, tokens []string) {
+	if c.AuthzToken == "" || len(tokens) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deleteTimeout)
+	defer cancel()
+
+	for _, token := range tokens {
+		if err := c.DeleteSubmissionContext(ctx, token); err != nil {
+			log.Printf("Failed to delete judge0 submission %s: %v", token, err)
+		}
+	}
+}
+
+// sleepContext waits for the delay or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Variants without a context run on context.Background() and are never cancelled
+
+func (c *Judge0Client) CreateSubmissionAndWait(submission Submission) (*SubmissionResult, error) {
+	return c.CreateSubmissionAndWaitContext(context.Background(), submission)
+}
+
+func (c *Judge0Client) CreateSubmission(submission Submission) (*SubmissionResponse, error) {
+	return c.CreateSubmissionContext(context.Background(), submission)
+}
+
+func (c *Judge0Client) CreateSubmissionBatch(submissions []Submission) (*SubmissionBatchResponse, error) {
+	return c.CreateSubmissionBatchContext(context.Background(), submissions)
+}
+
+func (c *Judge0Client) CreateSubmissionBatchAndWait(submissions []Submission) ([]SubmissionResult, error) {
+	return c.CreateSubmissionBatchAndWaitContext(context.Background(), submissions)
+}
+
+func (c *Judge0Client) GetSubmission(token string) (*SubmissionResult, error) {
+	return c.GetSubmissionContext(context.Background(), token)
+}
+
+func (c *Judge0Client) GetSubmissionBatch(tokens []string) ([]SubmissionResult, error) {
+	return c.GetSubmissionBatchContext(context.Background(), tokens)
+}
+
+func (c *Judge0Client) GetSubmissions() (*SubmissionsResponse, error) {
+	return c.GetSubmissionsContext(context.Background())
+}
+
+func (c *Judge0Client) GetLanguages() ([]Language, error) {
+	return c.GetLanguagesContext(context.Background())
+}
+
+func (c *Judge0Client) DeleteSubmission(token string) error {
+	return c.DeleteSubmissionContext(context.Background(), token)
 }
@@ -0,0 +1,573 @@
+// Package judge0test provides an in-process fake of the Judge0 HTTP API for hermetic tests.
+//
+// The fake never executes code. Every submission is answered by a Responder which decides the
+// status, output and how long the submission stays queued before it finishes.
+package judge0test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/config"
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// Judge0 status ids
+const (
+	StatusInQueue             = 1
+	StatusProcessing          = 2
+	StatusAccepted            = 3
+	StatusWrongAnswer         = 4
+	StatusTimeLimitExceeded   = 5
+	StatusCompilationError    = 6
+	StatusRuntimeErrorSIGSEGV = 7
+	StatusRuntimeErrorSIGXFSZ = 8
+	StatusRuntimeErrorSIGFPE  = 9
+	StatusRuntimeErrorSIGABRT = 10
+	StatusRuntimeErrorNZEC    = 11
+	StatusRuntimeErrorOther   = 12
+	StatusInternalError       = 13
+	StatusExecFormatError     = 14
+)
+
+var statusDescriptions = map[int]string{
+	StatusInQueue:             "In Queue",
+	StatusProcessing:          "Processing",
+	StatusAccepted:            "Accepted",
+	StatusWrongAnswer:         "Wrong Answer",
+	StatusTimeLimitExceeded:   "Time Limit Exceeded",
+	StatusCompilationError:    "Compilation Error",
+	StatusRuntimeErrorSIGSEGV: "Runtime Error (SIGSEGV)",
+	StatusRuntimeErrorSIGXFSZ: "Runtime Error (SIGXFSZ)",
+	StatusRuntimeErrorSIGFPE:  "Runtime Error (SIGFPE)",
+	StatusRuntimeErrorSIGABRT: "Runtime Error (SIGABRT)",
+	StatusRuntimeErrorNZEC:    "Runtime Error (NZEC)",
+	StatusRuntimeErrorOther:   "Runtime Error (Other)",
+	StatusInternalError:       "Internal Error",
+	StatusExecFormatError:     "Exec Format Error",
+}
+
+// StatusDescription returns the Judge0 description for a status id
+func StatusDescription(id int) string {
+	return statusDescriptions[id]
+}
+
+// Result is the scripted outcome of a single submission
+type Result struct {
+	StatusID      int           // final status, defaults to Accepted
+	Stdout        string        // plain text, base64 encoded on the wire when requested
+	Stderr        string        // plain text, base64 encoded on the wire when requested
+	CompileOutput string        // plain text, base64 encoded on the wire when requested
+	Message       string        // plain text, base64 encoded on the wire when requested
+	ExitCode      int           // process exit code
+	Time          string        // cpu time in seconds, defaults to "0.001"
+	Memory        int           // memory in KB, defaults to 1024
+	Delay         time.Duration // how long the submission reports Processing before finishing
+	Reject        string        // validation error returned instead of a token when set
+}
+
+// Responder scripts the result of a submission. The submission source code and stdin are
+// always decoded before the responder is called.
+type Responder func(submission judge0.Submission) Result
+
+// Accept returns a responder that accepts every submission with the given stdout
+func Accept(stdout string) Responder {
+	return func(judge0.Submission) Result {
+		return Result{StatusID: StatusAccepted, Stdout: stdout}
+	}
+}
+
+// Sequence returns a responder that answers submissions with the given results in order.
+// Once the results are exhausted the last one is repeated.
+func Sequence(results ...Result) Responder {
+	var mu sync.Mutex
+	next := 0
+	return func(judge0.Submission) Result {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(results) == 0 {
+			return Result{}
+		}
+		result := results[min(next, len(results)-1)]
+		next++
+		return result
+	}
+}
+
+// Language is an entry of GET /languages
+type Language = judge0.Language
+
+// DefaultLanguages mirrors the languages of a stock Judge0 1.13 install that kadane uses
+var DefaultLanguages = []Language{
+	{ID: 54, Name: "C++ (GCC 9.2.0)"},
+	{ID: 60, Name: "Go (1.13.5)"},
+	{ID: 62, Name: "Java (OpenJDK 13.0.1)"},
+	{ID: 63, Name: "JavaScript (Node.js 12.14.0)"},
+	{ID: 71, Name: "Python (3.8.1)"},
+	{ID: 74, Name: "TypeScript (3.7.4)"},
+	{ID: 73, Name: "Rust (1.40.0)"},
+	{ID: 50, Name: "C (GCC 9.2.0)"},
+	{ID: 51, Name: "C# (Mono 6.6.0.161)"},
+	{ID: 78, Name: "Kotlin (1.3.70)"},
+	{ID: 72, Name: "Ruby (2.7.0)"},
+}
+
+type entry struct {
+	token      string
+	submission judge0.Submission
+	result     Result
+	createdAt  time.Time
+}
+
+// Server is a fake Judge0 instance backed by an httptest.Server
+type Server struct {
+	*httptest.Server
+
+	// AuthToken, when set, must be sent in the X-Auth-Token header of every request
+	AuthToken string
+	// AuthzToken, when set, enables DELETE /submissions/{token} for requests with a matching X-Auth-User header
+	AuthzToken string
+	// Languages is served by GET /languages
+	Languages []Language
+
+	mu          sync.Mutex
+	responder   Responder
+	entries     map[string]*entry
+	submissions []judge0.Submission
+	deleted     []string
+	nextToken   int
+	requests    int
+	failures    int
+	failStatus  int
+	latency     time.Duration
+}
+
+// NewServer starts a fake Judge0 server answering every submission with the responder.
+// A nil responder accepts every submission with empty output. The caller must call Close.
+func NewServer(responder Responder) *Server {
+	s := &Server{
+		Languages: DefaultLanguages,
+		responder: responder,
+		entries:   make(map[string]*entry),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /submissions", s.handleCreateSubmission)
+	mux.HandleFunc("POST /submissions/batch", s.handleCreateSubmissionBatch)
+	mux.HandleFunc("GET /submissions", s.handleGetSubmissions)
+	mux.HandleFunc("GET /submissions/batch", s.handleGetSubmissionBatch)
+	mux.HandleFunc("GET /submissions/{token}", s.handleGetSubmission)
+	mux.HandleFunc("DELETE /submissions/{token}", s.handleDeleteSubmission)
+	mux.HandleFunc("GET /languages", s.handleGetLanguages)
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// Config returns a configuration pointing a judge0 client at the fake server
+func (s *Server) Config() *config.Config {
+	return &config.Config{
+		Judge0Url:        s.URL,
+		Judge0Token:      s.AuthToken,
+		Judge0AuthzToken: s.AuthzToken,
+	}
+}
+
+// Judge0Client returns a client connected to the fake server
+func (s *Server) Judge0Client() *judge0.Judge0Client {
+	return judge0.NewJudge0Client(s.Config())
+}
+
+// SetResponder replaces the responder used for new submissions
+func (s *Server) SetResponder(responder Responder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = responder
+}
+
+// Submissions returns every submission received so far with decoded source code and stdin
+func (s *Server) Submissions() []judge0.Submission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]judge0.Submission(nil), s.submissions...)
+}
+
+// Deleted returns the tokens of every submission deleted so far
+func (s *Server) Deleted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.deleted...)
+}
+
+// FailRequests makes the next count requests fail with the status code. A 503 reports a full queue like judge0 does.
+func (s *Server) FailRequests(statusCode, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failStatus = statusCode
+	s.failures = count
+}
+
+// SetLatency delays every response, e.g. to trigger client timeouts
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// Requests returns how many requests reached the server
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Reset forgets every received and deleted submission
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]*entry)
+	s.submissions = nil
+	s.deleted = nil
+}
+
+// intercept counts requests, injects latency and failures and checks the auth token before routing
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		latency := s.latency
+		failStatus := 0
+		if s.failures > 0 {
+			s.failures--
+			failStatus = s.failStatus
+		}
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if failStatus == http.StatusServiceUnavailable {
+			writeJSON(w, failStatus, map[string]string{"error": "queue is full"})
+			return
+		}
+		if failStatus != 0 {
+			writeJSON(w, failStatus, map[string]string{"error": http.StatusText(failStatus)})
+			return
+		}
+
+		if s.AuthToken != "" && r.Header.Get("X-Auth-Token") != s.AuthToken {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication failed."})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// submit decodes and records a submission, returning its token or the validation error judge0 would report
+func (s *Server) submit(submission judge0.Submission, base64Encoded bool) (string, error) {
+	if base64Encoded {
+		var err error
+		if submission.SourceCode, err = judge0.DecodeBase64(submission.SourceCode); err != nil {
+			return "", fmt.Errorf("source_code is not valid base64")
+		}
+		if submission.Stdin, err = judge0.DecodeBase64(submission.Stdin); err != nil {
+			return "", fmt.Errorf("stdin is not valid base64")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := Result{StatusID: StatusAccepted}
+	if s.responder != nil {
+		result = s.responder(submission)
+	}
+
+	s.submissions = append(s.submissions, submission)
+	if result.Reject != "" {
+		return "", fmt.Errorf("%s", result.Reject)
+	}
+
+	s.nextToken++
+	token := fmt.Sprintf("fake-token-%d", s.nextToken)
+	s.entries[token] = &entry{
+		token:      token,
+		submission: submission,
+		result:     result,
+		createdAt:  time.Now(),
+	}
+
+	if submission.CallbackURL != "" {
+		go s.callback(s.entries[token], base64Encoded)
+	}
+
+	return token, nil
+}
+
+// callback sends the finished result to the submission callback url like judge0 does
+func (s *Server) callback(e *entry, base64Encoded bool) {
+	time.Sleep(e.result.Delay)
+
+	result := s.render(e, base64Encoded)
+	body, err := json.Marshal(callbackResult{
+		Stdout:        result.Stdout,
+		Time:          result.Time,
+		Memory:        result.Memory,
+		Stderr:        result.Stderr,
+		Token:         result.Token,
+		CompileOutput: result.CompileOutput,
+		Message:       result.Message,
+		Status:        result.Status,
+	})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPut, e.submission.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *Server) handleCreateSubmission(w http.ResponseWriter, r *http.Request) {
+	var submission judge0.Submission
+	if err := decodeBody(r, &submission); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	token, err := s.submit(submission, isBase64(r))
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string][]string{"source_code": {err.Error()}})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, judge0.SubmissionResponse{Token: token})
+}
+
+func (s *Server) handleCreateSubmissionBatch(w http.ResponseWriter, r *http.Request) {
+	var batch judge0.SubmissionBatch
+	if err := decodeBody(r, &batch); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	responses := make([]any, 0, len(batch.Submissions))
+	for _, submission := range batch.Submissions {
+		token, err := s.submit(submission, isBase64(r))
+		if err != nil {
+			// Judge0 reports per-submission validation errors inline
+			responses = append(responses, map[string][]string{"source_code": {err.Error()}})
+			continue
+		}
+		responses = append(responses, judge0.SubmissionResponse{Token: token})
+	}
+
+	writeJSON(w, http.StatusCreated, responses)
+}
+
+func (s *Server) handleGetSubmission(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	e, ok := s.entries[r.PathValue("token")]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found."})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.render(e, isBase64(r)))
+}
+
+func (s *Server) handleDeleteSubmission(w http.ResponseWriter, r *http.Request) {
+	if s.AuthzToken == "" || r.Header.Get("X-Auth-User") != s.AuthzToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authorization failed."})
+		return
+	}
+
+	token := r.PathValue("token")
+	s.mu.Lock()
+	e, ok := s.entries[token]
+	if ok {
+		delete(s.entries, token)
+		s.deleted = append(s.deleted, token)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found."})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.render(e, isBase64(r)))
+}
+
+func (s *Server) handleGetSubmissionBatch(w http.ResponseWriter, r *http.Request) {
+	tokens := strings.Split(r.URL.Query().Get("tokens"), ",")
+
+	s.mu.Lock()
+	results := make([]any, 0, len(tokens))
+	for _, token := range tokens {
+		e, ok := s.entries[strings.TrimSpace(token)]
+		if !ok {
+			results = append(results, nil)
+			continue
+		}
+		results = append(results, s.render(e, isBase64(r)))
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"submissions": results})
+}
+
+func (s *Server) handleGetSubmissions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	results := make([]wireResult, 0, len(s.entries))
+	for _, e := range s.entries {
+		results = append(results, s.render(e, isBase64(r)))
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"submissions": results,
+		"meta": judge0.PaginationMeta{
+			CurrentPage: 1,
+			TotalPages:  1,
+			TotalCount:  len(results),
+		},
+	})
+}
+
+func (s *Server) handleGetLanguages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Languages)
+}
+
+// wireResult matches the JSON shape Judge0 returns for a submission
+type wireResult struct {
+	Stdout        *string  `json:"stdout"`
+	Stderr        *string  `json:"stderr"`
+	CompileOutput *string  `json:"compile_output"`
+	Message       *string  `json:"message"`
+	ExitCode      *int     `json:"exit_code"`
+	Status        status   `json:"status"`
+	CreatedAt     string   `json:"created_at"`
+	FinishedAt    *string  `json:"finished_at"`
+	Token         string   `json:"token"`
+	Time          *string  `json:"time"`
+	WallTime      *string  `json:"wall_time"`
+	Memory        *int     `json:"memory"`
+	Language      Language `json:"language"`
+}
+
+// callbackResult matches the JSON Judge0 sends to a callback url, only the default fields of a submission
+type callbackResult struct {
+	Stdout        *string `json:"stdout"`
+	Time          *string `json:"time"`
+	Memory        *int    `json:"memory"`
+	Stderr        *string `json:"stderr"`
+	Token         string  `json:"token"`
+	CompileOutput *string `json:"compile_output"`
+	Message       *string `json:"message"`
+	Status        status  `json:"status"`
+}
+
+type status struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+}
+
+func (s *Server) render(e *entry, base64Encoded bool) wireResult {
+	out := wireResult{
+		Token:     e.token,
+		CreatedAt: e.createdAt.UTC().Format(time.RFC3339Nano),
+		Language:  s.language(e.submission.LanguageID),
+	}
+
+	// Still running until the scripted delay has elapsed
+	if time.Since(e.createdAt) < e.result.Delay {
+		out.Status = status{ID: StatusProcessing, Description: StatusDescription(StatusProcessing)}
+		return out
+	}
+
+	statusID := e.result.StatusID
+	if statusID == 0 {
+		statusID = StatusAccepted
+	}
+	out.Status = status{ID: statusID, Description: StatusDescription(statusID)}
+
+	encode := func(text string) *string {
+		if text == "" {
+			return nil
+		}
+		if base64Encoded {
+			text = judge0.EncodeBase64(text)
+		}
+		return &text
+	}
+	out.Stdout = encode(e.result.Stdout)
+	out.Stderr = encode(e.result.Stderr)
+	out.CompileOutput = encode(e.result.CompileOutput)
+	out.Message = encode(e.result.Message)
+
+	runTime := e.result.Time
+	if runTime == "" {
+		runTime = "0.001"
+	}
+	memory := e.result.Memory
+	if memory == 0 {
+		memory = 1024
+	}
+	exitCode := e.result.ExitCode
+	finishedAt := e.createdAt.Add(e.result.Delay).UTC().Format(time.RFC3339Nano)
+
+	out.Time = &runTime
+	out.WallTime = &runTime
+	out.Memory = &memory
+	out.ExitCode = &exitCode
+	out.FinishedAt = &finishedAt
+
+	return out
+}
+
+func (s *Server) language(id int) Language {
+	for _, language := range s.Languages {
+		if language.ID == id {
+			return language
+		}
+	}
+	return Language{ID: id}
+}
+
+func isBase64(r *http.Request) bool {
+	return r.URL.Query().Get("base64_encoded") == "true"
+}
+
+func decodeBody(r *http.Request, v any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,389 @@
+package judge0test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+func TestCreateSubmissionBatchAndWait(t *testing.T) {
+	testCases := []struct {
+		name           string
+		result         Result
+		expectedStatus int
+	}{
+		{
+			name:           "Accepted",
+			result:         Result{StatusID: StatusAccepted, Stdout: "[0, 1]"},
+			expectedStatus: StatusAccepted,
+		},
+		{
+			name:           "Compilation error",
+			result:         Result{StatusID: StatusCompilationError, CompileOutput: "main.cpp:1: error"},
+			expectedStatus: StatusCompilationError,
+		},
+		{
+			name:           "Delayed",
+			result:         Result{StatusID: StatusWrongAnswer, Stdout: "[1, 0]", Delay: 200 * time.Millisecond},
+			expectedStatus: StatusWrongAnswer,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewServer(Sequence(testCase.result))
+			defer server.Close()
+
+			submission := judge0.Submission{SourceCode: "print('hello')", LanguageID: 71, Stdin: "it's \"quoted\""}
+			results, err := server.Judge0Client().CreateSubmissionBatchAndWait([]judge0.Submission{submission})
+			if err != nil {
+				t.Fatalf("Failed to run submission: %v", err)
+			}
+
+			result := results[0]
+			if result.Status.ID != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d", testCase.expectedStatus, result.Status.ID)
+			}
+			if result.Stdout != testCase.result.Stdout {
+				t.Errorf("Expected stdout %q, got %q", testCase.result.Stdout, result.Stdout)
+			}
+			if result.CompileOutput != testCase.result.CompileOutput {
+				t.Errorf("Expected compile output %q, got %q", testCase.result.CompileOutput, result.CompileOutput)
+			}
+
+			received := server.Submissions()
+			if len(received) != 1 || received[0] != submission {
+				t.Errorf("Expected server to receive decoded submission %+v, got %+v", submission, received)
+			}
+		})
+	}
+}
+
+func TestCreateSubmissionBatchAndWaitChunks(t *testing.T) {
+	server := NewServer(func(submission judge0.Submission) Result {
+		switch submission.Stdin {
+		case "reject":
+			return Result{Reject: "language_id is not valid"}
+		case "slow":
+			return Result{StatusID: StatusAccepted, Stdout: submission.Stdin, Delay: 100 * time.Millisecond}
+		}
+		return Result{StatusID: StatusAccepted, Stdout: submission.Stdin}
+	})
+	defer server.Close()
+
+	// Three batches, the last one partially rejected
+	submissions := make([]judge0.Submission, 45)
+	for i := range submissions {
+		submissions[i] = judge0.Submission{SourceCode: "x", LanguageID: 71, Stdin: fmt.Sprintf("%d", i)}
+	}
+	submissions[3].Stdin = "slow"
+	submissions[42].Stdin = "reject"
+
+	results, err := server.Judge0Client().CreateSubmissionBatchAndWait(submissions)
+
+	var batchErr *judge0.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a batch error, got %v", err)
+	}
+	if len(batchErr.Errors) != 1 || batchErr.Errors[42] == nil {
+		t.Errorf("Expected only submission 42 to fail, got %v", batchErr)
+	}
+	if len(results) != len(submissions) {
+		t.Fatalf("Expected %d results, got %d", len(submissions), len(results))
+	}
+	for i, result := range results {
+		if i == 42 {
+			if result.Status.ID != StatusInternalError {
+				t.Errorf("Expected rejected submission to be an internal error, got %d", result.Status.ID)
+			}
+			continue
+		}
+		if result.Status.ID != StatusAccepted || result.Stdout != submissions[i].Stdin {
+			t.Errorf("Unexpected result for submission %d: %+v", i, result)
+		}
+	}
+}
+
+func TestCreateSubmissionBatchAndWaitCancel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authzToken    string
+		expectDeleted bool
+	}{
+		{name: "Deletes pending submissions", authzToken: "authz", expectDeleted: true},
+		{name: "Without authorization token", expectDeleted: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewServer(func(judge0.Submission) Result {
+				return Result{StatusID: StatusAccepted, Delay: time.Minute}
+			})
+			defer server.Close()
+			server.AuthzToken = testCase.authzToken
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			submissions := []judge0.Submission{
+				{SourceCode: "x", LanguageID: 71},
+				{SourceCode: "y", LanguageID: 71},
+			}
+			start := time.Now()
+			results, err := server.Judge0Client().CreateSubmissionBatchAndWaitContext(ctx, submissions)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected cancellation to stop polling, took %v", elapsed)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected deadline exceeded, got %v", err)
+			}
+			for i, result := range results {
+				if result.Status.ID != StatusInternalError {
+					t.Errorf("Expected cancelled submission %d to be an internal error, got %d", i, result.Status.ID)
+				}
+			}
+
+			deleted := server.Deleted()
+			if testCase.expectDeleted && len(deleted) != len(submissions) {
+				t.Errorf("Expected %d deleted submissions, got %v", len(submissions), deleted)
+			}
+			if !testCase.expectDeleted && len(deleted) != 0 {
+				t.Errorf("Expected no deleted submissions, got %v", deleted)
+			}
+		})
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	server.AuthToken = "secret"
+
+	cfg := server.Config()
+	cfg.Judge0Token = "wrong"
+	if _, err := judge0.NewJudge0Client(cfg).GetLanguages(); err == nil {
+		t.Errorf("Expected unauthenticated request to fail")
+	}
+
+	client := server.Judge0Client()
+	resp, err := client.CreateSubmission(judge0.EncodeSubmissionInputs(judge0.Submission{SourceCode: "x", LanguageID: 60}))
+	if err != nil {
+		t.Fatalf("Failed to create submission: %v", err)
+	}
+	if _, err := client.GetSubmission(resp.Token); err != nil {
+		t.Errorf("Failed to get submission: %v", err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		failStatus  int
+		latency     time.Duration
+		expectedErr error
+	}{
+		{name: "Unauthorized", failStatus: http.StatusUnauthorized, expectedErr: judge0.ErrUnauthorized},
+		{name: "Queue full", failStatus: http.StatusServiceUnavailable, expectedErr: judge0.ErrQueueFull},
+		{name: "Throttled", failStatus: http.StatusTooManyRequests, expectedErr: judge0.ErrQueueFull},
+		{name: "Server error", failStatus: http.StatusInternalServerError, expectedErr: judge0.ErrUnavailable},
+		{name: "Timeout", latency: time.Second, expectedErr: judge0.ErrTimeout},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewServer(nil)
+			defer server.Close()
+			server.FailRequests(testCase.failStatus, 1)
+			server.SetLatency(testCase.latency)
+
+			client := server.Judge0Client()
+			client.Client.Timeout = 50 * time.Millisecond
+
+			_, err := client.CreateSubmissionContext(context.Background(), judge0.EncodeSubmissionInputs(judge0.Submission{SourceCode: "x", LanguageID: 71}))
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("Expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+
+	client := server.Judge0Client()
+	client.MaxRetries = 2
+
+	// GETs are idempotent and retried
+	server.FailRequests(http.StatusInternalServerError, 2)
+	if _, err := client.GetLanguagesContext(context.Background()); err != nil {
+		t.Errorf("Expected retries to recover, got %v", err)
+	}
+	if requests := server.Requests(); requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+
+	// POSTs could queue a submission twice and are not
+	server.FailRequests(http.StatusInternalServerError, 1)
+	_, err := client.CreateSubmissionContext(context.Background(), judge0.EncodeSubmissionInputs(judge0.Submission{SourceCode: "x", LanguageID: 71}))
+	if !errors.Is(err, judge0.ErrUnavailable) {
+		t.Errorf("Expected unavailable, got %v", err)
+	}
+	if requests := server.Requests(); requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests)
+	}
+
+	// Giving up while waiting to retry reports the cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.Client.Transport = cancelTransport{cancel}
+	server.FailRequests(http.StatusInternalServerError, 1)
+	_, err = client.GetLanguagesContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled, got %v", err)
+	}
+	if requests := server.Requests(); requests != 5 {
+		t.Errorf("Expected 5 requests, got %d", requests)
+	}
+}
+
+// cancelTransport cancels a context once a request got its response
+type cancelTransport struct {
+	cancel context.CancelFunc
+}
+
+func (t cancelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer t.cancel()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	server.FailRequests(http.StatusInternalServerError, 100)
+
+	client := server.Judge0Client()
+	client.MaxRetries = 0
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.GetLanguagesContext(context.Background()); !errors.Is(err, judge0.ErrUnavailable) {
+			t.Fatalf("Expected unavailable, got %v", err)
+		}
+	}
+
+	_, err := client.GetLanguagesContext(context.Background())
+	if !errors.Is(err, judge0.ErrCircuitOpen) || !errors.Is(err, judge0.ErrUnavailable) {
+		t.Errorf("Expected open circuit, got %v", err)
+	}
+	if requests := server.Requests(); requests != 5 {
+		t.Errorf("Expected the open circuit to skip judge0, got %d requests", requests)
+	}
+}
+
+func TestGetLanguages(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+
+	languages, err := server.Judge0Client().GetLanguages()
+	if err != nil {
+		t.Fatalf("Failed to get languages: %v", err)
+	}
+	if len(languages) != len(DefaultLanguages) {
+		t.Errorf("Expected %d languages, got %d", len(DefaultLanguages), len(languages))
+	}
+}
+
+func TestCallback(t *testing.T) {
+	results := make(chan judge0.SubmissionResult, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result judge0.SubmissionResult
+		if r.Method != http.MethodPut || json.NewDecoder(r.Body).Decode(&result) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		results <- result
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	server := NewServer(Accept("[0, 1]"))
+	defer server.Close()
+
+	submission := judge0.EncodeSubmissionInputs(judge0.Submission{SourceCode: "x", LanguageID: 71, CallbackURL: receiver.URL})
+	resp, err := server.Judge0Client().CreateSubmission(submission)
+	if err != nil {
+		t.Fatalf("Failed to create submission: %v", err)
+	}
+
+	select {
+	case result := <-results:
+		decoded, err := judge0.DecodeSubmissionResult(result)
+		if err != nil {
+			t.Fatalf("Failed to decode callback result: %v", err)
+		}
+		if decoded.Token != resp.Token || decoded.Stdout != "[0, 1]" || decoded.Status.ID != StatusAccepted {
+			t.Errorf("Unexpected callback result: %+v", decoded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for callback")
+	}
+}
+
+func TestLanguageRegistrySync(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	// Judge0 without Go
+	server.Languages = []Language{
+		{ID: 54, Name: "C++ (GCC 9.2.0)"},
+		{ID: 71, Name: "Python (3.8.1)"},
+		{ID: 73, Name: "Rust (1.40.0)"},
+	}
+
+	registry := judge0.NewLanguageRegistry()
+	if err := registry.Sync(context.Background(), server.Judge0Client(), []string{"cpp", "go", "python", "cobol"}); err != nil {
+		t.Fatalf("Failed to sync languages: %v", err)
+	}
+
+	testCases := []struct {
+		language string
+		id       int
+		version  string
+		enabled  bool
+	}{
+		{language: "cpp", id: 54, version: "GCC 9.2.0", enabled: true},
+		{language: "go", id: 60, enabled: false},
+		{language: "python", id: 71, version: "3.8.1", enabled: true},
+		{language: "cobol", enabled: false},
+		// Languages outside problem_language stay registered for the submissions made in them
+		{language: "rust", id: 73, version: "1.40.0", enabled: false},
+		{language: "java", id: 62, enabled: false},
+	}
+
+	for _, testCase := range testCases {
+		entry, ok := registry.Lookup(testCase.language)
+		if !ok {
+			t.Errorf("Expected %s to be registered", testCase.language)
+			continue
+		}
+		if entry.ID != testCase.id || entry.Version != testCase.version || entry.Enabled != testCase.enabled {
+			t.Errorf("Unexpected entry for %s: %+v", testCase.language, entry)
+		}
+	}
+
+	if entry, ok := registry.LookupID(73); !ok || entry.Language != "rust" {
+		t.Errorf("Expected judge0 id 73 to resolve to rust, got %+v", entry)
+	}
+}
@@ -0,0 +1,174 @@
+package judge0
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Language is a language as listed by judge0's GET /languages, e.g. {54, "C++ (GCC 9.2.0)"}
+type Language struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RegistryLanguage is a language problems can be solved in, keyed by its problem_language value
+type RegistryLanguage struct {
+	Language  string `json:"language"`  // problem_language value, e.g. "cpp"
+	ID        int    `json:"id"`        // judge0 language id
+	Name      string `json:"name"`      // display name, e.g. "C++"
+	Version   string `json:"version"`   // compiler or runtime version reported by judge0, e.g. "GCC 9.2.0"
+	Extension string `json:"extension"` // source file extension, e.g. "cpp"
+	Enabled   bool   `json:"enabled"`   // whether judge0 runs it and problems accept it
+	// TimeMultiplier scales problem time limits for slower runtimes unless the problem overrides it
+	TimeMultiplier float64 `json:"timeMultiplier"`
+}
+
+// defaultLanguages are the languages kadane knows how to template, with the judge0 ids of a stock install.
+// Versions are filled in once the registry is synced with judge0.
+var defaultLanguages = []RegistryLanguage{
+	{Language: "cpp", ID: 54, Name: "C++", Extension: "cpp", TimeMultiplier: 1},
+	{Language: "go", ID: 60, Name: "Go", Extension: "go", TimeMultiplier: 1},
+	{Language: "java", ID: 62, Name: "Java", Extension: "java", TimeMultiplier: 2},
+	{Language: "javascript", ID: 63, Name: "JavaScript", Extension: "js", TimeMultiplier: 1.5},
+	{Language: "python", ID: 71, Name: "Python", Extension: "py", TimeMultiplier: 3},
+	{Language: "typescript", ID: 74, Name: "TypeScript", Extension: "ts", TimeMultiplier: 1.5},
+	{Language: "rust", ID: 73, Name: "Rust", Extension: "rs", TimeMultiplier: 1},
+	{Language: "c", ID: 50, Name: "C", Extension: "c", TimeMultiplier: 1},
+	{Language: "csharp", ID: 51, Name: "C#", Extension: "cs", TimeMultiplier: 1.5},
+	{Language: "kotlin", ID: 78, Name: "Kotlin", Extension: "kt", TimeMultiplier: 2},
+	{Language: "ruby", ID: 72, Name: "Ruby", Extension: "rb", TimeMultiplier: 3},
+}
+
+// LanguageRegistry maps problem languages to judge0 languages. It is safe for concurrent use.
+type LanguageRegistry struct {
+	mu         sync.RWMutex
+	languages  []RegistryLanguage
+	byLanguage map[string]RegistryLanguage
+	byID       map[int]RegistryLanguage
+}
+
+// NewLanguageRegistry returns a registry with every default language enabled
+func NewLanguageRegistry() *LanguageRegistry {
+	r := &LanguageRegistry{}
+	languages := make([]RegistryLanguage, len(defaultLanguages))
+	for i, language := range defaultLanguages {
+		language.Enabled = true
+		languages[i] = language
+	}
+	r.set(languages)
+	return r
+}
+
+func (r *LanguageRegistry) set(languages []RegistryLanguage) {
+	byLanguage := make(map[string]RegistryLanguage, len(languages))
+	byID := make(map[int]RegistryLanguage, len(languages))
+	for _, language := range languages {
+		byLanguage[language.Language] = language
+		if language.ID != 0 {
+			byID[language.ID] = language
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.languages = languages
+	r.byLanguage = byLanguage
+	r.byID = byID
+}
+
+// Languages lists every registered language, including disabled ones
+func (r *LanguageRegistry) Languages() []RegistryLanguage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.languages)
+}
+
+// Lookup finds a language by its problem_language value
+func (r *LanguageRegistry) Lookup(language string) (RegistryLanguage, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.byLanguage[language]
+	return entry, ok
+}
+
+// LookupID finds a language by its judge0 id
+func (r *LanguageRegistry) LookupID(id int) (RegistryLanguage, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.byID[id]
+	return entry, ok
+}
+
+// Sync reconciles the registry with the languages judge0 offers and the problem_language values
+// the database accepts. A language is enabled only when both know it. Languages missing on either
+// side are kept disabled, so they still resolve for existing submissions.
+func (r *LanguageRegistry) Sync(ctx context.Context, executor Executor, problemLanguages []string) error {
+	available, err := executor.GetLanguagesContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching judge0 languages: %w", err)
+	}
+	judge0Languages := make(map[int]Language, len(available))
+	for _, language := range available {
+		judge0Languages[language.ID] = language
+	}
+
+	known := make(map[string]RegistryLanguage, len(defaultLanguages))
+	for _, language := range defaultLanguages {
+		known[language.Language] = language
+	}
+
+	languages := make([]RegistryLanguage, 0, len(problemLanguages))
+	for _, name := range problemLanguages {
+		language, ok := known[name]
+		if !ok {
+			log.Printf("Language %s has no judge0 mapping and is disabled", name)
+			languages = append(languages, RegistryLanguage{Language: name, Name: name, TimeMultiplier: 1})
+			continue
+		}
+
+		judge0Language, ok := judge0Languages[language.ID]
+		if !ok {
+			log.Printf("Language %s (judge0 id %d) is not offered by judge0 and is disabled", name, language.ID)
+		} else {
+			language.Version = languageVersion(judge0Language.Name)
+			language.Enabled = true
+		}
+		languages = append(languages, language)
+	}
+
+	for _, language := range defaultLanguages {
+		if slices.Contains(problemLanguages, language.Language) {
+			continue
+		}
+		log.Printf("Language %s is not a problem_language value and is disabled", language.Language)
+		if judge0Language, ok := judge0Languages[language.ID]; ok {
+			language.Version = languageVersion(judge0Language.Name)
+		}
+		languages = append(languages, language)
+	}
+
+	r.set(languages)
+	return nil
+}
+
+// languageVersion extracts the version from a judge0 language name, "C++ (GCC 9.2.0)" gives "GCC 9.2.0"
+func languageVersion(name string) string {
+	start := strings.LastIndex(name, "(")
+	end := strings.LastIndex(name, ")")
+	if start == -1 || end < start {
+		return ""
+	}
+	return name[start+1 : end]
+}
+
+// registry backs the package level language lookups
+var registry = NewLanguageRegistry()
+
+// Languages returns the package level registry used by LanguageToLanguageID and LanguageIDToLanguage
+func Languages() *LanguageRegistry {
+	return registry
+}
@@ -0,0 +1,229 @@
+package judge0
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+type priorityKey struct{}
+
+type holdKey struct{}
+
+// WithPriority sets the priority executions created with the context wait for judge0 with, see Scheduler.
+// Executions without one have priority 0.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority set by WithPriority
+func PriorityFromContext(ctx context.Context) int {
+	priority, _ := ctx.Value(priorityKey{}).(int)
+	return priority
+}
+
+// SlotHolder is an Executor whose slots can be held past creating submissions, see Scheduler.Hold
+type SlotHolder interface {
+	Executor
+	Hold(ctx context.Context, key string) (context.Context, error)
+	Release(key string)
+}
+
+// Scheduler is an Executor admitting at most capacity executions to judge0 at once. Once it is saturated,
+// waiting executions are admitted by the priority of their context, highest first, then in arrival order.
+// Runs hold their slot until their results are in. Judge0 runs queued submissions in its own order, so they only
+// wait their turn here when their slot is held with Hold until they are graded. Fetching results and languages is
+// never held back.
+type Scheduler struct {
+	Executor
+
+	mu       sync.Mutex
+	capacity int
+	running  int
+	waiting  schedulerQueue
+	arrivals uint64
+	held     map[string]struct{} // keys of the slots taken with Hold
+}
+
+// NewScheduler schedules the executions of an executor, capacity is at least 1
+func NewScheduler(executor Executor, capacity int) *Scheduler {
+	return &Scheduler{Executor: executor, capacity: max(capacity, 1)}
+}
+
+// Hold waits for a slot like an execution does and keeps it for the key until Release is called. Executions with the
+// returned context use the held slot instead of waiting for another.
+func (s *Scheduler) Hold(ctx context.Context, key string) (context.Context, error) {
+	if err := s.acquire(ctx); err != nil {
+		return ctx, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held == nil {
+		s.held = make(map[string]struct{})
+	}
+	if _, ok := s.held[key]; ok {
+		// Already held, the slot just taken goes back
+		s.releaseLocked()
+	}
+	s.held[key] = struct{}{}
+	return context.WithValue(ctx, holdKey{}, key), nil
+}
+
+// Release gives back the slot held for the key, keys without one are ignored
+func (s *Scheduler) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.held[key]; !ok {
+		return
+	}
+	delete(s.held, key)
+	s.releaseLocked()
+}
+
+// holds reports whether the context carries a slot held with Hold
+func (s *Scheduler) holds(ctx context.Context) bool {
+	key, ok := ctx.Value(holdKey{}).(string)
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok = s.held[key]
+	return ok
+}
+
+func (s *Scheduler) CreateSubmissionContext(ctx context.Context, submission Submission) (*SubmissionResponse, error) {
+	if s.holds(ctx) {
+		return s.Executor.CreateSubmissionContext(ctx, submission)
+	}
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.Executor.CreateSubmissionContext(ctx, submission)
+}
+
+func (s *Scheduler) CreateSubmissionBatchContext(ctx context.Context, submissions []Submission) (*SubmissionBatchResponse, error) {
+	if s.holds(ctx) {
+		return s.Executor.CreateSubmissionBatchContext(ctx, submissions)
+	}
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.Executor.CreateSubmissionBatchContext(ctx, submissions)
+}
+
+// CreateSubmissionBatchAndWaitContext reports every submission as failed with the context's error when the
+// context is done before the batch is admitted
+func (s *Scheduler) CreateSubmissionBatchAndWaitContext(ctx context.Context, submissions []Submission) ([]SubmissionResult, error) {
+	if s.holds(ctx) {
+		return s.Executor.CreateSubmissionBatchAndWaitContext(ctx, submissions)
+	}
+	if err := s.acquire(ctx); err != nil {
+		results := make([]SubmissionResult, len(submissions))
+		batchErr := newBatchError(len(submissions))
+		for i := range submissions {
+			batchErr.set(i, err)
+			results[i] = failedResult(err)
+		}
+		return results, batchErr.errOrNil()
+	}
+	defer s.release()
+	return s.Executor.CreateSubmissionBatchAndWaitContext(ctx, submissions)
+}
+
+// Waiting is the number of executions waiting for a slot
+func (s *Scheduler) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting.Len()
+}
+
+// acquire waits for a slot until the context is done
+func (s *Scheduler) acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running < s.capacity && s.waiting.Len() == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	s.arrivals++
+	waiter := &schedulerWaiter{priority: PriorityFromContext(ctx), arrival: s.arrivals, ready: make(chan struct{})}
+	heap.Push(&s.waiting, waiter)
+	s.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if waiter.index < 0 {
+			// Admitted while giving up, pass the slot on
+			s.releaseLocked()
+		} else {
+			heap.Remove(&s.waiting, waiter.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// release hands the slot to the first waiting execution, if any
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+func (s *Scheduler) releaseLocked() {
+	if s.waiting.Len() == 0 {
+		s.running--
+		return
+	}
+	waiter := heap.Pop(&s.waiting).(*schedulerWaiter)
+	close(waiter.ready)
+}
+
+type schedulerWaiter struct {
+	priority int
+	arrival  uint64
+	index    int // in the queue, -1 once admitted
+	ready    chan struct{}
+}
+
+// schedulerQueue is a heap of waiting executions, see Scheduler
+type schedulerQueue []*schedulerWaiter
+
+func (q schedulerQueue) Len() int { return len(q) }
+
+func (q schedulerQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].arrival < q[j].arrival
+}
+
+func (q schedulerQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *schedulerQueue) Push(x any) {
+	waiter := x.(*schedulerWaiter)
+	waiter.index = len(*q)
+	*q = append(*q, waiter)
+}
+
+func (q *schedulerQueue) Pop() any {
+	old := *q
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	waiter.index = -1
+	*q = old[:len(old)-1]
+	return waiter
+}
+
+// Ensure Scheduler satisfies the SlotHolder interface
+var _ SlotHolder = (*Scheduler)(nil)
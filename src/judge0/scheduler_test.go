@@ -0,0 +1,159 @@
+package judge0
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingExecutor runs submissions once released and records the order they ran in
+type blockingExecutor struct {
+	Executor
+	release chan struct{}
+	mu      sync.Mutex
+	order   []string
+}
+
+func (e *blockingExecutor) CreateSubmissionBatchAndWaitContext(ctx context.Context, submissions []Submission) ([]SubmissionResult, error) {
+	e.mu.Lock()
+	e.order = append(e.order, submissions[0].Stdin)
+	e.mu.Unlock()
+	<-e.release
+	return make([]SubmissionResult, len(submissions)), nil
+}
+
+// waitForStarted polls until count executions started running
+func waitForStarted(t *testing.T, executor *blockingExecutor, count int) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		executor.mu.Lock()
+		started := len(executor.order)
+		executor.mu.Unlock()
+		if started == count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d started executions, got %d", count, started)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForWaiting polls until count executions wait for the scheduler
+func waitForWaiting(t *testing.T, scheduler *Scheduler, count int) {
+	deadline := time.Now().Add(time.Second)
+	for scheduler.Waiting() != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d waiting executions, got %d", count, scheduler.Waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("Admit higher priorities first", func(t *testing.T) {
+		t.Parallel()
+
+		executor := &blockingExecutor{release: make(chan struct{})}
+		scheduler := NewScheduler(executor, 1)
+
+		var wg sync.WaitGroup
+		run := func(name string, priority int) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				scheduler.CreateSubmissionBatchAndWaitContext(WithPriority(context.Background(), priority), []Submission{{Stdin: name}})
+			}()
+		}
+
+		run("running", 0)
+		waitForStarted(t, executor, 1)
+		run("free", 0)
+		waitForWaiting(t, scheduler, 1)
+		run("plus", 1)
+		waitForWaiting(t, scheduler, 2)
+		run("pro", 2)
+		waitForWaiting(t, scheduler, 3)
+
+		close(executor.release)
+		wg.Wait()
+
+		expected := []string{"running", "pro", "plus", "free"}
+		for i, name := range expected {
+			if executor.order[i] != name {
+				t.Fatalf("Expected order %v, got %v", expected, executor.order)
+			}
+		}
+	})
+
+	t.Run("Stop waiting once the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		executor := &blockingExecutor{release: make(chan struct{})}
+		scheduler := NewScheduler(executor, 1)
+
+		done := make(chan struct{})
+		go func() {
+			scheduler.CreateSubmissionBatchAndWaitContext(context.Background(), []Submission{{Stdin: "running"}})
+			close(done)
+		}()
+		waitForStarted(t, executor, 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			results, err := scheduler.CreateSubmissionBatchAndWaitContext(ctx, []Submission{{Stdin: "cancelled"}})
+			if len(results) != 1 || results[0].Status.Description != "Internal Error" {
+				t.Errorf("Expected a failed result, got %v", results)
+			}
+			errs <- err
+		}()
+		waitForWaiting(t, scheduler, 1)
+		cancel()
+
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if scheduler.Waiting() != 0 {
+			t.Errorf("Expected no waiting executions, got %d", scheduler.Waiting())
+		}
+
+		close(executor.release)
+		<-done
+		if _, err := scheduler.CreateSubmissionBatchAndWaitContext(context.Background(), []Submission{{Stdin: "next"}}); err != nil {
+			t.Errorf("Expected the slot to be free, got %v", err)
+		}
+	})
+	t.Run("Keep held slots until they are released", func(t *testing.T) {
+		t.Parallel()
+
+		executor := &blockingExecutor{release: make(chan struct{})}
+		close(executor.release)
+		scheduler := NewScheduler(executor, 1)
+
+		held, err := scheduler.Hold(context.Background(), "submission")
+		if err != nil {
+			t.Fatalf("Failed to hold a slot: %v", err)
+		}
+		// The held slot is used by executions of its context
+		if _, err := scheduler.CreateSubmissionBatchAndWaitContext(held, []Submission{{Stdin: "held"}}); err != nil {
+			t.Fatalf("Expected the held slot to be used, got %v", err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			scheduler.CreateSubmissionBatchAndWaitContext(context.Background(), []Submission{{Stdin: "next"}})
+			close(done)
+		}()
+		waitForWaiting(t, scheduler, 1)
+
+		scheduler.Release("submission")
+		scheduler.Release("submission")
+		<-done
+		if scheduler.Waiting() != 0 {
+			t.Errorf("Expected no waiting executions, got %d", scheduler.Waiting())
+		}
+	})
+}
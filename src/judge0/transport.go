@@ -0,0 +1,156 @@
+package judge0
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 10 * time.Second
+	retryBaseDelay        = 100 * time.Millisecond
+	retryMaxDelay         = 2 * time.Second
+
+	// breakerThreshold consecutive failures open the circuit for breakerCooldown
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// circuitBreaker fails requests fast while judge0 is down. After the cooldown a single
+// probe request is let through, closing the circuit again if it succeeds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a request may be sent. A nil breaker allows everything.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Since(b.openedAt) < b.cooldown || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// abort releases a probe whose outcome is unknown because the caller gave up
+func (b *circuitBreaker) abort() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// record updates the breaker with the outcome of a request
+func (b *circuitBreaker) record(success bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// do sends a judge0 request and returns the response body. Requests go through the circuit breaker,
+// and GETs, being idempotent, are retried with jittered backoff on transient failures.
+// Responses outside 2xx come back as a *StatusError.
+func (c *Judge0Client) do(req *http.Request) ([]byte, error) {
+	attempts := 1
+	if req.Method == http.MethodGet {
+		attempts += c.MaxRetries
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if sleepErr := sleepContext(req.Context(), retryDelay(attempt)); sleepErr != nil {
+				return nil, fmt.Errorf("%w: %w", sleepErr, err)
+			}
+		}
+
+		var body []byte
+		var retry bool
+		body, retry, err = c.send(req)
+		if err == nil || !retry {
+			return body, err
+		}
+	}
+	return nil, err
+}
+
+// send makes a single attempt at a request, reporting whether the failure is worth retrying
+func (c *Judge0Client) send(req *http.Request) ([]byte, bool, error) {
+	if !c.breaker.allow() {
+		return nil, false, ErrCircuitOpen
+	}
+
+	resp, err := c.Client.Do(req.Clone(req.Context()))
+	if err != nil {
+		// The caller giving up says nothing about judge0's health
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			c.breaker.abort()
+			return nil, false, fmt.Errorf("error sending request: %w", ctxErr)
+		}
+		c.breaker.record(false)
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, true, fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return nil, true, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.breaker.record(false)
+		return nil, true, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+		c.breaker.record(resp.StatusCode < 500)
+		return nil, statusErr.transient(), statusErr
+	}
+
+	c.breaker.record(true)
+	return body, false, nil
+}
+
+// retryDelay is a full jitter exponential backoff, so concurrent retries don't hit judge0 in lockstep
+func retryDelay(attempt int) time.Duration {
+	ceiling := min(retryBaseDelay<<attempt, retryMaxDelay)
+	return rand.N(ceiling)
+}
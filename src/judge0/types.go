@@ -1,10 +0,0 @@
-package judge0
-
-var languageIDMap = map[string]int{
-	"cpp":        54,
-	"go":         60,
-	"java":       62,
-	"javascript": 63,
-	"python":     71,
-	"typescript": 74,
-}
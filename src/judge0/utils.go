@@ -1,18 +1,20 @@
 package judge0
 
-import "encoding/base64"
+import (
+	"encoding/base64"
+	"fmt"
+)
 
+// LanguageToLanguageID returns the judge0 id of a problem language, or 0 if unknown
 func LanguageToLanguageID(language string) int {
-	return languageIDMap[language]
+	entry, _ := registry.Lookup(language)
+	return entry.ID
 }
 
+// LanguageIDToLanguage returns the problem language of a judge0 id, or "" if unknown
 func LanguageIDToLanguage(languageID int) string {
-	for language, id := range languageIDMap {
-		if id == languageID {
-			return language
-		}
-	}
-	return ""
+	entry, _ := registry.LookupID(languageID)
+	return entry.Language
 }
 
 func EncodeBase64(text string) string {
@@ -43,3 +45,26 @@ func EncodeInputSubmissionsInput(submissions []Submission) []Submission {
 	}
 	return submissions
 }
+
+// DecodeSubmissionResult decodes the base64 encoded output fields of a submission result
+func DecodeSubmissionResult(result SubmissionResult) (SubmissionResult, error) {
+	var err error
+	resultDecoded := result
+	resultDecoded.Stdout, err = DecodeBase64(result.Stdout)
+	if err != nil {
+		return SubmissionResult{}, fmt.Errorf("error decoding stdout: %w", err)
+	}
+	resultDecoded.Stderr, err = DecodeBase64(result.Stderr)
+	if err != nil {
+		return SubmissionResult{}, fmt.Errorf("error decoding stderr: %w", err)
+	}
+	resultDecoded.CompileOutput, err = DecodeBase64(result.CompileOutput)
+	if err != nil {
+		return SubmissionResult{}, fmt.Errorf("error decoding compile output: %w", err)
+	}
+	resultDecoded.Message, err = DecodeBase64(result.Message)
+	if err != nil {
+		return SubmissionResult{}, fmt.Errorf("error decoding message: %w", err)
+	}
+	return resultDecoded, nil
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// InternalPathPrefix prefixes routes called by internal services such as judge0.
+// These routes authenticate requests themselves instead of using firebase auth.
+const InternalPathPrefix = "/v1/internal/"
+
+// SkipInternal applies the middleware to every request except those to internal routes
+func SkipInternal(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, InternalPathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
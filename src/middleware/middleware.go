@@ -54,8 +54,8 @@ func getStringClaim(claims map[string]interface{}, key string) string {
 func Middleware(m *Handler, r chi.Router) {
 	r.Use(middleware.RealIP)
 	//r.Use(routeValidator)
-	r.Use(httprate.LimitByIP(10, 1*time.Second)) // LimitByIP middleware will limit the number of requests per IP address
-	r.Use(middleware.Heartbeat("/health"))       // Heartbeat middleware will create a simple health check endpoint
+	r.Use(SkipInternal(httprate.LimitByIP(10, 1*time.Second))) // LimitByIP middleware will limit the number of requests per IP address
+	r.Use(middleware.Heartbeat("/health"))                     // Heartbeat middleware will create a simple health check endpoint
 	r.Use(CustomLogger)
 	r.Use(BlockConnectMethod)                                 // BlockConnectMethod middleware will block any request using the CONNECT method
 	r.Use(middleware.AllowContentEncoding("deflate", "gzip")) // AllowContentEncoding middleware will allow the client to request compressed content
@@ -66,19 +66,19 @@ func Middleware(m *Handler, r chi.Router) {
 		AllowedOrigins:   []string{"https://kadane.xyz", "https://www.kadane.xyz", "https://api.kadane.xyz", "http://localhost:5173"}, // Define allowed origins (wildcard "*" can be used but it's not recommended for security reasons)
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},                                                // HTTP methods that are allowed
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"}, // Headers that browsers can expose to frontend JavaScript
-		AllowCredentials: true,             // Allow credentials (cookies, authentication) to be shared
-		MaxAge:           300,              // Max age for preflight requests
+		ExposedHeaders:   []string{"Link", "Retry-After"}, // Headers that browsers can expose to frontend JavaScript
+		AllowCredentials: true,                            // Allow credentials (cookies, authentication) to be shared
+		MaxAge:           300,                             // Max age for preflight requests
 	}))
 	r.Use(middleware.Recoverer)
 	// DEBUG bypass firebase auth
 	if m.Config.Debug {
-		r.Use(m.FirebaseDebugAuth())
+		r.Use(SkipInternal(m.FirebaseDebugAuth()))
 	} else {
-		r.Use(m.FirebaseAuth()) // Firebase Auth middleware
+		r.Use(SkipInternal(m.FirebaseAuth())) // Firebase Auth middleware
 	}
-	r.Use(m.AdminAuth()) // Admin Auth middleware
-	r.Use(m.UserAuth())  // User Auth middleware
+	r.Use(SkipInternal(m.AdminAuth())) // Admin Auth middleware
+	r.Use(SkipInternal(m.UserAuth()))  // User Auth middleware
 
 	log.Println("Middleware initialized")
 }
@@ -0,0 +1,106 @@
+package resultcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+const (
+	// DefaultMemorySize is how many results the memory cache keeps when no size is set
+	DefaultMemorySize = 10000
+	// DefaultPostgresTTL is how long the postgres cache keeps a result when no ttl is set. Results outside of a
+	// problem are never invalidated, so the ttl is what bounds them.
+	DefaultPostgresTTL = 7 * 24 * time.Hour
+	// postgresPruneInterval is how often the postgres cache deletes its expired results
+	postgresPruneInterval = time.Hour
+)
+
+// Memory caches results in process, evicting the least recently used once it holds size results
+type Memory struct {
+	mu       sync.Mutex
+	size     int
+	order    *list.List // most recently used first
+	entries  map[string]*list.Element
+	problems map[int32]map[string]struct{} // keys cached for each problem
+}
+
+type memoryEntry struct {
+	key       string
+	problemID int32
+	result    judge0.SubmissionResult
+}
+
+// NewMemory creates a memory cache of size results, DefaultMemorySize when not positive
+func NewMemory(size int) *Memory {
+	if size <= 0 {
+		size = DefaultMemorySize
+	}
+	return &Memory{
+		size:     size,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		problems: make(map[int32]map[string]struct{}),
+	}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (judge0.SubmissionResult, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return judge0.SubmissionResult{}, false
+	}
+	m.order.MoveToFront(element)
+	return element.Value.(*memoryEntry).result, true
+}
+
+func (m *Memory) Set(ctx context.Context, key string, problemID int32, submissionResult judge0.SubmissionResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.order.MoveToFront(element)
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, problemID: problemID, result: result(submissionResult)})
+	if m.problems[problemID] == nil {
+		m.problems[problemID] = make(map[string]struct{})
+	}
+	m.problems[problemID][key] = struct{}{}
+
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *Memory) InvalidateProblem(ctx context.Context, problemID int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.problems[problemID] {
+		m.remove(m.entries[key])
+	}
+}
+
+// remove drops an entry, the lock must be held
+func (m *Memory) remove(element *list.Element) {
+	entry := m.order.Remove(element).(*memoryEntry)
+	delete(m.entries, entry.key)
+	delete(m.problems[entry.problemID], entry.key)
+	if len(m.problems[entry.problemID]) == 0 {
+		delete(m.problems, entry.problemID)
+	}
+}
+
+// Len is the number of cached results
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
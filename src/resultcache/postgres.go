@@ -0,0 +1,101 @@
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"kadane.xyz/go-backend/v2/src/judge0"
+	"kadane.xyz/go-backend/v2/src/sql/sql"
+)
+
+// Postgres caches results in the submission_result_cache table, shared by every instance of the api. Results expire
+// ttl after they are cached, expired ones are deleted by the instance caching a result once they are due.
+type Postgres struct {
+	Queries *sql.Queries
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	prunedAt time.Time // last time the expired results were deleted
+}
+
+// NewPostgres creates a cache backed by the database keeping results for ttl, DefaultPostgresTTL when not positive
+func NewPostgres(queries *sql.Queries, ttl time.Duration) *Postgres {
+	if ttl <= 0 {
+		ttl = DefaultPostgresTTL
+	}
+	return &Postgres{Queries: queries, ttl: ttl}
+}
+
+// expiredBefore is the creation time results older than are expired
+func (p *Postgres) expiredBefore(now time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: now.Add(-p.ttl).UTC(), Valid: true}
+}
+
+func (p *Postgres) Get(ctx context.Context, key string) (judge0.SubmissionResult, bool) {
+	encoded, err := p.Queries.GetSubmissionResultCache(ctx, sql.GetSubmissionResultCacheParams{
+		Key:           key,
+		ExpiredBefore: p.expiredBefore(time.Now()),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return judge0.SubmissionResult{}, false
+	}
+	if err != nil {
+		log.Printf("Failed to get cached result: %v", err)
+		return judge0.SubmissionResult{}, false
+	}
+
+	var cached judge0.SubmissionResult
+	if err := json.Unmarshal(encoded, &cached); err != nil {
+		log.Printf("Failed to decode cached result: %v", err)
+		return judge0.SubmissionResult{}, false
+	}
+	return cached, true
+}
+
+func (p *Postgres) Set(ctx context.Context, key string, problemID int32, submissionResult judge0.SubmissionResult) {
+	encoded, err := json.Marshal(result(submissionResult))
+	if err != nil {
+		log.Printf("Failed to encode result: %v", err)
+		return
+	}
+
+	err = p.Queries.CreateSubmissionResultCache(ctx, sql.CreateSubmissionResultCacheParams{
+		Key:       key,
+		ProblemID: pgtype.Int4{Int32: problemID, Valid: problemID != 0},
+		Result:    encoded,
+	})
+	if err != nil {
+		log.Printf("Failed to cache result: %v", err)
+	}
+
+	now := time.Now()
+	if p.pruneDue(now) {
+		if _, err := p.Queries.DeleteExpiredSubmissionResultCache(ctx, p.expiredBefore(now)); err != nil {
+			log.Printf("Failed to delete expired cached results: %v", err)
+		}
+	}
+}
+
+// pruneDue reports whether the expired results are due to be deleted, at most once every postgresPruneInterval
+func (p *Postgres) pruneDue(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.prunedAt.IsZero() && now.Sub(p.prunedAt) < postgresPruneInterval {
+		return false
+	}
+	p.prunedAt = now
+	return true
+}
+
+func (p *Postgres) InvalidateProblem(ctx context.Context, problemID int32) {
+	if err := p.Queries.DeleteProblemSubmissionResultCache(ctx, problemID); err != nil {
+		log.Printf("Failed to invalidate cached results of problem %d: %v", problemID, err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package resultcache stores judge0 results of deterministic executions so identical submissions skip judge0.
+//
+// Results are addressed by a hash of the judge0 submission: the source code with the test harness, the
+// language, stdin holding the test case inputs, and the limits. Expected outputs are not part of it as results
+// are graded after they are read from the cache, so fixing an expected output never serves a stale verdict.
+// Entries are tagged with their problem and dropped when the problem's test cases change.
+// The memory cache keeps a number of results and the postgres cache keeps them for a time, see DefaultMemorySize
+// and DefaultPostgresTTL.
+package resultcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+// Cache is a result cache backend, failures are treated as misses
+type Cache interface {
+	// Get returns the result cached for a key
+	Get(ctx context.Context, key string) (judge0.SubmissionResult, bool)
+	// Set caches a result for a key, problemID is 0 for executions outside of a problem
+	Set(ctx context.Context, key string, problemID int32, result judge0.SubmissionResult)
+	// InvalidateProblem drops every result cached for a problem
+	InvalidateProblem(ctx context.Context, problemID int32)
+}
+
+// Key hashes everything judge0 executes a submission with, a callback doesn't change the result
+func Key(submission judge0.Submission) string {
+	submission.CallbackURL = ""
+	encoded, _ := json.Marshal(submission)
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
+
+// Cacheable reports whether running the submission again would give the same result. Time limits depend on the
+// load of judge0 and internal errors on its health, so only results the program itself decides are cached.
+func Cacheable(result judge0.SubmissionResult) bool {
+	switch result.Status.ID {
+	case 3, 6, 7, 8, 9, 10, 11, 12: // Accepted, Compilation Error and Runtime Errors
+		return true
+	}
+	return false
+}
+
+// result strips what belongs to a single execution from a result before it is cached
+func result(result judge0.SubmissionResult) judge0.SubmissionResult {
+	result.Token = ""
+	result.CreatedAt = ""
+	result.FinishedAt = ""
+	return result
+}
@@ -0,0 +1,152 @@
+package resultcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kadane.xyz/go-backend/v2/src/judge0"
+)
+
+func TestKey(t *testing.T) {
+	submission := judge0.Submission{LanguageID: 71, SourceCode: "print(1)", Stdin: "[1]", CPUTimeLimit: 2}
+
+	testCases := []struct {
+		name       string
+		submission func(judge0.Submission) judge0.Submission
+		same       bool
+	}{
+		{name: "Same submission", submission: func(s judge0.Submission) judge0.Submission { return s }, same: true},
+		{name: "Callback is ignored", submission: func(s judge0.Submission) judge0.Submission { s.CallbackURL = "http://api"; return s }, same: true},
+		{name: "Source code", submission: func(s judge0.Submission) judge0.Submission { s.SourceCode = "print(2)"; return s }},
+		{name: "Language", submission: func(s judge0.Submission) judge0.Submission { s.LanguageID = 63; return s }},
+		{name: "Test case inputs", submission: func(s judge0.Submission) judge0.Submission { s.Stdin = "[2]"; return s }},
+		{name: "Limits", submission: func(s judge0.Submission) judge0.Submission { s.CPUTimeLimit = 3; return s }},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			same := Key(submission) == Key(testCase.submission(submission))
+			if same != testCase.same {
+				t.Errorf("Expected same key %v, got %v", testCase.same, same)
+			}
+		})
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		cacheable bool
+	}{
+		{name: "Accepted", status: 3, cacheable: true},
+		{name: "Compilation error", status: 6, cacheable: true},
+		{name: "Runtime error", status: 11, cacheable: true},
+		{name: "Time limit exceeded", status: 5, cacheable: false},
+		{name: "Internal error", status: 13, cacheable: false},
+		{name: "Processing", status: 2, cacheable: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var result judge0.SubmissionResult
+			result.Status.ID = testCase.status
+			if cacheable := Cacheable(result); cacheable != testCase.cacheable {
+				t.Errorf("Expected cacheable %v, got %v", testCase.cacheable, cacheable)
+			}
+		})
+	}
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	result := judge0.SubmissionResult{Stdout: "1", Token: "token"}
+
+	t.Run("Get cached result", func(t *testing.T) {
+		t.Parallel()
+
+		cache := NewMemory(0)
+		cache.Set(ctx, "a", 1, result)
+		cached, ok := cache.Get(ctx, "a")
+		if !ok || cached.Stdout != "1" {
+			t.Fatalf("Expected cached result, got %v %v", cached, ok)
+		}
+		if cached.Token != "" {
+			t.Errorf("Expected token to be stripped, got %s", cached.Token)
+		}
+		if _, ok := cache.Get(ctx, "b"); ok {
+			t.Errorf("Expected miss for an unknown key")
+		}
+	})
+
+	t.Run("Evict least recently used", func(t *testing.T) {
+		t.Parallel()
+
+		cache := NewMemory(2)
+		cache.Set(ctx, "a", 1, result)
+		cache.Set(ctx, "b", 1, result)
+		cache.Get(ctx, "a")
+		cache.Set(ctx, "c", 1, result)
+		if _, ok := cache.Get(ctx, "b"); ok {
+			t.Errorf("Expected b to be evicted")
+		}
+		if _, ok := cache.Get(ctx, "a"); !ok {
+			t.Errorf("Expected a to be kept")
+		}
+		if cache.Len() != 2 {
+			t.Errorf("Expected 2 results, got %d", cache.Len())
+		}
+	})
+
+	t.Run("Invalidate problem", func(t *testing.T) {
+		t.Parallel()
+
+		cache := NewMemory(0)
+		cache.Set(ctx, "a", 1, result)
+		cache.Set(ctx, "b", 1, result)
+		cache.Set(ctx, "c", 2, result)
+		cache.InvalidateProblem(ctx, 1)
+		if _, ok := cache.Get(ctx, "a"); ok {
+			t.Errorf("Expected a to be invalidated")
+		}
+		if _, ok := cache.Get(ctx, "c"); !ok {
+			t.Errorf("Expected results of other problems to be kept")
+		}
+		if cache.Len() != 1 {
+			t.Errorf("Expected 1 result, got %d", cache.Len())
+		}
+	})
+}
+
+func TestPostgres(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Default ttl", func(t *testing.T) {
+		t.Parallel()
+
+		cache := NewPostgres(nil, 0)
+		if expired := cache.expiredBefore(now).Time; !expired.Equal(now.Add(-DefaultPostgresTTL)) {
+			t.Errorf("Expected results created before %v to be expired, got %v", now.Add(-DefaultPostgresTTL), expired)
+		}
+	})
+
+	t.Run("Prune once per interval", func(t *testing.T) {
+		t.Parallel()
+
+		cache := NewPostgres(nil, time.Hour)
+		if !cache.pruneDue(now) {
+			t.Errorf("Expected the first result cached to prune")
+		}
+		if cache.pruneDue(now.Add(postgresPruneInterval / 2)) {
+			t.Errorf("Expected no prune within the interval")
+		}
+		if !cache.pruneDue(now.Add(postgresPruneInterval)) {
+			t.Errorf("Expected a prune after the interval")
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/go-chi/chi/v5"
@@ -17,6 +18,7 @@ import (
 	"kadane.xyz/go-backend/v2/src/db"
 	"kadane.xyz/go-backend/v2/src/judge0"
 	"kadane.xyz/go-backend/v2/src/middleware"
+	"kadane.xyz/go-backend/v2/src/resultcache"
 	"kadane.xyz/go-backend/v2/src/sql/sql"
 
 	firebase "firebase.google.com/go/v4"
@@ -90,6 +92,11 @@ func (s *Server) Run() error {
 	queries := sql.New(s.postgresClient)
 	s.PostgresQueries = queries // Set queries to server
 
+	// Sync the language registry, the built in defaults stay in use if this fails
+	if err := s.syncLanguages(); err != nil {
+		log.Printf("Failed to sync languages with judge0: %v", err)
+	}
+
 	//middleware handler
 	middlewareHandler := &middleware.Handler{
 		Config:          s.config,
@@ -98,6 +105,12 @@ func (s *Server) Run() error {
 		PostgresQueries: s.PostgresQueries,
 	}
 
+	// Executions wait for judge0 by plan once the limit is reached, 0 sends them right away
+	var executor judge0.Executor = s.judge0Client
+	if s.config.Judge0MaxConcurrent > 0 {
+		executor = judge0.NewScheduler(s.judge0Client, s.config.Judge0MaxConcurrent)
+	}
+
 	//api handler
 	ApiHandler := &api.Handler{
 		PostgresClient:  s.postgresClient,
@@ -106,9 +119,42 @@ func (s *Server) Run() error {
 		AWSBucketAvatar: s.config.AWSBucketAvatar,
 		AWSRegion:       s.config.AWSRegion,
 		CloudFrontUrl:   s.config.CloudFrontUrl,
-		Judge0Client:    s.judge0Client,
+		Executor:        executor,
+		// Submission progress streams
+		SubmissionEvents: api.NewSubmissionEventHub(),
+		// Judge0 callbacks
+		Judge0CallbackURL:    s.config.Judge0CallbackUrl,
+		Judge0CallbackSecret: s.config.Judge0CallbackSecret,
+		BatchTestCases:       s.config.Judge0BatchTestCases,
 	}
 
+	// Plan quotas, executions are unlimited without them
+	if s.config.Quotas {
+		ApiHandler.Quotas = api.NewQuotas(nil)
+	}
+
+	// Result cache
+	switch s.config.ResultCache {
+	case "memory":
+		ApiHandler.ResultCache = resultcache.NewMemory(s.config.ResultCacheSize)
+	case "postgres":
+		ApiHandler.ResultCache = resultcache.NewPostgres(s.PostgresQueries, s.config.ResultCacheTTL)
+	}
+
+	// Submissions queued before a restart are polled, their judge0 callbacks may have been missed
+	go func() {
+		if err := ApiHandler.ResumeSubmissions(context.Background()); err != nil {
+			log.Printf("Failed to resume submissions: %v", err)
+		}
+	}()
+
+	// Rejudge jobs run in the background, the ones cut short by a restart carry on where they stopped
+	go func() {
+		if err := ApiHandler.ResumeRejudgeJobs(context.Background()); err != nil {
+			log.Printf("Failed to resume rejudge jobs: %v", err)
+		}
+	}()
+
 	// HTTP router
 	r := chi.NewRouter()
 
@@ -132,3 +178,20 @@ func (s *Server) Run() error {
 
 	return nil
 }
+
+// syncLanguages reconciles the judge0 language registry with judge0 and the problem_language enum
+func (s *Server) syncLanguages() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	problemLanguages, err := s.PostgresQueries.GetProblemLanguages(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching problem languages: %w", err)
+	}
+
+	if err := judge0.Languages().Sync(ctx, s.judge0Client, problemLanguages); err != nil {
+		return err
+	}
+	log.Println("Languages synced with judge0")
+	return nil
+}
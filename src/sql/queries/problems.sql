@@ -1,5 +1,5 @@
 -- name: CreateProblem :one
-INSERT INTO problem (title, description, function_name, points, tags, difficulty) VALUES (@title, @description::text, @function_name, @points, @tags, @difficulty) RETURNING id;
+INSERT INTO problem (title, description, function_name, points, tags, difficulty, cpu_time_limit, wall_time_limit, memory_limit, stack_limit, time_limit_multipliers, checker, checker_epsilon, checker_language, checker_source_code, signature, design) VALUES (@title, @description::text, @function_name, @points, @tags, @difficulty, @cpu_time_limit, @wall_time_limit, @memory_limit, @stack_limit, @time_limit_multipliers, @checker, @checker_epsilon, @checker_language, @checker_source_code, @signature, @design) RETURNING id;
 
 -- name: CreateProblemCode :exec
 INSERT INTO problem_code (problem_id, language, code) VALUES (@problem_id::int, @language::problem_language, @code::text);
@@ -55,6 +55,7 @@ SELECT
                             FROM problem_test_case_input pti
                             WHERE pti.problem_test_case_id = pt.id
                         ),
+                    'calls', pt.calls,
                     'output',
                         (
                             SELECT pto.value
@@ -130,6 +131,7 @@ WITH problem_data AS (
                             FROM problem_test_case_input pti
                             WHERE pti.problem_test_case_id = pt.id
                         ),
+                    'calls', pt.calls,
                     'output',
                         (
                             SELECT pto.value
@@ -151,6 +153,7 @@ WITH problem_data AS (
     LEFT JOIN problem_hint ph ON p.id = ph.problem_id
     LEFT JOIN problem_test_case pt ON p.id = pt.problem_id
     LEFT JOIN submission s ON p.id = s.problem_id
+    WHERE p.retired_at IS NULL
     GROUP BY p.id, p.title, p.description, p.tags, p.difficulty, p.points
 )
 SELECT 
@@ -180,6 +183,7 @@ WITH filtered_problems AS (
     WHERE
         (@title::text = '' OR p.title ILIKE '%' || @title::text || '%')
         AND (@difficulty::text = '' OR p.difficulty = @difficulty::problem_difficulty)
+        AND p.retired_at IS NULL
     GROUP BY p.id, sp.problem_id
 
     -- No ORDER BY / LIMIT / OFFSET here: this is the "full" matching set
@@ -228,6 +232,7 @@ SELECT
                             FROM problem_test_case_input pti
                             WHERE pti.problem_test_case_id = pt.id
                         ),
+                    'calls', pt.calls,
                     'output',
                         (
                             SELECT pto.value
@@ -303,7 +308,7 @@ SELECT * FROM problem WHERE title = @title::text;
 SELECT * FROM problem WHERE difficulty = @difficulty::problem_difficulty ORDER BY RANDOM() LIMIT @per_page::int OFFSET ((@page::int) - 1) * @per_page::int;
 
 -- name: CreateProblemTestCase :one
-INSERT INTO problem_test_case (problem_id, description, visibility) VALUES (@problem_id::int, @description::text, @visibility::visibility) RETURNING *;
+INSERT INTO problem_test_case (problem_id, description, visibility, calls) VALUES (@problem_id::int, @description::text, @visibility::visibility, @calls) RETURNING *;
 
 -- name: CreateProblemTestCaseInput :one
 INSERT INTO problem_test_case_input (problem_test_case_id, name, value, type) VALUES (@problem_test_case_id::int, @name::text, @value::text, @type::problem_test_case_type) RETURNING *;
@@ -332,4 +337,54 @@ SELECT ptc.*,
     ) AS output
 FROM problem_test_case ptc
 WHERE ptc.problem_id = @problem_id::int 
-    AND (@visibility::text = '' OR ptc.visibility = @visibility::visibility);
\ No newline at end of file
+    AND (@visibility::text = '' OR ptc.visibility = @visibility::visibility)
+ORDER BY ptc.id;
+
+-- name: GetProblemLanguages :many
+SELECT unnest(enum_range(NULL::problem_language))::text AS language;
+
+-- name: UpdateProblem :one
+-- Replaces the problem's fields and bumps its version, retired problems are left alone
+UPDATE problem
+SET
+    title = @title,
+    description = @description::text,
+    function_name = @function_name,
+    points = @points,
+    tags = @tags,
+    difficulty = @difficulty,
+    cpu_time_limit = @cpu_time_limit,
+    wall_time_limit = @wall_time_limit,
+    memory_limit = @memory_limit,
+    stack_limit = @stack_limit,
+    time_limit_multipliers = @time_limit_multipliers,
+    checker = @checker,
+    checker_epsilon = @checker_epsilon,
+    checker_language = @checker_language,
+    checker_source_code = @checker_source_code,
+    signature = @signature,
+    design = @design,
+    version = version + 1
+WHERE id = @id::int AND retired_at IS NULL
+RETURNING version;
+
+-- name: DeleteProblemCodes :exec
+DELETE FROM problem_code WHERE problem_id = @problem_id::int;
+
+-- name: DeleteProblemHints :exec
+DELETE FROM problem_hint WHERE problem_id = @problem_id::int;
+
+-- name: DeleteProblemTestCases :exec
+DELETE FROM problem_test_case WHERE problem_id = @problem_id::int;
+
+-- name: DeleteProblemSolutions :exec
+DELETE FROM problem_solution WHERE problem_id = @problem_id::int;
+
+-- name: RetireProblem :execrows
+UPDATE problem SET retired_at = NOW() WHERE id = @id::int AND retired_at IS NULL;
+
+-- name: CreateProblemVersion :exec
+INSERT INTO problem_version (problem_id, version, problem, created_by) VALUES (@problem_id::int, @version::int, @problem, @created_by);
+
+-- name: GetProblemVersions :many
+SELECT * FROM problem_version WHERE problem_id = @problem_id::int ORDER BY version DESC;
@@ -1,5 +1,5 @@
 -- name: CreateSubmission :one
-INSERT INTO submission (id, stdout, time, memory, stderr, compile_output, message, status, language_id, language_name, account_id, problem_id, submitted_code, submitted_stdin, failed_test_case, passed_test_cases, total_test_cases) VALUES (@id::uuid, @stdout::text, @time::text, @memory::int, @stderr::text, @compile_output::text, @message::text, @status, @language_id, @language_name, @account_id, @problem_id, @submitted_code, @submitted_stdin, @failed_test_case, @passed_test_cases::int, @total_test_cases::int) RETURNING *;
+INSERT INTO submission (id, stdout, time, memory, stderr, compile_output, message, status, language_id, language_name, account_id, problem_id, problem_version, submitted_code, submitted_stdin, failed_test_case, passed_test_cases, total_test_cases) VALUES (@id::uuid, @stdout::text, @time::text, @memory::int, @stderr::text, @compile_output::text, @message::text, @status, @language_id, @language_name, @account_id, @problem_id, @problem_version, @submitted_code, @submitted_stdin, @failed_test_case, @passed_test_cases::int, @total_test_cases::int) RETURNING *;
 
 -- name: GetSubmissionByID :one
 SELECT 
@@ -129,3 +129,207 @@ ORDER BY
     -- 5) Fallback ordering for stability
     submission_id DESC
 NULLS LAST;
+
+-- name: CreateSubmissionJudge0Token :exec
+INSERT INTO submission_judge0_token (token, submission_id, test_case_index, test_case, batch, cache_key) VALUES (@token, @submission_id::uuid, @test_case_index::int, @test_case, @batch::boolean, sqlc.narg(cache_key)::text);
+
+-- name: GetSubmissionJudge0Token :one
+SELECT t.*, s.problem_id
+FROM submission_judge0_token t
+JOIN submission s ON s.id = t.submission_id
+WHERE t.token = @token;
+
+-- name: UpdateSubmissionJudge0TokenResult :execrows
+-- Judge0 callbacks and polling can both deliver a result, only the first one is recorded
+UPDATE submission_judge0_token SET result = @result, statuses = @statuses WHERE token = @token AND result IS NULL;
+
+-- name: GetSubmissionJudge0Tokens :many
+SELECT * FROM submission_judge0_token WHERE submission_id = @submission_id::uuid ORDER BY test_case_index;
+
+-- name: CreateSubmissionTestResult :exec
+INSERT INTO submission_test_result (submission_id, test_case_index, status, time, memory, stdout, stderr, hidden)
+VALUES (@submission_id::uuid, @test_case_index::int, @status, @time, @memory, @stdout, @stderr, @hidden::boolean)
+ON CONFLICT (submission_id, test_case_index) DO NOTHING;
+
+-- name: GetSubmissionTestResults :many
+SELECT * FROM submission_test_result WHERE submission_id = @submission_id::uuid ORDER BY test_case_index;
+
+-- name: GetSubmissionLanguage :one
+SELECT language_id FROM submission WHERE id = @id::uuid;
+
+-- name: GetSubmissionChecker :one
+SELECT p.checker, p.checker_epsilon, p.checker_language, p.checker_source_code
+FROM submission s
+JOIN problem p ON p.id = s.problem_id
+WHERE s.id = @id::uuid;
+
+-- name: UpdateSubmissionStatus :exec
+UPDATE submission SET status = @status WHERE id = @id::uuid AND status IN ('In Queue', 'Processing');
+
+-- name: GetPendingSubmissions :many
+SELECT id FROM submission WHERE status IN ('In Queue', 'Processing') ORDER BY created_at;
+
+-- name: CompleteSubmission :exec
+UPDATE submission
+SET
+    stdout = @stdout::text,
+    time = @time::text,
+    memory = @memory::int,
+    stderr = @stderr::text,
+    compile_output = @compile_output::text,
+    message = @message::text,
+    status = @status,
+    failed_test_case = @failed_test_case,
+    passed_test_cases = @passed_test_cases::int
+WHERE id = @id::uuid AND status IN ('In Queue', 'Processing');
+
+-- name: RejudgeSubmission :exec
+UPDATE submission
+SET
+    stdout = @stdout::text,
+    time = @time::text,
+    memory = @memory::int,
+    stderr = @stderr::text,
+    compile_output = @compile_output::text,
+    message = @message::text,
+    status = @status,
+    failed_test_case = @failed_test_case,
+    passed_test_cases = @passed_test_cases::int,
+    total_test_cases = @total_test_cases::int,
+    problem_version = @problem_version
+WHERE id = @id::uuid;
+
+-- name: DeleteSubmissionTestResults :exec
+DELETE FROM submission_test_result WHERE submission_id = @submission_id::uuid;
+
+-- name: SyncAccountSolvedProblem :exec
+-- Records the problem as solved by the account while it has an accepted submission, forgets it otherwise
+WITH accepted AS (
+    SELECT EXISTS (
+        SELECT 1 FROM submission WHERE account_id = @account_id::text AND problem_id = @problem_id::int AND status = 'Accepted'
+    ) AS solved
+), forgotten AS (
+    DELETE FROM account_solved_problem
+    WHERE user_id = @account_id::text AND problem_id = @problem_id::int AND NOT (SELECT solved FROM accepted)
+)
+INSERT INTO account_solved_problem (user_id, problem_id)
+SELECT @account_id::text, @problem_id::int
+WHERE (SELECT solved FROM accepted)
+    AND NOT EXISTS (SELECT 1 FROM account_solved_problem WHERE user_id = @account_id::text AND problem_id = @problem_id::int);
+
+-- name: CreateRejudgeJob :one
+INSERT INTO rejudge_job (problem_id, submission_ids, since, created_by, lease_owner, lease_expires_at)
+VALUES (sqlc.narg(problem_id)::int, sqlc.narg(submission_ids)::uuid[], sqlc.narg(since)::timestamp, @created_by::text,
+    @lease_owner::uuid, CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::int))
+RETURNING *;
+
+-- name: CreateRejudgeSubmissions :execrows
+-- Submissions still being graded are left out, they are graded against the current test cases anyway
+INSERT INTO rejudge_submission (job_id, submission_id, old_status, old_passed_test_cases)
+SELECT @job_id::uuid, s.id, s.status, COALESCE(s.passed_test_cases, 0)
+FROM submission s
+WHERE s.status NOT IN ('In Queue', 'Processing')
+    AND (sqlc.narg(problem_id)::int IS NULL OR s.problem_id = sqlc.narg(problem_id)::int)
+    AND (sqlc.narg(submission_ids)::uuid[] IS NULL OR s.id = ANY(sqlc.narg(submission_ids)::uuid[]))
+    AND (sqlc.narg(since)::timestamp IS NULL OR s.created_at >= sqlc.narg(since)::timestamp);
+
+-- name: UpdateRejudgeJobTotal :exec
+UPDATE rejudge_job SET total = @total::int WHERE id = @id::uuid;
+
+-- name: GetRejudgeJob :one
+SELECT * FROM rejudge_job WHERE id = @id::uuid;
+
+-- name: ClaimRejudgeJob :one
+-- The oldest job a server stopped in the middle of, its lease ran out. Instances claiming at once get different jobs.
+UPDATE rejudge_job
+SET lease_owner = @lease_owner::uuid, lease_expires_at = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::int)
+WHERE id = (
+    SELECT id FROM rejudge_job
+    WHERE status = 'Running' AND (lease_expires_at IS NULL OR lease_expires_at <= CURRENT_TIMESTAMP)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id;
+
+-- name: RenewRejudgeJobLease :execrows
+-- Nothing is renewed once another run claimed the job
+UPDATE rejudge_job SET lease_expires_at = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::int)
+WHERE id = @id::uuid AND lease_owner = @lease_owner::uuid;
+
+-- name: GetRejudgeSubmissions :many
+SELECT
+    rs.*,
+    s.account_id,
+    s.problem_id,
+    s.language_id,
+    s.submitted_code
+FROM rejudge_submission rs
+JOIN submission s ON s.id = rs.submission_id
+WHERE rs.job_id = @job_id::uuid
+ORDER BY s.created_at, rs.submission_id;
+
+-- name: UpdateRejudgeSubmission :execrows
+-- A submission is only recorded once, by the first run of its job to grade it
+UPDATE rejudge_submission
+SET
+    new_status = sqlc.narg(new_status),
+    new_passed_test_cases = sqlc.narg(new_passed_test_cases)::int,
+    error = sqlc.narg(error)::text,
+    rejudged_at = CURRENT_TIMESTAMP
+WHERE job_id = @job_id::uuid AND submission_id = @submission_id::uuid AND rejudged_at IS NULL;
+
+-- name: UpdateRejudgeJobProgress :exec
+UPDATE rejudge_job
+SET
+    completed = completed + 1,
+    changed = changed + CASE WHEN @changed::boolean THEN 1 ELSE 0 END,
+    failed = failed + CASE WHEN @failed::boolean THEN 1 ELSE 0 END
+WHERE id = @id::uuid;
+
+-- name: CompleteRejudgeJob :exec
+UPDATE rejudge_job SET status = @status, finished_at = CURRENT_TIMESTAMP WHERE id = @id::uuid;
+
+-- name: GetSubmissionResultCache :one
+-- Expired results are misses until they are deleted
+SELECT result FROM submission_result_cache WHERE key = @key AND created_at >= @expired_before::timestamp;
+
+-- name: CreateSubmissionResultCache :exec
+-- Results are only set after a miss, an expired result of the key is replaced
+INSERT INTO submission_result_cache (key, problem_id, result) VALUES (@key, sqlc.narg(problem_id)::int, @result)
+ON CONFLICT (key) DO UPDATE SET problem_id = EXCLUDED.problem_id, result = EXCLUDED.result, created_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredSubmissionResultCache :execrows
+-- Results outside of a problem have no problem to be invalidated with, they expire like the others
+DELETE FROM submission_result_cache WHERE created_at < @expired_before::timestamp;
+
+-- name: DeleteProblemSubmissionResultCache :exec
+DELETE FROM submission_result_cache WHERE problem_id = @problem_id::int;
+
+-- name: LockAccountQuota :exec
+-- Held until the transaction ends, so the executions of an account are checked and recorded one at a time
+SELECT pg_advisory_xact_lock(hashtext('account_quota:' || @account_id::text));
+
+-- name: GetQuotaUsage :one
+-- Executions of a kind of the account in the last minute and day, the resets are the seconds until the oldest of each leaves it
+SELECT
+    COUNT(*) FILTER (WHERE created_at > CURRENT_TIMESTAMP - INTERVAL '1 minute')::int AS minute,
+    COUNT(*)::int AS day,
+    COALESCE(EXTRACT(EPOCH FROM MIN(created_at) FILTER (WHERE created_at > CURRENT_TIMESTAMP - INTERVAL '1 minute') + INTERVAL '1 minute' - CURRENT_TIMESTAMP), 0)::float8 AS minute_reset,
+    COALESCE(EXTRACT(EPOCH FROM MIN(created_at) + INTERVAL '1 day' - CURRENT_TIMESTAMP), 0)::float8 AS day_reset
+FROM account_execution
+WHERE account_id = @account_id::text AND kind = @kind::text AND created_at > CURRENT_TIMESTAMP - INTERVAL '1 day';
+
+-- name: CreateAccountExecution :exec
+-- Executions older than a day no longer count, they are deleted as the account executes again
+WITH expired AS (
+    DELETE FROM account_execution WHERE account_id = @account_id::text AND created_at <= CURRENT_TIMESTAMP - INTERVAL '1 day'
+)
+INSERT INTO account_execution (account_id, kind) VALUES (@account_id::text, @kind::text);
+
+-- name: CountPendingSubmissions :one
+-- Submissions older than the window were abandoned by their grading and no longer count
+SELECT COUNT(*) FROM submission
+WHERE account_id = @account_id::text
+    AND status IN ('In Queue', 'Processing')
+    AND created_at > CURRENT_TIMESTAMP - make_interval(secs => @window_seconds::int);
@@ -1,9 +1,11 @@
-CREATE TYPE problem_language AS ENUM ('cpp', 'go', 'java', 'javascript', 'python', 'typescript');
+CREATE TYPE problem_language AS ENUM ('cpp', 'go', 'java', 'javascript', 'python', 'typescript', 'rust', 'c', 'csharp', 'kotlin', 'ruby');
 
 CREATE TYPE problem_difficulty AS ENUM ('easy', 'medium', 'hard');
 
 CREATE TYPE problem_sort AS ENUM ('alpha', 'index');
 
+CREATE TYPE checker_mode AS ENUM ('default', 'exact', 'token', 'float', 'unordered', 'any', 'custom');
+
 CREATE TABLE problem (
     id SERIAL PRIMARY KEY,
     title TEXT NOT NULL,
@@ -13,9 +15,35 @@ CREATE TABLE problem (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     difficulty problem_difficulty NOT NULL,
     tags TEXT[],
+    -- judge0 limits, NULL falls back to judge0's defaults
+    cpu_time_limit DOUBLE PRECISION, -- seconds
+    wall_time_limit DOUBLE PRECISION, -- seconds
+    memory_limit INT, -- kilobytes
+    stack_limit INT, -- kilobytes
+    time_limit_multipliers JSONB, -- {"language": multiplier} overriding the language defaults
+    -- how outputs are compared with the expected output
+    checker checker_mode NOT NULL DEFAULT 'default',
+    checker_epsilon DOUBLE PRECISION, -- float checker tolerance, NULL uses the default
+    checker_language problem_language, -- custom checker program
+    checker_source_code TEXT, -- custom checker program
+    signature JSONB, -- {"parameters": [{"name", "type"}], "returnType"} of the function solutions implement
+    design JSONB, -- {"constructor", "methods"} of the class design problems implement, instead of a function
+    version INT NOT NULL DEFAULT 1, -- bumped by every edit, see problem_version
+    retired_at TIMESTAMP NULL, -- retired problems are hidden and take no new runs or submissions, their submissions are kept
     UNIQUE (id, title)
 );
 
+-- every version of a problem as the request it was created or edited with, including test cases and solutions
+CREATE TABLE problem_version (
+    id SERIAL PRIMARY KEY,
+    problem_id INT NOT NULL REFERENCES problem(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    problem JSONB NOT NULL,
+    created_by TEXT NULL REFERENCES account(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (problem_id, version)
+);
+
 CREATE TABLE problem_solution (
     id SERIAL PRIMARY KEY,
     problem_id INT REFERENCES problem(id) ON DELETE CASCADE,
@@ -49,6 +77,7 @@ CREATE TABLE problem_test_case (
     description TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     visibility visibility NOT NULL,
+    calls JSONB, -- [{"method", "arguments"}] of design problems, which have no inputs
     UNIQUE (problem_id, id)
 );
 
@@ -63,7 +92,15 @@ CREATE TYPE problem_test_case_type AS ENUM (
     'double[]',
     'boolean',
     'boolean[]',
-    'null'
+    'null',
+    'long',
+    'char',
+    'int[][]',
+    'string[][]',
+    'ListNode',
+    'TreeNode',
+    'graph',
+    'map'
 );
 
 CREATE TABLE problem_test_case_input (
@@ -33,5 +33,88 @@ CREATE TABLE submission (
     failed_test_case JSONB NULL,
     passed_test_cases INTEGER DEFAULT 0,
     total_test_cases INTEGER DEFAULT 0,
-    problem_id INTEGER NOT NULL REFERENCES problem(id) ON DELETE CASCADE
-);
\ No newline at end of file
+    problem_id INTEGER NOT NULL REFERENCES problem(id) ON DELETE CASCADE,
+    problem_version INTEGER NULL -- version of the problem the submission was last judged against, NULL before versioning
+);
+
+-- judge0 submissions created for a kadane submission, one per test case or a single batch of all of them
+CREATE TABLE submission_judge0_token (
+    token TEXT PRIMARY KEY,
+    submission_id UUID NOT NULL REFERENCES submission(id) ON DELETE CASCADE,
+    test_case_index INTEGER NOT NULL,
+    test_case JSONB NOT NULL,
+    batch BOOLEAN NOT NULL DEFAULT false, -- runs every test case of the submission, stored in test_case
+    result JSONB NULL, -- judge0 result, set once the judge0 submission finishes
+    statuses JSONB NULL, -- graded status of every test case of the result, set along with it
+    cache_key TEXT NULL, -- result cache key of the judge0 submission, see resultcache
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX submission_judge0_token_submission_id_idx ON submission_judge0_token (submission_id);
+
+-- verdict of every test case of a graded submission
+CREATE TABLE submission_test_result (
+    submission_id UUID NOT NULL REFERENCES submission(id) ON DELETE CASCADE,
+    test_case_index INTEGER NOT NULL,
+    status submission_status NOT NULL,
+    time TEXT NOT NULL DEFAULT '',
+    memory INTEGER NOT NULL DEFAULT 0,
+    stdout TEXT NOT NULL DEFAULT '', -- truncated
+    stderr TEXT NOT NULL DEFAULT '', -- truncated
+    hidden BOOLEAN NOT NULL DEFAULT false, -- private test case, its output is only shown to admins
+    PRIMARY KEY (submission_id, test_case_index)
+);
+
+CREATE TYPE rejudge_status AS ENUM ('Running', 'Completed', 'Failed');
+
+-- admin job grading stored submissions again against the current test cases and checker of their problem
+CREATE TABLE rejudge_job (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status rejudge_status NOT NULL DEFAULT 'Running',
+    problem_id INTEGER NULL REFERENCES problem(id) ON DELETE CASCADE, -- filters, combined when several are set
+    submission_ids UUID[] NULL,
+    since TIMESTAMP NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    completed INTEGER NOT NULL DEFAULT 0,
+    changed INTEGER NOT NULL DEFAULT 0, -- submissions whose verdict changed
+    failed INTEGER NOT NULL DEFAULT 0, -- submissions that could not be graded and kept their verdict
+    created_by TEXT NULL REFERENCES account(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP NULL,
+    lease_owner UUID NULL, -- the run of the job, a run stops once another one claimed the job
+    lease_expires_at TIMESTAMP NULL -- renewed while the job runs, running jobs with an expired lease are resumed
+);
+
+-- submissions of a rejudge job with their verdict before and after
+CREATE TABLE rejudge_submission (
+    job_id UUID NOT NULL REFERENCES rejudge_job(id) ON DELETE CASCADE,
+    submission_id UUID NOT NULL REFERENCES submission(id) ON DELETE CASCADE,
+    old_status submission_status NOT NULL,
+    old_passed_test_cases INTEGER NOT NULL DEFAULT 0,
+    new_status submission_status NULL, -- set once graded again
+    new_passed_test_cases INTEGER NULL,
+    error TEXT NULL, -- why the submission could not be graded again
+    rejudged_at TIMESTAMP NULL,
+    PRIMARY KEY (job_id, submission_id)
+);
+
+-- judge0 results of deterministic executions, keyed by a hash of the judge0 submission, see resultcache
+CREATE TABLE submission_result_cache (
+    key TEXT PRIMARY KEY,
+    problem_id INTEGER NULL REFERENCES problem(id) ON DELETE CASCADE, -- dropped when its test cases change
+    result JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP -- expires after the ttl of the cache
+);
+
+CREATE INDEX submission_result_cache_problem_id_idx ON submission_result_cache (problem_id);
+CREATE INDEX submission_result_cache_created_at_idx ON submission_result_cache (created_at);
+
+-- runs and submissions of each account in the last day, counted against the quota of its plan
+CREATE TABLE account_execution (
+    account_id TEXT NOT NULL REFERENCES account(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('runs', 'submissions')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX account_execution_account_id_kind_created_at_idx ON account_execution (account_id, kind, created_at);
+CREATE INDEX submission_account_id_created_at_idx ON submission (account_id, created_at);